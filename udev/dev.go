@@ -30,6 +30,17 @@ func (me SrcMsgs) Len() int           { return len(me) }
 func (me SrcMsgs) Swap(i, j int)      { me[i], me[j] = me[j], me[i] }
 func (me SrcMsgs) Less(i, j int) bool { return me[i].Msg < me[j].Msg }
 
+//	Returns all `me` as `ustr.TextEdit`s for `ustr.Rope.ApplyEdits`, keyed by the `Ref` (file) each applies to.
+//	Each replaces the range from `Pos1Ln`/`Pos1Ch` to `Pos2Ln`/`Pos2Ch` (taken as `0`-based, as in the edits
+//	returned by `udevgo.Gorename`) with `Msg`, in the order of `me`.
+func (me SrcMsgs) TextEdits() (editsByRef map[string][]ustr.TextEdit) {
+	editsByRef = make(map[string][]ustr.TextEdit, 1)
+	for _, m := range me {
+		editsByRef[m.Ref] = append(editsByRef[m.Ref], ustr.TextEdit{Start: ustr.TextPos{Line: m.Pos1Ln, Col: m.Pos1Ch}, End: ustr.TextPos{Line: m.Pos2Ln, Col: m.Pos2Ch}, Text: m.Msg})
+	}
+	return
+}
+
 var (
 	SrcDir string
 )
@@ -0,0 +1,30 @@
+package udev
+
+import (
+	"testing"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+func TestSrcMsgsTextEdits(t *testing.T) {
+	msgs := SrcMsgs{
+		{Ref: "a.go", Msg: "bar", Pos1Ln: 0, Pos1Ch: 4, Pos2Ln: 0, Pos2Ch: 7},
+		{Ref: "b.go", Msg: "x", Pos1Ln: 1, Pos1Ch: 0, Pos2Ln: 1, Pos2Ch: 1},
+		{Ref: "a.go", Msg: "bar", Pos1Ln: 1, Pos1Ch: 0, Pos2Ln: 1, Pos2Ch: 3},
+	}
+	editsByRef := msgs.TextEdits()
+	if len(editsByRef) != 2 || len(editsByRef["a.go"]) != 2 || len(editsByRef["b.go"]) != 1 {
+		t.Fatalf("got %v", editsByRef)
+	}
+	for ref, test := range map[string]struct{ src, want string }{
+		"a.go": {"var foo = 1\nfoo++\n", "var bar = 1\nbar++\n"},
+		"b.go": {"package b\ny := 2\n", "package b\nx := 2\n"},
+	} {
+		rope := ustr.NewRope(test.src)
+		if err := rope.ApplyEdits(editsByRef[ref], ustr.TextUnitByte); err != nil {
+			t.Fatalf("%s: %v", ref, err)
+		} else if got := rope.String(); got != test.want {
+			t.Errorf("%s: got %q, want %q", ref, got, test.want)
+		}
+	}
+}
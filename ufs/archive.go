@@ -0,0 +1,518 @@
+package ufs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	A stream compression usable for tar archives, see `ArchiveCompressions`.
+type ArchiveCompression struct {
+	NewReader func(io.Reader) (io.ReadCloser, error)
+
+	//	May be `nil` for compressions that can only be extracted.
+	NewWriter func(io.Writer) (io.WriteCloser, error)
+}
+
+//	Options for `CreateArchive`, `ExtractArchive` and related functions.
+type ArchiveOptions struct {
+	//	If set, only dirs/files whose `/`-separated path inside the archive is matched (as per `ustr.Matcher.IsMatchPath`)
+	//	are archived or extracted. Directories containing such files are created as needed even when not matched themselves.
+	Include *ustr.Matcher
+
+	//	If set, dirs/files whose `/`-separated path inside the archive is matched are neither archived nor extracted,
+	//	and neither is anything inside such dirs.
+	Exclude *ustr.Matcher
+
+	//	If set, called after each dir/file archived or extracted with its `/`-separated path inside the archive,
+	//	the number of entries and the total number of (uncompressed) file bytes processed so far.
+	OnProgress func(relPath string, entriesDone int, bytesDone int64)
+}
+
+var (
+	//	The tar stream compressions supported by `CreateArchive` and `ExtractArchive`, by file extension (such as in `.tar.gz`).
+	//	Additional ones (such as `.zst` or `.xz`) can be registered here, eg. from third-party packages.
+	ArchiveCompressions = map[string]*ArchiveCompression{
+		".gz": {
+			NewReader: func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil },
+		},
+		".bz2": {
+			NewReader: func(r io.Reader) (io.ReadCloser, error) { return ioutil.NopCloser(bzip2.NewReader(r)), nil },
+		},
+	}
+
+	//	Returned when extracting an archive entry whose path (or link target) would end up outside the target directory,
+	//	including by way of symlinks extracted earlier.
+	ErrArchiveEntryOutside = errors.New("ufs: archive entry outside target directory")
+
+	errArchiveFormat      = errors.New("ufs: unsupported archive format")
+	errArchiveSymlinkLoop = errors.New("ufs: too many levels of symbolic links")
+)
+
+//	The state of a single archive extraction.
+type archiveExtraction struct {
+	*ArchiveOptions
+	dstDirPath string
+	rootPath   string
+	dirs       []archiveDir
+	entries    int
+	bytes      int64
+}
+
+type archiveDir struct {
+	dirPath string
+	info    os.FileInfo
+}
+
+//	Writes all dirs/files inside `srcDirPath` into a new archive at `archiveFilePath`, whose format is
+//	determined by its extension: `.zip`, `.tar`, `.tar.gz` (or `.tgz`) or `.tar` plus any other `ArchiveCompressions` extension.
+//	If `WriteAtomically` is set, the archive file is written via `WriteAtomic`. `opt` may be `nil`.
+func CreateArchive(archiveFilePath, srcDirPath string, opt *ArchiveOptions) (err error) {
+	var isZip bool
+	var compression *ArchiveCompression
+	if isZip, compression, err = archiveFormatOf(archiveFilePath); err == nil && compression != nil && compression.NewWriter == nil {
+		err = errArchiveFormat
+	}
+	if err == nil {
+		err = writeFile(archiveFilePath, func(w io.Writer) (err error) {
+			if isZip {
+				return WriteZip(w, srcDirPath, opt)
+			} else if compression == nil {
+				return WriteTar(w, srcDirPath, opt)
+			}
+			var cw io.WriteCloser
+			if cw, err = compression.NewWriter(w); err == nil {
+				if err = WriteTar(cw, srcDirPath, opt); err == nil {
+					err = cw.Close()
+				}
+			}
+			return
+		})
+	}
+	return
+}
+
+//	Extracts the archive at `archiveFilePath` (see `CreateArchive` for supported formats) into `dstDirPath`,
+//	preserving permission bits and modification times. `opt` may be `nil`.
+func ExtractArchive(archiveFilePath, dstDirPath string, opt *ArchiveOptions) (err error) {
+	var (
+		isZip       bool
+		compression *ArchiveCompression
+		file        *os.File
+		fi          os.FileInfo
+		cr          io.ReadCloser
+	)
+	if isZip, compression, err = archiveFormatOf(archiveFilePath); err != nil {
+		return
+	}
+	if file, err = os.Open(archiveFilePath); err != nil {
+		return
+	}
+	defer file.Close()
+	if isZip {
+		if fi, err = file.Stat(); err == nil {
+			err = ExtractZip(file, fi.Size(), dstDirPath, opt)
+		}
+	} else if compression == nil {
+		err = ExtractTar(file, dstDirPath, opt)
+	} else if cr, err = compression.NewReader(file); err == nil {
+		defer cr.Close()
+		err = ExtractTar(cr, dstDirPath, opt)
+	}
+	return
+}
+
+//	Streams all dirs/files inside `srcDirPath` as an uncompressed tar archive into `w`. `opt` may be `nil`.
+func WriteTar(w io.Writer, srcDirPath string, opt *ArchiveOptions) (err error) {
+	tw := tar.NewWriter(w)
+	err = archiveWalk(srcDirPath, opt, func(relPath, fullPath string, fi os.FileInfo, linkTarget string) (err error) {
+		var hdr *tar.Header
+		if hdr, err = tar.FileInfoHeader(fi, linkTarget); err == nil {
+			if hdr.Name = relPath; fi.IsDir() {
+				hdr.Name += "/"
+			}
+			if err = tw.WriteHeader(hdr); err == nil && fi.Mode().IsRegular() {
+				err = copyFileTo(tw, fullPath)
+			}
+		}
+		return
+	})
+	if e := tw.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+//	Streams all dirs/files inside `srcDirPath` as a (deflate-compressed) zip archive into `w`. `opt` may be `nil`.
+func WriteZip(w io.Writer, srcDirPath string, opt *ArchiveOptions) (err error) {
+	zw := zip.NewWriter(w)
+	err = archiveWalk(srcDirPath, opt, func(relPath, fullPath string, fi os.FileInfo, linkTarget string) (err error) {
+		var (
+			hdr *zip.FileHeader
+			fw  io.Writer
+		)
+		if hdr, err = zip.FileInfoHeader(fi); err == nil {
+			if hdr.Name = relPath; fi.IsDir() {
+				hdr.Name += "/"
+			} else if fi.Mode().IsRegular() {
+				hdr.Method = zip.Deflate
+			}
+			if fw, err = zw.CreateHeader(hdr); err == nil {
+				if fi.Mode()&os.ModeSymlink != 0 {
+					_, err = io.WriteString(fw, linkTarget)
+				} else if fi.Mode().IsRegular() {
+					err = copyFileTo(fw, fullPath)
+				}
+			}
+		}
+		return
+	})
+	if e := zw.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+//	Extracts the tar archive streamed from `r` into `dstDirPath`. `opt` may be `nil`.
+//	Symlinks and hard links are only extracted if pointing inside `dstDirPath`. Only files, dirs, symlinks and hard links
+//	are extracted: device files, FIFOs and meta-data entries (such as the PAX global header written by `git archive`) are skipped.
+//	No entry is ever written through a symlink leading outside `dstDirPath`, whether extracted earlier or pre-existing.
+func ExtractTar(r io.Reader, dstDirPath string, opt *ArchiveOptions) (err error) {
+	var hdr *tar.Header
+	x := newArchiveExtraction(dstDirPath, opt)
+	tr := tar.NewReader(r)
+	for hdr, err = tr.Next(); err == nil; hdr, err = tr.Next() {
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeGNUSparse, tar.TypeDir, tar.TypeSymlink, tar.TypeLink:
+			if err = x.extract(hdr.Name, hdr.FileInfo(), hdr.Linkname, hdr.Typeflag == tar.TypeLink, tr); err != nil {
+				return
+			}
+		}
+	}
+	if err == io.EOF {
+		err = x.finish()
+	}
+	return
+}
+
+//	Extracts the zip archive of the specified `size` readable from `r` into `dstDirPath`. `opt` may be `nil`.
+//	Symlinks are only extracted if pointing inside `dstDirPath`.
+func ExtractZip(r io.ReaderAt, size int64, dstDirPath string, opt *ArchiveOptions) (err error) {
+	var zr *zip.Reader
+	if zr, err = zip.NewReader(r, size); err != nil {
+		return
+	}
+	x := newArchiveExtraction(dstDirPath, opt)
+	for _, zf := range zr.File {
+		var (
+			rc         io.ReadCloser
+			linkTarget []byte
+		)
+		if rc, err = zf.Open(); err != nil {
+			return
+		}
+		if fi := zf.FileInfo(); fi.Mode()&os.ModeSymlink == 0 {
+			err = x.extract(zf.Name, fi, "", false, rc)
+		} else if linkTarget, err = ioutil.ReadAll(rc); err == nil {
+			err = x.extract(zf.Name, fi, string(linkTarget), false, nil)
+		}
+		if rc.Close(); err != nil {
+			return
+		}
+	}
+	return x.finish()
+}
+
+func newArchiveExtraction(dstDirPath string, opt *ArchiveOptions) *archiveExtraction {
+	if opt == nil {
+		opt = &ArchiveOptions{}
+	}
+	return &archiveExtraction{ArchiveOptions: opt, dstDirPath: dstDirPath}
+}
+
+//	Extracts a single archive entry: a directory (created now, but its permission bits and modification time
+//	get applied by `finish`), a symlink (or, if `isHardLink`, a hard link) to `linkName`, or a file with `contents`.
+func (me *archiveExtraction) extract(name string, fi os.FileInfo, linkName string, isHardLink bool, contents io.Reader) (err error) {
+	var dstPath string
+	relPath, isDir := strings.TrimSuffix(path.Clean(strings.Replace(name, "\\", "/", -1)), "/"), fi.IsDir()
+	if dstPath, err = archiveTargetPath(me.dstDirPath, relPath); err != nil || !me.isIncluded(relPath, isDir) {
+		return
+	}
+	//	the lexical check above is not enough once symlinks were extracted (eg. `a -> .` then `a/b -> ..`)
+	if isDir {
+		err = me.checkInside(dstPath)
+	} else if err = me.checkInside(filepath.Dir(dstPath)); err == nil {
+		err = os.MkdirAll(filepath.Dir(dstPath), ModePerm)
+	}
+	if err != nil {
+		return
+	}
+	mode := fi.Mode()
+	switch {
+	case isDir:
+		if err = os.MkdirAll(dstPath, ModePerm); err == nil {
+			me.dirs = append(me.dirs, archiveDir{dirPath: dstPath, info: fi})
+		}
+	case isHardLink:
+		var (
+			linkPath, linkTarget string
+			linkInfo             os.FileInfo
+		)
+		if linkPath, err = archiveTargetPath(me.dstDirPath, path.Clean(linkName)); err == nil {
+			if err = me.checkInside(linkPath); err == nil {
+				if linkInfo, err = os.Lstat(linkPath); err == nil && linkInfo.Mode()&os.ModeSymlink != 0 {
+					//	a hard link to a symlink is a copy of it, whose (relative) target now starts from `dstPath`
+					if linkTarget, err = os.Readlink(linkPath); err == nil {
+						err = me.checkLinkTarget(dstPath, linkTarget)
+					}
+				}
+			}
+			if err == nil {
+				if err = os.Remove(dstPath); err == nil || os.IsNotExist(err) {
+					err = os.Link(linkPath, dstPath)
+				}
+			}
+		}
+	case mode&os.ModeSymlink != 0:
+		if err = me.checkLinkTarget(dstPath, linkName); err == nil {
+			if err = os.Remove(dstPath); err == nil || os.IsNotExist(err) {
+				err = os.Symlink(linkName, dstPath)
+			}
+		}
+	case mode.IsRegular():
+		var file *os.File
+		if existing, _ := os.Lstat(dstPath); existing != nil && existing.Mode()&os.ModeSymlink != 0 {
+			//	replace rather than write through it
+			if err = os.Remove(dstPath); err != nil {
+				return
+			}
+		}
+		if file, err = os.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()); err == nil {
+			var n int64
+			n, err = io.Copy(file, contents)
+			if me.bytes += n; err == nil {
+				err = file.Close()
+			} else {
+				file.Close()
+			}
+			if err == nil {
+				if err = os.Chmod(dstPath, mode.Perm()); err == nil {
+					err = os.Chtimes(dstPath, fi.ModTime(), fi.ModTime())
+				}
+			}
+		}
+	default:
+		return
+	}
+	if err == nil {
+		me.progress(relPath)
+	}
+	return
+}
+
+//	Returns `ErrArchiveEntryOutside` unless `fsPath`, with all symlinks in it resolved, is inside `dstDirPath`.
+func (me *archiveExtraction) checkInside(fsPath string) (err error) {
+	if me.rootPath == "" {
+		var dirPath string
+		if dirPath, err = filepath.Abs(me.dstDirPath); err == nil {
+			if err = os.MkdirAll(dirPath, ModePerm); err == nil {
+				me.rootPath, err = archiveResolvePath(dirPath)
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+	if fsPath, err = filepath.Abs(fsPath); err == nil {
+		if fsPath, err = archiveResolvePath(fsPath); err == nil && !pathIsIn(fsPath, me.rootPath) {
+			err = ErrArchiveEntryOutside
+		}
+	}
+	return
+}
+
+//	Returns `ErrArchiveEntryOutside` unless a symlink at `linkPath` to `target` would resolve to inside `dstDirPath`.
+func (me *archiveExtraction) checkLinkTarget(linkPath string, target string) (err error) {
+	if target = filepath.FromSlash(target); !(filepath.IsAbs(target) || filepath.VolumeName(target) != "") {
+		//	joined without `filepath.Join`, whose lexical cleaning of `..` would ignore symlinks before it
+		target = filepath.Dir(linkPath) + string(filepath.Separator) + target
+	}
+	return me.checkInside(target)
+}
+
+//	Applies permission bits and modification times to all extracted directories, deepest first.
+func (me *archiveExtraction) finish() (err error) {
+	for i := len(me.dirs) - 1; i >= 0; i-- {
+		dir := me.dirs[i]
+		if err = os.Chmod(dir.dirPath, dir.info.Mode().Perm()); err == nil {
+			err = os.Chtimes(dir.dirPath, dir.info.ModTime(), dir.info.ModTime())
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+//	Returns whether the `/`-separated `relPath` is matched by `Include` (if any) and neither it nor any of its parent dirs by `Exclude`.
+func (me *archiveExtraction) isIncluded(relPath string, isDir bool) bool {
+	if me.Include != nil && !me.Include.IsMatchPath(relPath, isDir) {
+		return false
+	}
+	if me.Exclude != nil {
+		for p, d := relPath, isDir; p != "." && p != "/"; p, d = path.Dir(p), true {
+			if me.Exclude.IsMatchPath(p, d) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (me *archiveExtraction) progress(relPath string) {
+	if me.entries++; me.OnProgress != nil {
+		me.OnProgress(relPath, me.entries, me.bytes)
+	}
+}
+
+//	Walks `srcDirPath` for `WriteTar` and `WriteZip`, calling `write` for every dir/file to be archived.
+func archiveWalk(srcDirPath string, opt *ArchiveOptions, write func(relPath, fullPath string, fi os.FileInfo, linkTarget string) error) (err error) {
+	if opt == nil {
+		opt = &ArchiveOptions{}
+	}
+	var (
+		entries int
+		bytes   int64
+	)
+	w := NewDirWalker(true, nil, nil)
+	w.VisitSelf, w.Ignore = false, opt.Exclude
+	w.EntryVisitor = func(fullPath string, entry os.DirEntry) (keepWalking bool) {
+		var (
+			fi              os.FileInfo
+			relPath, target string
+		)
+		if relPath, err = filepath.Rel(srcDirPath, fullPath); err == nil {
+			if relPath = filepath.ToSlash(relPath); opt.Include != nil && !opt.Include.IsMatchPath(relPath, entry.IsDir()) {
+				return true
+			}
+			if fi, err = entry.Info(); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				target, err = os.Readlink(fullPath)
+			}
+			if err == nil && (fi.IsDir() || fi.Mode().IsRegular() || fi.Mode()&os.ModeSymlink != 0) {
+				if err = write(relPath, fullPath, fi, target); err == nil {
+					if entries++; fi.Mode().IsRegular() {
+						bytes += fi.Size()
+					}
+					if opt.OnProgress != nil {
+						opt.OnProgress(relPath, entries, bytes)
+					}
+				}
+			}
+		}
+		return err == nil
+	}
+	if errs := w.Walk(srcDirPath); err == nil && len(errs) > 0 {
+		err = errs[0]
+	}
+	return
+}
+
+//	Determines the archive format from the extension of `archiveFilePath`.
+func archiveFormatOf(archiveFilePath string) (isZip bool, compression *ArchiveCompression, err error) {
+	name := strings.ToLower(filepath.Base(archiveFilePath))
+	ext := path.Ext(name)
+	switch {
+	case ext == ".zip":
+		isZip = true
+	case ext == ".tar":
+	case ext == ".tgz":
+		compression = ArchiveCompressions[".gz"]
+	case ext == ".tbz2" || ext == ".tbz":
+		compression = ArchiveCompressions[".bz2"]
+	case strings.HasSuffix(strings.TrimSuffix(name, ext), ".tar") && ArchiveCompressions[ext] != nil:
+		compression = ArchiveCompressions[ext]
+	default:
+		err = errArchiveFormat
+	}
+	if err == nil && compression == nil && !(isZip || ext == ".tar") {
+		//	`.tgz` or `.tbz2` with the compression since removed from `ArchiveCompressions`
+		err = errArchiveFormat
+	}
+	return
+}
+
+//	Returns the local path for the `/`-separated archive entry `relPath` inside `dstDirPath`,
+//	or `ErrArchiveEntryOutside` if it is absolute or would escape `dstDirPath` via `..`.
+func archiveTargetPath(dstDirPath, relPath string) (dstPath string, err error) {
+	if relPath == ".." || strings.HasPrefix(relPath, "../") || path.IsAbs(relPath) || filepath.IsAbs(filepath.FromSlash(relPath)) || filepath.VolumeName(relPath) != "" {
+		return "", ErrArchiveEntryOutside
+	}
+	dstPath = filepath.Join(dstDirPath, filepath.FromSlash(relPath))
+	if !pathIsIn(dstPath, filepath.Clean(dstDirPath)) {
+		err = ErrArchiveEntryOutside
+	}
+	return
+}
+
+//	Resolves all symlinks in the absolute `fsPath` like `filepath.EvalSymlinks`, except that it evaluates each `..`
+//	after (rather than before) following the symlinks preceding it, just as the OS does, and that non-existent
+//	trailing path components are allowed (and returned lexically cleaned).
+func archiveResolvePath(fsPath string) (resolved string, err error) {
+	sep := string(filepath.Separator)
+	vol := filepath.VolumeName(fsPath)
+	resolved, rest := vol+sep, strings.Split(filepath.ToSlash(fsPath[len(vol):]), "/")
+	for numLinks := 0; len(rest) > 0; {
+		name := rest[0]
+		if rest = rest[1:]; name == "" || name == "." {
+			continue
+		} else if name == ".." {
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+		next := filepath.Join(resolved, name)
+		var fi os.FileInfo
+		if fi, err = os.Lstat(next); os.IsNotExist(err) {
+			return filepath.Join(append([]string{next}, rest...)...), nil
+		} else if err != nil {
+			return
+		} else if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+		var target string
+		if numLinks++; numLinks > 255 {
+			return "", errArchiveSymlinkLoop
+		} else if target, err = os.Readlink(next); err != nil {
+			return
+		}
+		if tvol := filepath.VolumeName(target); tvol != "" || strings.HasPrefix(filepath.ToSlash(target), "/") {
+			if tvol != "" {
+				vol = tvol
+			}
+			resolved, target = vol+sep, target[len(tvol):]
+		}
+		rest = append(strings.Split(filepath.ToSlash(target), "/"), rest...)
+	}
+	return
+}
+
+//	Performs an `io.Copy` from the file at `filePath` into `w`.
+func copyFileTo(w io.Writer, filePath string) (err error) {
+	var file *os.File
+	if file, err = os.Open(filePath); err == nil {
+		defer file.Close()
+		_, err = io.Copy(w, file)
+	}
+	return
+}
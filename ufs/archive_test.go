@@ -0,0 +1,209 @@
+package ufs
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+type testTarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	body     string
+}
+
+func testTar(t *testing.T, entries ...testTarEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0644, Size: int64(len(e.body))}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTarRejectsEscapes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	for _, test := range []struct {
+		name        string
+		preexisting string
+		entries     []testTarEntry
+	}{
+		{name: "dotdot file", entries: []testTarEntry{
+			{name: "../evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{name: "absolute symlink", entries: []testTarEntry{
+			{name: "l", typeflag: tar.TypeSymlink, linkname: "/"},
+			{name: "l/evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{name: "symlink chain", entries: []testTarEntry{
+			{name: "a", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "a/b", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "a/b/evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{name: "symlink dotdot after symlink", entries: []testTarEntry{
+			{name: "d/e/f", typeflag: tar.TypeDir},
+			{name: "deep", typeflag: tar.TypeSymlink, linkname: "d/e/f"},
+			{name: "l", typeflag: tar.TypeSymlink, linkname: "deep/../../../.."},
+			{name: "l/evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{name: "dir through symlink", entries: []testTarEntry{
+			{name: "a", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "a/b", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "a/b/evil", typeflag: tar.TypeDir}}},
+		{name: "file through preexisting symlink", preexisting: "out", entries: []testTarEntry{
+			{name: "out/evil.txt", typeflag: tar.TypeReg, body: "x"}}},
+		{name: "hard link dotdot", entries: []testTarEntry{
+			{name: "h", typeflag: tar.TypeLink, linkname: "../secret.txt"}}},
+		{name: "hard link through symlink chain", entries: []testTarEntry{
+			{name: "a", typeflag: tar.TypeSymlink, linkname: "."},
+			{name: "a/b", typeflag: tar.TypeSymlink, linkname: ".."},
+			{name: "h", typeflag: tar.TypeLink, linkname: "a/b/secret.txt"}}},
+		{name: "hard link through preexisting symlink", preexisting: "out", entries: []testTarEntry{
+			{name: "h", typeflag: tar.TypeLink, linkname: "out/secret.txt"}}},
+		{name: "hard link to relative symlink", entries: []testTarEntry{
+			{name: "p", typeflag: tar.TypeDir},
+			{name: "p/s", typeflag: tar.TypeSymlink, linkname: "../secret.txt"},
+			{name: "h", typeflag: tar.TypeLink, linkname: "p/s"}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			parentDirPath := t.TempDir()
+			dstDirPath, secretFilePath := filepath.Join(parentDirPath, "dst"), filepath.Join(parentDirPath, "secret.txt")
+			if err := os.WriteFile(secretFilePath, []byte("secret"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(dstDirPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if test.preexisting != "" {
+				if err := os.Symlink(parentDirPath, filepath.Join(dstDirPath, test.preexisting)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := ExtractTar(testTar(t, test.entries...), dstDirPath, nil)
+			if err != ErrArchiveEntryOutside {
+				t.Errorf("got error %v, want %v", err, ErrArchiveEntryOutside)
+			}
+			for _, name := range []string{"evil.txt", "evil", "h"} {
+				if _, err := os.Lstat(filepath.Join(parentDirPath, name)); err == nil {
+					t.Errorf("%s was written outside of the target directory", name)
+				}
+			}
+			if _, err := os.Lstat(filepath.Join(dstDirPath, "h")); err == nil {
+				t.Errorf("hard link h was extracted")
+			}
+		})
+	}
+}
+
+func TestExtractTarInside(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+	dstDirPath := t.TempDir()
+	err := ExtractTar(testTar(t,
+		testTarEntry{name: "d/", typeflag: tar.TypeDir},
+		testTarEntry{name: "d/f.txt", typeflag: tar.TypeReg, body: "hello"},
+		testTarEntry{name: "l", typeflag: tar.TypeSymlink, linkname: "d"},
+		testTarEntry{name: "l/g.txt", typeflag: tar.TypeReg, body: "via link"},
+		testTarEntry{name: "h", typeflag: tar.TypeLink, linkname: "d/f.txt"},
+		testTarEntry{name: "r", typeflag: tar.TypeSymlink, linkname: "d/f.txt"},
+		testTarEntry{name: "r", typeflag: tar.TypeReg, body: "replaced"},
+	), dstDirPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"d/f.txt": "hello", "d/g.txt": "via link", "h": "hello", "r": "replaced"} {
+		if data, err := os.ReadFile(filepath.Join(dstDirPath, filepath.FromSlash(name))); err != nil || string(data) != want {
+			t.Errorf("%s: got %q (%v), want %q", name, data, err, want)
+		}
+	}
+	if fi, err := os.Lstat(filepath.Join(dstDirPath, "r")); err != nil || !fi.Mode().IsRegular() {
+		t.Errorf("r: want a regular file replacing the symlink")
+	}
+}
+
+func TestExtractTarSkipsMetaEntries(t *testing.T) {
+	//	as written by `git archive --format=tar`
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "pax_global_header", Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "0123456789abcdef"}},
+		{Name: "src/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "src/main.go", Typeflag: tar.TypeReg, Mode: 0644, Size: 12},
+		{Name: "fifo", Typeflag: tar.TypeFifo, Mode: 0644},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			if _, err := tw.Write([]byte("package main")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dstDirPath := t.TempDir()
+	if err := ExtractTar(&buf, dstDirPath, nil); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dstDirPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 1 || names[0] != "src" {
+		t.Errorf("got %v, want only src", names)
+	}
+	if data, err := os.ReadFile(filepath.Join(dstDirPath, "src", "main.go")); err != nil || string(data) != "package main" {
+		t.Errorf("src/main.go: got %q (%v)", data, err)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	srcDirPath, dstDirPath := t.TempDir(), t.TempDir()
+	files := map[string]string{"a.txt": "A", "sub/b.txt": "BB", "sub/deeper/c.txt": ""}
+	for name, contents := range files {
+		filePath := filepath.Join(srcDirPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, ext := range []string{".zip", ".tar", ".tar.gz"} {
+		archiveFilePath := filepath.Join(t.TempDir(), "archive"+ext)
+		if err := CreateArchive(archiveFilePath, srcDirPath, nil); err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		extractDirPath := filepath.Join(dstDirPath, ext)
+		if err := ExtractArchive(archiveFilePath, extractDirPath, nil); err != nil {
+			t.Fatalf("%s: %v", ext, err)
+		}
+		for name, want := range files {
+			if data, err := os.ReadFile(filepath.Join(extractDirPath, filepath.FromSlash(name))); err != nil || string(data) != want {
+				t.Errorf("%s: %s: got %q (%v), want %q", ext, name, data, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package ufs
+
+import (
+	"container/heap"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	Options for `DiskUsage`.
+type DiskUsageOptions struct {
+	//	If set, dirs/files whose `/`-separated path relative to the walked directory is matched
+	//	(as per `ustr.Matcher.IsMatchPath`, such as `"*.tmp"` or `"node_modules"`) are not counted.
+	Exclude *ustr.Matcher
+
+	//	How many of the largest files to report in `DiskUsageReport.Largest`, defaults to `10` if `0` (none if negative).
+	Largest int
+
+	//	See `DirWalker.FollowSymlinks`. Symlinks to files are also resolved if `true`, else counted with their own size.
+	FollowSymlinks bool
+
+	//	See `DirWalker.Workers`.
+	Workers int
+}
+
+//	A directory in a `DiskUsageReport`, with totals covering all its (non-excluded) contents.
+type DiskUsageDir struct {
+	Name string `json:"name"`
+
+	//	The `/`-separated path relative to the walked directory, `"."` for the walked directory itself.
+	RelPath string `json:"path"`
+
+	//	The total (apparent) size of all files.
+	Size int64 `json:"size"`
+
+	//	The total number of files.
+	Files int `json:"files"`
+
+	//	The total number of sub-directories.
+	Dirs int `json:"dirs"`
+
+	//	The modification times of the newest and oldest files, zero if there are none.
+	Newest time.Time `json:"newest"`
+	Oldest time.Time `json:"oldest"`
+
+	//	The direct sub-directories, sorted by name.
+	SubDirs []*DiskUsageDir `json:"subDirs,omitempty"`
+}
+
+//	A file listed in `DiskUsageReport.Largest`.
+type DiskUsageFile struct {
+	//	The `/`-separated path relative to the walked directory.
+	RelPath string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+//	The totals for all files with the same file-name extension.
+type DiskUsageExt struct {
+	//	The lower-cased extension including its dot (such as `".go"`), or empty for files without any.
+	Ext   string `json:"ext"`
+	Files int    `json:"files"`
+	Size  int64  `json:"size"`
+}
+
+//	Returned by `DiskUsage`.
+type DiskUsageReport struct {
+	//	The walked directory, as passed to `DiskUsage`.
+	DirPath string `json:"dirPath"`
+
+	//	The tree of all directories.
+	Root *DiskUsageDir `json:"root"`
+
+	//	The largest files, largest first.
+	Largest []DiskUsageFile `json:"largest"`
+
+	//	The totals per file-name extension, largest total size first.
+	Extensions []DiskUsageExt `json:"extensions"`
+}
+
+//	The order of `DiskUsageReport.List`.
+type DiskUsageSort uint8
+
+const (
+	//	Largest total size first.
+	DiskUsageBySize DiskUsageSort = iota
+
+	//	Most files first.
+	DiskUsageByFiles
+
+	//	Most recently modified (newest file) first.
+	DiskUsageByNewest
+
+	//	By `RelPath`, parents before their contents.
+	DiskUsageByPath
+)
+
+//	Walks `dirPath` (via a `DirWalker`) and returns the sizes, file counts and modification-time ranges of
+//	all its directories, along with its largest files and per-extension totals. Sizes are apparent sizes,
+//	so sparse files and hard links may add up to more than the actual disk usage.
+//
+//	`opt` may be `nil` to use the defaults.
+func DiskUsage(dirPath string, opt *DiskUsageOptions) (report *DiskUsageReport, errs []error) {
+	if opt == nil {
+		opt = &DiskUsageOptions{}
+	}
+	numLargest := opt.Largest
+	if numLargest == 0 {
+		numLargest = 10
+	}
+	root := &DiskUsageDir{Name: filepath.Base(dirPath), RelPath: "."}
+	report = &DiskUsageReport{DirPath: dirPath, Root: root}
+	dirs, exts, largest := map[string]*DiskUsageDir{".": root}, map[string]*DiskUsageExt{}, &diskUsageLargest{}
+
+	w := NewDirWalker(true, nil, nil)
+	w.VisitSelf, w.Ignore, w.FollowSymlinks, w.Workers = false, opt.Exclude, opt.FollowSymlinks, opt.Workers
+	w.EntryVisitor = func(fullPath string, entry os.DirEntry) (keepWalking bool) {
+		relPath, err := filepath.Rel(dirPath, fullPath)
+		if err != nil {
+			errs = append(errs, err)
+			return true
+		}
+		relPath = filepath.ToSlash(relPath)
+		parent := dirs[path.Dir(relPath)]
+		if parent == nil {
+			return true
+		}
+		if entry.IsDir() {
+			dir := &DiskUsageDir{Name: entry.Name(), RelPath: relPath}
+			dirs[relPath], parent.SubDirs = dir, append(parent.SubDirs, dir)
+			return true
+		}
+		var fi os.FileInfo
+		if entry.Type()&os.ModeSymlink != 0 && opt.FollowSymlinks {
+			fi, err = os.Stat(fullPath)
+		}
+		if fi == nil {
+			fi, err = entry.Info()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			return true
+		}
+		parent.Files, parent.Size = parent.Files+1, parent.Size+fi.Size()
+		parent.addModTimes(fi.ModTime(), fi.ModTime())
+		ext := strings.ToLower(path.Ext(entry.Name()))
+		if exts[ext] == nil {
+			exts[ext] = &DiskUsageExt{Ext: ext}
+		}
+		exts[ext].Files, exts[ext].Size = exts[ext].Files+1, exts[ext].Size+fi.Size()
+		if numLargest > 0 {
+			if heap.Push(largest, DiskUsageFile{RelPath: relPath, Size: fi.Size(), ModTime: fi.ModTime()}); largest.Len() > numLargest {
+				heap.Pop(largest)
+			}
+		}
+		return true
+	}
+	errs = append(errs, w.Walk(dirPath)...)
+
+	root.sum()
+	report.Largest = make([]DiskUsageFile, largest.Len())
+	for i := len(report.Largest) - 1; i >= 0; i-- {
+		report.Largest[i] = heap.Pop(largest).(DiskUsageFile)
+	}
+	report.Extensions = make([]DiskUsageExt, 0, len(exts))
+	for _, ext := range exts {
+		report.Extensions = append(report.Extensions, *ext)
+	}
+	sort.Slice(report.Extensions, func(i, j int) bool {
+		if a, b := report.Extensions[i], report.Extensions[j]; a.Size != b.Size {
+			return a.Size > b.Size
+		} else {
+			return a.Ext < b.Ext
+		}
+	})
+	return
+}
+
+func (me *DiskUsageDir) addModTimes(newest time.Time, oldest time.Time) {
+	if me.Newest.IsZero() || newest.After(me.Newest) {
+		me.Newest = newest
+	}
+	if me.Oldest.IsZero() || oldest.Before(me.Oldest) {
+		me.Oldest = oldest
+	}
+}
+
+//	Adds the totals of all sub-directories (recursively) to those of `me`.
+func (me *DiskUsageDir) sum() {
+	sort.Slice(me.SubDirs, func(i, j int) bool { return me.SubDirs[i].Name < me.SubDirs[j].Name })
+	for _, sub := range me.SubDirs {
+		sub.sum()
+		me.Size, me.Files, me.Dirs = me.Size+sub.Size, me.Files+sub.Files, me.Dirs+1+sub.Dirs
+		if sub.Files > 0 {
+			me.addModTimes(sub.Newest, sub.Oldest)
+		}
+	}
+}
+
+//	Returns all directories in the tree, sorted as specified.
+func (me *DiskUsageReport) List(sortBy DiskUsageSort) (dirs []*DiskUsageDir) {
+	var collect func(*DiskUsageDir)
+	collect = func(dir *DiskUsageDir) {
+		dirs = append(dirs, dir)
+		for _, sub := range dir.SubDirs {
+			collect(sub)
+		}
+	}
+	collect(me.Root)
+	var less func(a, b *DiskUsageDir) bool
+	switch sortBy {
+	case DiskUsageByFiles:
+		less = func(a, b *DiskUsageDir) bool { return a.Files > b.Files }
+	case DiskUsageByNewest:
+		less = func(a, b *DiskUsageDir) bool { return a.Newest.After(b.Newest) }
+	case DiskUsageByPath:
+		return
+	default:
+		less = func(a, b *DiskUsageDir) bool { return a.Size > b.Size }
+	}
+	sort.SliceStable(dirs, func(i, j int) bool { return less(dirs[i], dirs[j]) })
+	return
+}
+
+//	Writes a `du -h`-style listing (a human-readable total size and a path per line, contents before their parents)
+//	of all directories down to `maxDepth` levels below the walked directory (or all of them if `maxDepth` is negative).
+func (me *DiskUsageReport) WriteText(w io.Writer, maxDepth int) (err error) {
+	var write func(*DiskUsageDir, int)
+	write = func(dir *DiskUsageDir, depth int) {
+		if maxDepth < 0 || depth < maxDepth {
+			for _, sub := range dir.SubDirs {
+				if write(sub, depth+1); err != nil {
+					return
+				}
+			}
+		}
+		_, err = io.WriteString(w, formatByteSize(dir.Size)+"\t"+me.displayPath(dir.RelPath)+"\n")
+	}
+	write(me.Root, 0)
+	return
+}
+
+//	Writes a listing of (at most `limit`, if positive) directories sorted as per `List`, one per line,
+//	each with its total size, file count, newest modification time and path, separated by tabs.
+func (me *DiskUsageReport) WriteList(w io.Writer, sortBy DiskUsageSort, limit int) (err error) {
+	dirs := me.List(sortBy)
+	if limit > 0 && limit < len(dirs) {
+		dirs = dirs[:limit]
+	}
+	for _, dir := range dirs {
+		newest := "-"
+		if !dir.Newest.IsZero() {
+			newest = dir.Newest.Format("2006-01-02 15:04")
+		}
+		if _, err = io.WriteString(w, formatByteSize(dir.Size)+"\t"+strconv.Itoa(dir.Files)+"\t"+newest+"\t"+me.displayPath(dir.RelPath)+"\n"); err != nil {
+			return
+		}
+	}
+	return
+}
+
+//	Writes the entire report as indented JSON.
+func (me *DiskUsageReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(me)
+}
+
+func (me *DiskUsageReport) displayPath(relPath string) string {
+	return filepath.Join(me.DirPath, filepath.FromSlash(relPath))
+}
+
+//	Formats `size` much like `du -h` does, such as `"512B"`, `"4.0K"`, `"12M"` or `"1.5G"` (with powers of 1024).
+func formatByteSize(size int64) string {
+	const units = "BKMGTPE"
+	if size < 1024 {
+		return strconv.FormatInt(size, 10) + "B"
+	}
+	f, unit := float64(size), 0
+	for ; f >= 1024 && unit < len(units)-1; unit++ {
+		f /= 1024
+	}
+	if f >= 1023.5 && unit < len(units)-1 {
+		//	would print as "1024", so move on to the next unit
+		f, unit = f/1024, unit+1
+	}
+	if s := strconv.FormatFloat(f, 'f', 1, 64); len(s) == 3 {
+		return s + units[unit:unit+1]
+	}
+	return strconv.FormatFloat(f, 'f', 0, 64) + units[unit:unit+1]
+}
+
+//	A min-heap of `DiskUsageFile`s by size, so that the smallest of the largest can be dropped.
+type diskUsageLargest []DiskUsageFile
+
+func (me diskUsageLargest) Len() int            { return len(me) }
+func (me diskUsageLargest) Less(i, j int) bool  { return me[i].Size < me[j].Size }
+func (me diskUsageLargest) Swap(i, j int)       { me[i], me[j] = me[j], me[i] }
+func (me *diskUsageLargest) Push(x interface{}) { *me = append(*me, x.(DiskUsageFile)) }
+func (me *diskUsageLargest) Pop() (x interface{}) {
+	old := *me
+	x, *me = old[len(old)-1], old[:len(old)-1]
+	return
+}
@@ -0,0 +1,218 @@
+package ufs
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+func testDiskUsageDir(t *testing.T) (dirPath string, base time.Time) {
+	dirPath, base = t.TempDir(), time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	files := map[string]string{
+		"readme.md":          strings.Repeat("r", 10),
+		"src/main.go":        strings.Repeat("m", 300),
+		"src/util/a.go":      strings.Repeat("a", 100),
+		"src/util/B.GO":      strings.Repeat("b", 50),
+		"assets/logo.png":    strings.Repeat("p", 2000),
+		"assets/empty/.keep": "",
+		"tmp/cache.tmp":      strings.Repeat("t", 5000),
+	}
+	testWriteFiles(t, dirPath, files)
+	hours := 0
+	for _, name := range []string{"readme.md", "src/main.go", "src/util/a.go", "src/util/B.GO", "assets/logo.png", "assets/empty/.keep", "tmp/cache.tmp"} {
+		mtime := base.Add(time.Duration(hours) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dirPath, filepath.FromSlash(name)), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		hours++
+	}
+	return
+}
+
+func TestDiskUsage(t *testing.T) {
+	dirPath, base := testDiskUsageDir(t)
+	var exclude ustr.Matcher
+	exclude.AddPatterns("*.tmp")
+	report, errs := DiskUsage(dirPath, &DiskUsageOptions{Exclude: &exclude, Largest: 2})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	hourOf := func(tm time.Time) int {
+		if tm.IsZero() {
+			return -1
+		}
+		return int(tm.Sub(base) / time.Hour)
+	}
+	byPath := map[string]*DiskUsageDir{}
+	for _, dir := range report.List(DiskUsageByPath) {
+		byPath[dir.RelPath] = dir
+	}
+	for _, test := range []struct {
+		relPath                string
+		size                   int64
+		files, dirs            int
+		newestHour, oldestHour int
+	}{
+		{".", 2460, 6, 5, 5, 0},
+		{"src", 450, 3, 1, 3, 1},
+		{"src/util", 150, 2, 0, 3, 2},
+		{"assets", 2000, 2, 1, 5, 4},
+		{"assets/empty", 0, 1, 0, 5, 5},
+		{"tmp", 0, 0, 0, -1, -1},
+	} {
+		dir := byPath[test.relPath]
+		if dir == nil {
+			t.Errorf("%s: missing from the report", test.relPath)
+			continue
+		}
+		if dir.Size != test.size || dir.Files != test.files || dir.Dirs != test.dirs {
+			t.Errorf("%s: got size %d, %d files, %d dirs, want %d, %d, %d", test.relPath, dir.Size, dir.Files, dir.Dirs, test.size, test.files, test.dirs)
+		}
+		if hourOf(dir.Newest) != test.newestHour || hourOf(dir.Oldest) != test.oldestHour {
+			t.Errorf("%s: got newest %v, oldest %v", test.relPath, dir.Newest, dir.Oldest)
+		}
+	}
+	if len(byPath) != 6 {
+		t.Errorf("got %d dirs, want 6", len(byPath))
+	}
+	if names := []string{report.Root.SubDirs[0].Name, report.Root.SubDirs[1].Name, report.Root.SubDirs[2].Name}; !reflect.DeepEqual(names, []string{"assets", "src", "tmp"}) {
+		t.Errorf("sub-directories not sorted by name: %v", names)
+	}
+
+	var largest []string
+	for _, file := range report.Largest {
+		largest = append(largest, file.RelPath)
+	}
+	if want := []string{"assets/logo.png", "src/main.go"}; !reflect.DeepEqual(largest, want) {
+		t.Errorf("Largest: got %v, want %v", largest, want)
+	}
+	want := []DiskUsageExt{{".png", 1, 2000}, {".go", 3, 450}, {".md", 1, 10}, {".keep", 1, 0}}
+	if !reflect.DeepEqual(report.Extensions, want) {
+		t.Errorf("Extensions: got %v, want %v", report.Extensions, want)
+	}
+
+	//	concurrent directory reads make no difference
+	concurrent, errs := DiskUsage(dirPath, &DiskUsageOptions{Exclude: &exclude, Largest: 2, Workers: 4})
+	if len(errs) > 0 || !reflect.DeepEqual(concurrent, report) {
+		t.Errorf("Workers: got a different report, errors %v", errs)
+	}
+	if all, _ := DiskUsage(dirPath, nil); all.Root.Size != 7460 || len(all.Largest) != 7 {
+		t.Errorf("defaults: got size %d, %d largest", all.Root.Size, len(all.Largest))
+	}
+	if none, _ := DiskUsage(dirPath, &DiskUsageOptions{Largest: -1}); len(none.Largest) != 0 {
+		t.Errorf("negative Largest: got %v", none.Largest)
+	}
+}
+
+func TestDiskUsageSymlinks(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"data/big.bin": strings.Repeat("x", 1000)})
+	if err := os.Symlink(filepath.Join("data", "big.bin"), filepath.Join(dirPath, "link.bin")); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	for _, follow := range []bool{false, true} {
+		report, errs := DiskUsage(dirPath, &DiskUsageOptions{FollowSymlinks: follow})
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if size := report.Root.Size - 1000; follow != (size == 1000) || report.Root.Files != 2 {
+			t.Errorf("FollowSymlinks %v: got size %d for %d files", follow, report.Root.Size, report.Root.Files)
+		}
+	}
+}
+
+func TestDiskUsageReportOutput(t *testing.T) {
+	dirPath, _ := testDiskUsageDir(t)
+	report, errs := DiskUsage(dirPath, nil)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	for _, test := range []struct {
+		sortBy DiskUsageSort
+		want   []string
+	}{
+		{DiskUsageBySize, []string{".", "tmp", "assets", "src", "src/util", "assets/empty"}},
+		{DiskUsageByFiles, []string{".", "src", "assets", "src/util", "assets/empty", "tmp"}},
+		{DiskUsageByNewest, []string{".", "tmp", "assets", "assets/empty", "src", "src/util"}},
+		{DiskUsageByPath, []string{".", "assets", "assets/empty", "src", "src/util", "tmp"}},
+	} {
+		var got []string
+		for _, dir := range report.List(test.sortBy) {
+			got = append(got, dir.RelPath)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("List(%d): got %v, want %v", test.sortBy, got, test.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	want := "2.0K\t" + filepath.Join(dirPath, "assets") + "\n" +
+		"450B\t" + filepath.Join(dirPath, "src") + "\n" +
+		"4.9K\t" + filepath.Join(dirPath, "tmp") + "\n" +
+		"7.3K\t" + dirPath + "\n"
+	if buf.String() != want {
+		t.Errorf("WriteText: got %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	if err := report.WriteText(&buf, -1); err != nil || strings.Count(buf.String(), "\n") != 6 {
+		t.Errorf("WriteText of all: got %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := report.WriteList(&buf, DiskUsageBySize, 2); err != nil {
+		t.Fatal(err)
+	}
+	newest := report.Root.Newest.Format("2006-01-02 15:04")
+	want = "7.3K\t7\t" + newest + "\t" + dirPath + "\n" +
+		"4.9K\t1\t" + newest + "\t" + filepath.Join(dirPath, "tmp") + "\n"
+	if buf.String() != want {
+		t.Errorf("WriteList: got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded DiskUsageReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Root.Size != report.Root.Size || len(decoded.Root.SubDirs) != 3 || len(decoded.Extensions) != len(report.Extensions) {
+		t.Errorf("WriteJSON did not round-trip: %s", buf.String())
+	}
+}
+
+func TestFormatByteSize(t *testing.T) {
+	for _, test := range []struct {
+		size int64
+		want string
+	}{
+		{0, "0B"},
+		{1023, "1023B"},
+		{1024, "1.0K"},
+		{1536, "1.5K"},
+		{10239, "10K"},
+		{1048575, "1.0M"},
+		{1 << 20, "1.0M"},
+		{1<<30 - 1<<19, "1.0G"},
+		{1<<30 - 1<<20, "1023M"},
+		{12 << 20, "12M"},
+		{3 << 29, "1.5G"},
+		{1 << 62, "4.0E"},
+	} {
+		if got := formatByteSize(test.size); got != test.want {
+			t.Errorf("formatByteSize(%d): got %q, want %q", test.size, got, test.want)
+		}
+	}
+}
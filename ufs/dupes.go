@@ -0,0 +1,254 @@
+package ufs
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	The number of leading bytes hashed by `FindDupes` before hashing any file in full.
+const dupePartialHashSize = 16 * 1024
+
+//	Options for `FindDupes`.
+type DupeOptions struct {
+	//	Files smaller than this are not considered. Empty files never are.
+	MinSize int64
+
+	//	If greater than `1`, up to this many directories are read and files are hashed concurrently.
+	Workers int
+
+	//	If set, dirs/files whose `/`-separated path relative to their `dirPaths` entry is matched
+	//	(as per `ustr.Matcher.IsMatchPath`) are not considered, and neither is anything inside such dirs.
+	Skip *ustr.Matcher
+}
+
+//	A set of files with identical contents, as returned by `FindDupes`.
+type DupeGroup struct {
+	//	The size of each file.
+	Size int64
+
+	//	The full paths of all the identical files, in lexical order.
+	Paths []string
+}
+
+//	What `Dedupe` does with duplicates.
+type DupeAction uint8
+
+const (
+	//	Each duplicate is replaced by a hard link to the kept file.
+	DupeHardLink DupeAction = iota + 1
+
+	//	Each duplicate is deleted.
+	DupeDelete
+)
+
+//	Returned by `Dedupe`.
+type DedupeReport struct {
+	//	The full paths of all duplicates that were (or, if `dryRun`, would be) replaced or deleted.
+	Deduped []string
+
+	//	The total size of all `Deduped` files.
+	BytesSaved int64
+}
+
+//	Finds all sets of files with identical contents in the specified `dirPaths` (recursively). Candidates are
+//	first grouped by size, then by a hash of their first 16 KiB and only then by a hash of their full contents,
+//	so that most files never need to be read completely. Multiple hard links to the same file count as one
+//	(where the OS exposes inode numbers), as do files found more than once via overlapping `dirPaths`.
+//
+//	`groups` are ordered by descending `DupeGroup.Size` (then by their first path). `opt` may be `nil`.
+func FindDupes(opt *DupeOptions, dirPaths ...string) (groups []DupeGroup, errs []error) {
+	if opt == nil {
+		opt = &DupeOptions{}
+	}
+	var (
+		mutex     sync.Mutex
+		bySize    = map[int64][]string{}
+		byInode   = map[uint64][]os.FileInfo{}
+		seenPaths = map[string]bool{}
+	)
+	for _, dirPath := range dirPaths {
+		w := NewDirWalker(true, nil, nil)
+		w.VisitSelf, w.Ignore, w.Workers, w.Unordered = false, opt.Skip, opt.Workers, true
+		w.EntryVisitor = func(fullPath string, entry os.DirEntry) (keepWalking bool) {
+			if entry.Type().IsRegular() {
+				fi, err := entry.Info()
+				absPath := fullPath
+				if err == nil {
+					absPath, err = filepath.Abs(fullPath)
+				}
+				mutex.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else if size := fi.Size(); !seenPaths[absPath] && size > 0 && size >= opt.MinSize && !isHardLinkOfAny(fi, byInode) {
+					bySize[size] = append(bySize[size], fullPath)
+				}
+				seenPaths[absPath] = true
+				mutex.Unlock()
+			}
+			return true
+		}
+		errs = append(errs, w.Walk(dirPath)...)
+	}
+
+	for size, paths := range bySize {
+		if len(paths) < 2 {
+			continue
+		}
+		candidates, hashErrs := dupeGroupByHash(paths, dupePartialHashSize, opt.Workers)
+		errs = append(errs, hashErrs...)
+		if size > dupePartialHashSize {
+			var full [][]string
+			for _, paths := range candidates {
+				groups, hashErrs := dupeGroupByHash(paths, 0, opt.Workers)
+				full, errs = append(full, groups...), append(errs, hashErrs...)
+			}
+			candidates = full
+		}
+		for _, paths := range candidates {
+			sort.Strings(paths)
+			groups = append(groups, DupeGroup{Size: size, Paths: paths})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size == groups[j].Size {
+			return groups[i].Paths[0] < groups[j].Paths[0]
+		}
+		return groups[i].Size > groups[j].Size
+	})
+	return
+}
+
+//	For each of the `groups` (as returned by `FindDupes`), keeps its first path and replaces all other ones
+//	with hard links to it or deletes them, depending on `action`. If `dryRun`, nothing is modified and the
+//	returned `report` merely describes what would be done. Paths denoting the same file as the kept one
+//	(as per `os.SameFile`, such as hard links to it) are left alone, so that its last copy is never deleted.
+func Dedupe(groups []DupeGroup, action DupeAction, dryRun bool) (report *DedupeReport, errs []error) {
+	report = &DedupeReport{}
+	for _, group := range groups {
+		if len(group.Paths) < 2 {
+			continue
+		}
+		keepInfo, err := os.Stat(group.Paths[0])
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, dupePath := range group.Paths[1:] {
+			var dupeInfo os.FileInfo
+			if dupeInfo, err = os.Stat(dupePath); err == nil && os.SameFile(keepInfo, dupeInfo) {
+				continue
+			}
+			if err == nil && !dryRun {
+				if action == DupeDelete {
+					err = os.Remove(dupePath)
+				} else {
+					err = replaceWithHardLink(group.Paths[0], dupePath)
+				}
+			}
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				report.Deduped, report.BytesSaved = append(report.Deduped, dupePath), report.BytesSaved+group.Size
+			}
+		}
+	}
+	return
+}
+
+//	Groups `paths` by the hash of their first `maxBytes` (or all if `0`) bytes, omitting unique ones.
+func dupeGroupByHash(paths []string, maxBytes int64, workers int) (groups [][]string, errs []error) {
+	var (
+		mutex sync.Mutex
+		wait  sync.WaitGroup
+	)
+	byHash := map[string][]string{}
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan string)
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for filePath := range jobs {
+				sum, err := hashFile(filePath, maxBytes)
+				mutex.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					byHash[string(sum)] = append(byHash[string(sum)], filePath)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, filePath := range paths {
+		jobs <- filePath
+	}
+	close(jobs)
+	wait.Wait()
+	for _, group := range byHash {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return
+}
+
+//	Atomically replaces the file at `dupePath` by a hard link to `keepPath`.
+func replaceWithHardLink(keepPath, dupePath string) (err error) {
+	var tmpPath string
+	for i := 0; i < 10000; i++ {
+		seq := atomic.AddUint32(&atomicWriteSeq, 1)
+		tmpPath = filepath.Join(filepath.Dir(dupePath), "."+filepath.Base(dupePath)+"."+strconv.FormatUint(uint64(seq), 36)+".tmp")
+		if err = os.Link(keepPath, tmpPath); !os.IsExist(err) {
+			break
+		}
+	}
+	if err == nil {
+		if err = os.Rename(tmpPath, dupePath); err != nil {
+			os.Remove(tmpPath)
+		}
+	}
+	return
+}
+
+//	Returns whether `fi` is the same file as any previously seen in `byInode`, else records it there.
+func isHardLinkOfAny(fi os.FileInfo, byInode map[uint64][]os.FileInfo) bool {
+	inode := fileInode(fi)
+	if inode == 0 {
+		return false
+	}
+	for _, other := range byInode[inode] {
+		if os.SameFile(fi, other) {
+			return true
+		}
+	}
+	byInode[inode] = append(byInode[inode], fi)
+	return false
+}
+
+//	Returns the SHA-256 digest of the contents of the file at `filePath`, or only of its first `maxBytes` if greater than `0`.
+func hashFile(filePath string, maxBytes int64) (sum []byte, err error) {
+	var file *os.File
+	if file, err = os.Open(filePath); err == nil {
+		defer file.Close()
+		var r io.Reader = file
+		if maxBytes > 0 {
+			r = io.LimitReader(file, maxBytes)
+		}
+		h := sha256.New()
+		if _, err = io.Copy(h, r); err == nil {
+			sum = h.Sum(nil)
+		}
+	}
+	return
+}
@@ -0,0 +1,94 @@
+package ufs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindDupes(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{
+		"a.txt":       "same",
+		"sub/b.txt":   "same",
+		"sub/c.txt":   "diff",
+		"d.txt":       "other contents",
+		"sub/e.txt":   "other contents",
+		"empty1.txt":  "",
+		"empty2.txt":  "",
+		"unique.txt":  "unique",
+		"sub/x/f.txt": "same",
+	})
+	for _, test := range []struct {
+		name     string
+		dirPaths []string
+	}{
+		{name: "single", dirPaths: []string{dirPath}},
+		{name: "overlapping", dirPaths: []string{dirPath, filepath.Join(dirPath, "sub"), dirPath}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			groups, errs := FindDupes(&DupeOptions{Workers: 2}, test.dirPaths...)
+			if len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			var got []string
+			for _, group := range groups {
+				var relPaths []string
+				for _, p := range group.Paths {
+					relPath, _ := filepath.Rel(dirPath, p)
+					relPaths = append(relPaths, filepath.ToSlash(relPath))
+				}
+				got = append(got, strings.Join(relPaths, ","))
+			}
+			if want := "d.txt,sub/e.txt a.txt,sub/b.txt,sub/x/f.txt"; strings.Join(got, " ") != want {
+				t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	for _, action := range []DupeAction{DupeDelete, DupeHardLink} {
+		dirPath := t.TempDir()
+		testWriteFiles(t, dirPath, map[string]string{"a.txt": "same", "b.txt": "same"})
+		keepPath, dupePath := filepath.Join(dirPath, "a.txt"), filepath.Join(dirPath, "b.txt")
+		groups := []DupeGroup{{Size: 4, Paths: []string{keepPath, dupePath}}}
+
+		report, errs := Dedupe(groups, action, true)
+		if len(errs) > 0 || len(report.Deduped) != 1 || report.BytesSaved != 4 {
+			t.Fatalf("dry run: got %+v (%v)", report, errs)
+		} else if _, err := os.Stat(dupePath); err != nil {
+			t.Fatalf("dry run modified %s: %v", dupePath, err)
+		}
+		if report, errs = Dedupe(groups, action, false); len(errs) > 0 || len(report.Deduped) != 1 {
+			t.Fatalf("got %+v (%v)", report, errs)
+		}
+		keepInfo, _ := os.Stat(keepPath)
+		dupeInfo, err := os.Stat(dupePath)
+		if action == DupeDelete && err == nil {
+			t.Error("duplicate was not deleted")
+		} else if action == DupeHardLink && (err != nil || !os.SameFile(keepInfo, dupeInfo)) {
+			t.Errorf("duplicate was not hard-linked: %v", err)
+		}
+	}
+}
+
+func TestDedupeKeepsLastCopy(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"a.txt": "same"})
+	keepPath := filepath.Join(dirPath, "a.txt")
+	dupePaths := []string{keepPath, filepath.Join(dirPath, ".", "a.txt")}
+	if linkPath := filepath.Join(dirPath, "link.txt"); os.Link(keepPath, linkPath) == nil {
+		dupePaths = append(dupePaths, linkPath)
+	}
+	for _, action := range []DupeAction{DupeDelete, DupeHardLink} {
+		report, errs := Dedupe([]DupeGroup{{Size: 4, Paths: append([]string{keepPath}, dupePaths...)}}, action, false)
+		if len(errs) > 0 || len(report.Deduped) != 0 {
+			t.Errorf("got %+v (%v), want nothing deduped", report, errs)
+		}
+		if data, err := os.ReadFile(keepPath); err != nil || string(data) != "same" {
+			t.Fatalf("kept file is gone: %q, %v", data, err)
+		}
+	}
+}
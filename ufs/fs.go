@@ -2,13 +2,16 @@ package ufs
 
 import (
 	"archive/zip"
+	"encoding/binary"
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/wwsheng009/go-util/umisc"
 	"github.com/wwsheng009/go-util/uslice"
 	"github.com/wwsheng009/go-util/ustr"
 )
@@ -16,8 +19,12 @@ import (
 //	Handles a file-system notification originating in a `Watcher`.
 type WatcherHandler func(path string)
 
+//	Handles a batch of coalesced file-system notifications originating in a `Watcher`.
+type WatcherBatchHandler func(evts []WatchEvent)
+
 var (
-	//	The permission bits used in the `EnsureDirExists`, `WriteBinaryFile` and `WriteTextFile` functions.
+	//	The permission bits used in the `EnsureDirExists`, `WriteBinaryFile` and `WriteTextFile` functions
+	//	(and, if `WriteAtomically`, in `SaveToFile`).
 	ModePerm = os.ModePerm
 )
 
@@ -36,77 +43,38 @@ func PathPrefix(val string, pathprefix string) bool {
 	return strings.HasPrefix(val, pathprefix)
 }
 
-//	Removes anything in `dirPath` (but not `dirPath` itself), except items whose `os.FileInfo.Name` matches any of the specified `keepNamePatterns`.
+//	Returns whether `path` equals `dirPath` or is located somewhere below it.
+func pathIsIn(path, dirPath string) bool {
+	return path == dirPath || PathPrefix(path, dirPath+string(filepath.Separator))
+}
+
+//	Removes anything in `dirPath` (but not `dirPath` itself), except items whose `os.FileInfo.Name` matches any of the specified `keepNamePatterns`
+//	(simple-patterns or, if prefixed with `ustr.GlobPatternPrefix`, `ustr.Glob` patterns, as per `ustr.Matcher.AddPatterns`).
+//	If `RemoveToTrash` is set, the removed items are moved there (via `ClearDirectoryToTrash`).
 func ClearDirectory(dirPath string, keepNamePatterns ...string) (err error) {
-	var fileInfos []os.FileInfo
-	var matcher ustr.Matcher
-	matcher.AddPatterns(keepNamePatterns...)
-	if fileInfos, err = ioutil.ReadDir(dirPath); err == nil {
-		for _, fi := range fileInfos {
-			if fn := fi.Name(); !matcher.IsMatch(fn) {
-				if err = os.RemoveAll(filepath.Join(dirPath, fn)); err != nil {
-					return
-				}
-			}
-		}
+	if RemoveToTrash != nil {
+		_, err = ClearDirectoryToTrash(RemoveToTrash, dirPath, keepNamePatterns...)
+		return
 	}
-	return
+	return FsClearDirectory(OsFileSystem, dirPath, keepNamePatterns...)
 }
 
 //	Removes all directories inside `dirPath`, except those that
 //	contain files or descendent directories that contain files.
+//	If `RemoveToTrash` is set, the removed directories are moved there (via `ClearEmptyDirectoriesToTrash`).
 func ClearEmptyDirectories(dirPath string) (canDelete bool, err error) {
-	var (
-		fi     os.FileInfo
-		subs   []os.FileInfo
-		canDel bool
-		subDir string
-	)
-	canDelete = true
-	if subs, err = ioutil.ReadDir(dirPath); err == nil {
-		for _, fi = range subs {
-			if fi.IsDir() {
-				subDir = filepath.Join(dirPath, fi.Name())
-				if canDel, err = ClearEmptyDirectories(subDir); err != nil {
-					break
-				} else if !canDel {
-					canDelete = false
-				} else if err = os.RemoveAll(subDir); err != nil {
-					break
-				}
-			} else {
-				canDelete = false
-			}
-		}
-	}
-	if err != nil {
-		canDelete = false
+	if RemoveToTrash != nil {
+		_, canDelete, err = ClearEmptyDirectoriesToTrash(RemoveToTrash, dirPath)
+		return
 	}
-	return
+	return FsClearEmptyDirectories(OsFileSystem, dirPath)
 }
 
 //	Copies all files and directories inside `srcDirPath` to `dstDirPath`.
-//	All sub-directories whose `os.FileInfo.Name` is matched by `skipDirs` (optional) are skipped.
+//	All sub-directories matched by `skipDirs` (optional) are skipped: simple-patterns are matched against their `os.FileInfo.Name`,
+//	`ustr.Glob` patterns (see `ustr.Matcher.AddGlobs`) against their `/`-separated path relative to `srcDirPath` (as per `ustr.Matcher.IsMatchPath`).
 func CopyAll(srcDirPath, dstDirPath string, skipDirs *ustr.Matcher, skipFileSuffix string) (err error) {
-	var (
-		srcPath, destPath string
-		fileInfos         []os.FileInfo
-	)
-	if fileInfos, err = ioutil.ReadDir(srcDirPath); err == nil {
-		EnsureDirExists(dstDirPath)
-		for _, fi := range fileInfos {
-			if srcPath, destPath = filepath.Join(srcDirPath, fi.Name()), filepath.Join(dstDirPath, fi.Name()); fi.IsDir() {
-				if skipDirs == nil || !skipDirs.IsMatch(fi.Name()) {
-					if skipFileSuffix == "" || !strings.HasSuffix(srcPath, skipFileSuffix) {
-						CopyAll(srcPath, destPath, skipDirs, skipFileSuffix)
-					}
-				}
-			} else {
-				CopyFile(srcPath, destPath)
-			}
-		}
-	}
-	return
+	return fsCopyAll(OsFileSystem, srcDirPath, dstDirPath, "", skipDirs, skipFileSuffix, CopyFile)
 }
 
 //	Performs an `io.Copy` from the specified source file to the specified destination file.
@@ -122,46 +90,29 @@ func CopyFile(srcFilePath, dstFilePath string) (err error) {
 
 //	Returns whether a directory (not a file) exists at the specified `dirpath`.
 func DirExists(dirpath string) bool {
-	if len(dirpath) == 0 {
-		return false
-	}
-	stat, err := os.Stat(dirpath)
-	return err == nil && stat.IsDir()
+	return FsDirExists(OsFileSystem, dirpath)
 }
 
 //	Returns whether all of the specified `dirOrFileNames` exist in `dirPath`.
 func DirsOrFilesExistIn(dirPath string, dirOrFileNames ...string) bool {
-	var (
-		err  error
-		stat os.FileInfo
-	)
-	for _, name := range dirOrFileNames {
-		if stat, err = os.Stat(filepath.Join(dirPath, name)); err != nil || stat == nil {
-			return false
-		}
-
-	}
-	return true
+	return FsDirsOrFilesExistIn(OsFileSystem, dirPath, dirOrFileNames...)
 }
 
 //	If a directory does not exist at the specified `dirPath`, attempts to create it.
 func EnsureDirExists(dirPath string) (err error) {
-	if !DirExists(dirPath) {
-		if err = EnsureDirExists(filepath.Dir(dirPath)); err == nil {
-			err = os.Mkdir(dirPath, ModePerm)
-		}
-	}
-	return
+	return FsEnsureDirExists(OsFileSystem, dirPath)
 }
 
 //	Extracts a ZIP archive to the local file system.
 //	zipFilePath: full file path to the ZIP archive file.
 //	targetDirPath: directory path where un-zipped archive contents are extracted to.
 //	deleteZipFile: deletes the ZIP archive file upon successful extraction.
+//	Entries that would end up outside `targetDirPath` fail with `ErrArchiveEntryOutside`. See also `ExtractArchive`.
 func ExtractZipFile(zipFilePath, targetDirPath string, deleteZipFile bool, fileNamesPrefix string, fileNamesToExtract ...string) error {
 	var (
 		fnames      []string
 		fnprefix    string
+		efilePath   string
 		efile       *os.File
 		zfile       *zip.File
 		zfileReader io.ReadCloser
@@ -181,7 +132,10 @@ func ExtractZipFile(zipFilePath, targetDirPath string, deleteZipFile bool, fileN
 				if len(fnames) == 0 || uslice.StrHas(fnames, zfile.FileHeader.Name) {
 					if zfileReader, err = zfile.Open(); zfileReader != nil {
 						if err == nil {
-							if efile, err = os.Create(filepath.Join(targetDirPath, fnprefix+zfile.FileHeader.Name)); efile != nil {
+							efilePath, err = archiveTargetPath(targetDirPath, path.Clean(fnprefix+zfile.FileHeader.Name))
+						}
+						if err == nil {
+							if efile, err = os.Create(efilePath); efile != nil {
 								if err == nil {
 									_, err = io.Copy(efile, zfileReader)
 								}
@@ -206,102 +160,38 @@ func ExtractZipFile(zipFilePath, targetDirPath string, deleteZipFile bool, fileN
 
 //	Returns whether a file (not a directory) exists at the specified `filePath`.
 func FileExists(filePath string) bool {
-	stat, err := os.Stat(filePath)
-	return err == nil && stat.Mode().IsRegular()
+	return FsFileExists(OsFileSystem, filePath)
 }
 
-/*
 //	If a file with a given base-name and one of a set of extensions exists in the specified directory, returns details on it.
 //	The tryLower and tryUpper flags also test for upper-case and lower-case variants of the specified fileBaseName.
 func FindFileInfo(dirPath string, fileBaseName string, fileExts []string, tryLower bool, tryUpper bool) (fullFilePath string, fileInfo *os.FileInfo) {
-	var (
-		stat        os.FileInfo
-		err         error
-		fext, fpath string
-	)
-	for _, fext = range fileExts {
-		fpath = filepath.Join(dirPath, fileBaseName+fext)
-		if stat, err = os.Stat(fpath); err != nil {
-			if tryUpper {
-				fpath = filepath.Join(dirPath, strings.ToUpper(fileBaseName)+fext)
-				stat, err = os.Stat(fpath)
-			}
-			if (err != nil) && tryLower {
-				fpath = filepath.Join(dirPath, strings.ToLower(fileBaseName)+fext)
-				stat, err = os.Stat(fpath)
-			}
-		}
-		if (err == nil) && !stat.IsDir() {
-			return fpath, &stat
-		}
-	}
-	return "", nil
+	return FsFindFileInfo(OsFileSystem, dirPath, fileBaseName, fileExts, tryLower, tryUpper)
 }
-*/
 
 //	Returns whether `srcFilePath` has been modified later than `dstFilePath`.
 //
 //	NOTE: be aware that `newer` will be returned as `true` if `err` is returned as *not* `nil`,
 //	since that is often more convenient for many use-cases.
 func IsNewerThan(srcFilePath, dstFilePath string) (newer bool, err error) {
-	var out, src os.FileInfo
-	newer = true
-	if out, err = os.Stat(dstFilePath); err == nil && out != nil {
-		if src, err = os.Stat(srcFilePath); err == nil && src != nil {
-			newer = src.ModTime().UnixNano() > out.ModTime().UnixNano() //|| (out.Size() == 0 && src.Size() != 0)
-		}
-	}
-	return
+	return FsIsNewerThan(OsFileSystem, srcFilePath, dstFilePath)
 }
 
 func IsNewerThanTime(srcFilePath string, time int64) (newer bool, err error) {
-	var src os.FileInfo
-	if newer = true; time > 0 {
-		if src, err = os.Stat(srcFilePath); err == nil && src != nil {
-			newer = src.ModTime().UnixNano() > time
-		}
-	}
-	return
+	return FsIsNewerThanTime(OsFileSystem, srcFilePath, time)
 }
 
 func AllFilePathsIn(dirpath string, ignoresubpath string) (filepaths []string) {
-	if len(ignoresubpath) > 0 && !strings.HasPrefix(ignoresubpath, dirpath) {
-		ignoresubpath = filepath.Join(dirpath, ignoresubpath)
-	}
-	WalkAllFiles(dirpath, func(disfilepath string) (keepWalking bool) {
-		if !strings.HasPrefix(disfilepath, ignoresubpath) {
-			filepaths = append(filepaths, disfilepath)
-		}
-		return true
-	})
-	return
+	return FsAllFilePathsIn(OsFileSystem, dirpath, ignoresubpath)
 }
 
 func IsAnyInNewerThanAnyOf(dirpath string, filepaths ...string) (isAnyNewer bool) {
-	var cmpfiletimeoldest int64 = 0
-	if len(filepaths) == 0 {
-		return true
-	}
-	for _, fp := range filepaths {
-		if cmpfile, err := os.Stat(fp); err != nil || cmpfile == nil {
-			return true
-		} else if modtime := cmpfile.ModTime().UnixNano(); modtime > 0 && (cmpfiletimeoldest == 0 || modtime < cmpfiletimeoldest) {
-			cmpfiletimeoldest = modtime
-		}
-	}
-	if errs := WalkAllFiles(dirpath, func(disfilepath string) (keepWalking bool) {
-		if !uslice.StrHas(filepaths, disfilepath) {
-			disfile, err := os.Stat(disfilepath)
-			if err != nil || disfile == nil || disfile.ModTime().UnixNano() > cmpfiletimeoldest {
-				isAnyNewer = true
-			}
-		}
-		keepWalking = !isAnyNewer
-		return
-	}); len(errs) > 0 {
-		return true
-	}
-	return
+	return FsIsAnyInNewerThanAnyOf(OsFileSystem, dirpath, filepaths...)
+}
+
+//	Like `umisc.JsonEncodeToFile`, but writes via `WriteAtomic` if `WriteAtomically` is set.
+func JsonEncodeToFile(from interface{}, toFilePath string) error {
+	return umisc.JsonEncodeToFileWith(from, toFilePath, writeFile)
 }
 
 //	Applies all specified `patterns` to `filepath.Match` and returns the first
@@ -322,16 +212,21 @@ func MatchesAny(name string, patterns ...string) (matchingPattern string, err er
 	return
 }
 
+//	Reads the `.gitignore`-style file at `filePath` into a new `ustr.Matcher` (see `ustr.ParseGitIgnore`),
+//	for example to be used as a `DirWalker.Ignore` or as `CopyAll`'s `skipDirs`.
+func LoadGitIgnore(filePath string) (matcher *ustr.Matcher, err error) {
+	var src string
+	if err = ReadFileIntoStr(filePath, &src); err == nil {
+		matcher = ustr.ParseGitIgnore(src)
+	}
+	return
+}
+
 //	Reads and returns the binary contents of a file with non-idiomatic error handling, mostly for one-off `package main`s.
 func ReadBinaryFile(filePath string, panicOnError bool) []byte {
-	bytes, err := ioutil.ReadFile(filePath)
-	if panicOnError && (err != nil) {
-		panic(err)
-	}
-	return bytes
+	return FsReadBinaryFile(OsFileSystem, filePath, panicOnError)
 }
 
-/*
 //	Reads binary data into the specified interface{} from the specified io.ReadSeeker at the specified offset using the specified binary.ByteOrder.
 //	Returns false if data could not be successfully read as specified, otherwise true.
 func ReadFromBinary(readSeeker io.ReadSeeker, offset int64, byteOrder binary.ByteOrder, ptr interface{}) bool {
@@ -344,26 +239,14 @@ func ReadFromBinary(readSeeker io.ReadSeeker, offset int64, byteOrder binary.Byt
 	}
 	return true
 }
-*/
 
 //	Reads and returns the contents of a text file with non-idiomatic error handling, mostly for one-off `package main`s.
 func ReadTextFile(filePath string, panicOnError bool, defaultValue string) string {
-	bytes, err := ioutil.ReadFile(filePath)
-	if err == nil {
-		return string(bytes)
-	}
-	if panicOnError && (err != nil) {
-		panic(err)
-	}
-	return defaultValue
+	return FsReadTextFile(OsFileSystem, filePath, panicOnError, defaultValue)
 }
 
 func ReadFileIntoStr(filePath string, contents *string) error {
-	bytes, err := ioutil.ReadFile(filePath)
-	if err == nil {
-		*contents = string(bytes)
-	}
-	return err
+	return FsReadFileIntoStr(OsFileSystem, filePath, contents)
 }
 
 func SanitizeFsName(name string) string {
@@ -381,7 +264,11 @@ func SanitizeFsName(name string) string {
 }
 
 //	Performs an `io.Copy` from the specified `io.Reader` to the specified local file.
+//	If `WriteAtomically` is set, this is done via `SaveToFileAtomic`.
 func SaveToFile(src io.Reader, dstFilePath string) (err error) {
+	if WriteAtomically != nil {
+		return SaveToFileAtomic(src, dstFilePath, WriteAtomically)
+	}
 	var file *os.File
 	if file, err = os.Create(dstFilePath); file != nil {
 		defer file.Close()
@@ -394,50 +281,59 @@ func SaveToFile(src io.Reader, dstFilePath string) (err error) {
 
 //	Calls `visitor` for `dirPath` and all descendent directories (but not files).
 func WalkAllDirs(dirPath string, visitor WalkerVisitor) []error {
-	return NewDirWalker(true, visitor, nil).Walk(dirPath)
+	return FsWalkAllDirs(OsFileSystem, dirPath, visitor)
 }
 
 //	Calls `visitor` for all files (but not directories) directly or indirectly descendent to `dirPath`.
 func WalkAllFiles(dirPath string, visitor WalkerVisitor) []error {
-	return NewDirWalker(true, nil, visitor).Walk(dirPath)
+	return FsWalkAllFiles(OsFileSystem, dirPath, visitor)
 }
 
 //	Calls `visitor` for all directories (but not files) in `dirPath`, but not their sub-directories and not `dirPath` itself.
 func WalkDirsIn(dirPath string, visitor WalkerVisitor) []error {
-	w := NewDirWalker(false, visitor, nil)
-	w.VisitSelf = false
-	return w.Walk(dirPath)
+	return FsWalkDirsIn(OsFileSystem, dirPath, visitor)
 }
 
 //	Calls `visitor` for all files (but not directories) directly inside `dirPath`, but not for any inside sub-directories.
 func WalkFilesIn(dirPath string, visitor WalkerVisitor) []error {
-	w := NewDirWalker(false, nil, visitor)
-	w.VisitSelf = false
-	return w.Walk(dirPath)
+	return FsWalkFilesIn(OsFileSystem, dirPath, visitor)
 }
 
-//	A short-hand for `ioutil.WriteFile` using `ModePerm`.
+//	A short-hand for `ioutil.WriteFile` using `ModePerm` (or for `WriteFileAtomic` if `WriteAtomically` is set).
 //	Also ensures the target file's directory exists.
 func WriteBinaryFile(filePath string, contents []byte) error {
 	EnsureDirExists(filepath.Dir(filePath))
+	if WriteAtomically != nil {
+		return WriteFileAtomic(filePath, contents, WriteAtomically)
+	}
 	return ioutil.WriteFile(filePath, contents, ModePerm)
 }
 
-//	A short-hand for `ioutil.WriteFile`, using `ModePerm`.
+//	A short-hand for `WriteBinaryFile`.
 //	Also ensures the target file's directory exists.
 func WriteTextFile(filePath, contents string) error {
 	return WriteBinaryFile(filePath, []byte(contents))
 }
 
-func watchRunHandler(dirPath string, namePattern ustr.Pattern, handler WatcherHandler) []error {
+//	Matches the path of `fullPath` relative to `dirPath` against `namePattern` (as per `ustr.Pattern.IsMatchPath`).
+func watchIsMatch(dirPath string, namePattern ustr.Pattern, fullPath string) bool {
+	relPath, err := filepath.Rel(dirPath, fullPath)
+	if err != nil {
+		return false
+	}
+	isDir := strings.HasSuffix(string(namePattern), "/") && DirExists(fullPath)
+	return namePattern.IsMatchPath(filepath.ToSlash(relPath), isDir)
+}
+
+func watchRunHandler(dirPath string, namePattern ustr.Pattern, deep bool, handler WatcherHandler) []error {
 	vis := func(fullPath string) (keepWalking bool) {
 		keepWalking = true
-		if namePattern.IsMatch(filepath.Base(fullPath)) {
+		if watchIsMatch(dirPath, namePattern, fullPath) {
 			handler(fullPath)
 		}
 		return
 	}
-	w := NewDirWalker(false, vis, vis)
+	w := NewDirWalker(deep, vis, vis)
 	w.VisitSelf = false
 	w.VisitDirsFirst = true
 	return w.Walk(dirPath)
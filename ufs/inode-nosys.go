@@ -0,0 +1,13 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package ufs
+
+import (
+	"os"
+)
+
+//	Returns the inode number of `fi`, or `0` where not available.
+func fileInode(fi os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ufs
+
+import (
+	"os"
+	"syscall"
+)
+
+//	Returns the inode number of `fi`, or `0` where not available.
+func fileInode(fi os.FileInfo) uint64 {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && st != nil {
+		return uint64(st.Ino)
+	}
+	return 0
+}
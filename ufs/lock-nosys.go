@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!windows
+
+package ufs
+
+import (
+	"os"
+)
+
+func lockFile(file *os.File, exclusive bool, block bool) (bool, error) {
+	return false, ErrFileLockUnsupported
+}
+
+func unlockFile(file *os.File) error {
+	return ErrFileLockUnsupported
+}
+
+//	Can't tell here, so errs on the safe side.
+func processExists(pid int) bool {
+	return true
+}
@@ -0,0 +1,67 @@
+//go:build windows
+// +build windows
+
+package ufs
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errorLockViolation syscall.Errno = 33
+
+	//	the locked byte lies far beyond any content, so that locking doesn't prevent other processes from reading it
+	lockOffsetHigh = 0x40000000
+
+	processQueryLimitedInformation = 0x1000
+	stillActive                    = 259
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+//	Returns `false` (and no error) if `!block` and the lock is held elsewhere.
+func lockFile(file *os.File, exclusive bool, block bool) (locked bool, err error) {
+	var flags uint32
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	if !block {
+		flags |= lockfileFailImmediately
+	}
+	overlapped := syscall.Overlapped{OffsetHigh: lockOffsetHigh}
+	if ok, _, errno := procLockFileEx.Call(file.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped))); ok != 0 {
+		return true, nil
+	} else if errno == errorLockViolation {
+		return false, nil
+	} else {
+		return false, errno
+	}
+}
+
+func unlockFile(file *os.File) error {
+	overlapped := syscall.Overlapped{OffsetHigh: lockOffsetHigh}
+	if ok, _, errno := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&overlapped))); ok == 0 {
+		return errno
+	}
+	return nil
+}
+
+func processExists(pid int) bool {
+	proc, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		//	access denied means it exists, but belongs to another user
+		return err == syscall.ERROR_ACCESS_DENIED
+	}
+	defer syscall.CloseHandle(proc)
+	var exitCode uint32
+	return syscall.GetExitCodeProcess(proc, &exitCode) == nil && exitCode == stillActive
+}
@@ -0,0 +1,285 @@
+package ufs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	//	Reported (wrapped, check via `errors.Is`) by `AcquirePidFile` when the PID file is held by another process.
+	//	`FileLock.TryLock` and `FileLock.TryRLock` instead report contention by returning `false` (and no error).
+	ErrFileLocked = errors.New("ufs: file is locked")
+
+	//	Reported by `FileLock` operations on platforms without advisory file locks.
+	ErrFileLockUnsupported = errors.New("ufs: file locks not supported on this platform")
+
+	errFileLockHeld    = errors.New("ufs: file lock already held")
+	errFileLockNotHeld = errors.New("ufs: file lock not held")
+)
+
+//	An advisory, cross-process lock on a file: `flock` on Linux, macOS and the BSDs, `LockFileEx` on Windows.
+//	Being advisory, it only guards against other processes (or other `FileLock`s in the same process) also
+//	using it. A `FileLock` is safe for concurrent use, but holds at most one lock at a time: while one call waits for
+//	the lock, others (such as `IsLocked` or `Unlock`) don't block, and of two concurrent lockers only the first to
+//	succeed keeps it, the other getting an error.
+//
+//	Lock a dedicated lock file (such as `cacheFilePath + ".lock"`) rather than the file being guarded:
+//	`WriteAtomic` (and thus `WriteAtomically`) replaces files, so a lock on the old file would no longer apply.
+type FileLock struct {
+	filePath  string
+	file      *os.File
+	exclusive bool
+	mutex     sync.Mutex
+}
+
+//	Returns a new `FileLock` for `filePath`, which (along with its directory) gets created on the first locking attempt
+//	if it doesn't exist yet. It is never removed, since that would defeat the lock for concurrent lockers.
+func NewFileLock(filePath string) *FileLock {
+	return &FileLock{filePath: filePath}
+}
+
+//	Returns the path of the lock file.
+func (me *FileLock) Path() string {
+	return me.filePath
+}
+
+//	Returns whether a lock is currently held, and if so, whether it's exclusive.
+func (me *FileLock) IsLocked() (locked bool, exclusive bool) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.file != nil, me.exclusive
+}
+
+//	Acquires an exclusive lock, blocking until no other process holds a (shared or exclusive) lock.
+func (me *FileLock) Lock() (err error) {
+	_, err = me.lock(true, -1)
+	return
+}
+
+//	Acquires a shared lock, blocking until no other process holds an exclusive lock.
+func (me *FileLock) RLock() (err error) {
+	_, err = me.lock(false, -1)
+	return
+}
+
+//	Attempts to acquire an exclusive lock, retrying for up to `timeout` (or just once, if `timeout` is `0`).
+//	Returns `false` (and no error) if the lock is still held by another process by then.
+func (me *FileLock) TryLock(timeout time.Duration) (locked bool, err error) {
+	return me.lock(true, timeout)
+}
+
+//	Attempts to acquire a shared lock, retrying for up to `timeout` (or just once, if `timeout` is `0`).
+//	Returns `false` (and no error) if an exclusive lock is still held by another process by then.
+func (me *FileLock) TryRLock(timeout time.Duration) (locked bool, err error) {
+	return me.lock(false, timeout)
+}
+
+//	Releases the lock acquired via `Lock`, `RLock`, `TryLock` or `TryRLock`.
+func (me *FileLock) Unlock() (err error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.file == nil {
+		return errFileLockNotHeld
+	}
+	err = unlockFile(me.file)
+	if errclose := me.file.Close(); err == nil {
+		err = errclose
+	}
+	me.file = nil
+	return
+}
+
+//	A negative `timeout` blocks indefinitely. `me.mutex` is only held to check and publish `me.file`, not while waiting.
+func (me *FileLock) lock(exclusive bool, timeout time.Duration) (locked bool, err error) {
+	if held, _ := me.IsLocked(); held {
+		return false, errFileLockHeld
+	}
+	var file *os.File
+	if file, err = os.OpenFile(me.filePath, os.O_RDWR|os.O_CREATE, ModePerm); os.IsNotExist(err) {
+		if err = EnsureDirExists(filepath.Dir(me.filePath)); err == nil {
+			file, err = os.OpenFile(me.filePath, os.O_RDWR|os.O_CREATE, ModePerm)
+		}
+	}
+	if err != nil {
+		return
+	}
+	if timeout < 0 {
+		locked, err = lockFile(file, exclusive, true)
+	} else {
+		deadline, delay := time.Now().Add(timeout), time.Millisecond
+		for locked, err = lockFile(file, exclusive, false); err == nil && !locked; locked, err = lockFile(file, exclusive, false) {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				break
+			} else if delay > remaining {
+				delay = remaining
+			}
+			time.Sleep(delay)
+			if delay *= 2; delay > 100*time.Millisecond {
+				delay = 100 * time.Millisecond
+			}
+		}
+	}
+	if locked && err == nil {
+		me.mutex.Lock()
+		defer me.mutex.Unlock()
+		if me.file == nil {
+			me.file, me.exclusive = file, exclusive
+			return
+		}
+		//	a concurrent call got (and keeps) the lock first
+		locked, err = false, errFileLockHeld
+		unlockFile(file)
+	}
+	file.Close()
+	if err != nil && err != errFileLockHeld {
+		err = &os.PathError{Op: "lock", Path: me.filePath, Err: err}
+	}
+	return
+}
+
+//	Runs `do` while holding a lock on `lockFilePath`: exclusive if `exclusive`, else shared.
+//	Blocks until the lock is acquired, and releases it even if `do` panics.
+func WithFileLock(lockFilePath string, exclusive bool, do func() error) (err error) {
+	lock := NewFileLock(lockFilePath)
+	if exclusive {
+		err = lock.Lock()
+	} else {
+		err = lock.RLock()
+	}
+	if err == nil {
+		defer func() {
+			if errunlock := lock.Unlock(); err == nil {
+				err = errunlock
+			}
+		}()
+		err = do()
+	}
+	return
+}
+
+//	A PID lock file held via `AcquirePidFile`, ensuring that only a single process (such as a daemon) runs at a time.
+type PidFile struct {
+	filePath string
+	lock     *FileLock
+	released bool
+}
+
+//	Returned by `AcquirePidFile` if the PID file is held by another live process. Satisfies `errors.Is(err, ErrFileLocked)`.
+type PidFileLockedError struct {
+	FilePath string
+
+	//	The ID of the process holding the PID file, or `0` if it couldn't be read.
+	Pid int
+}
+
+func (me *PidFileLockedError) Error() string {
+	if me.Pid > 0 {
+		return "ufs: " + me.FilePath + " is locked by process " + strconv.Itoa(me.Pid)
+	}
+	return "ufs: " + me.FilePath + " is locked by another process"
+}
+
+func (me *PidFileLockedError) Is(err error) bool {
+	return err == ErrFileLocked
+}
+
+//	Creates (or reclaims) the PID file at `filePath`, writes the current process ID into it and keeps it locked
+//	until `Release`. If another live process holds it, a `*PidFileLockedError` is returned.
+//
+//	Stale PID files left behind by crashed processes are reclaimed: the lock is released by the OS when its holder
+//	dies, and where no file locks are available, the recorded process ID is checked for a running process instead.
+func AcquirePidFile(filePath string) (me *PidFile, err error) {
+	for attempt := 0; attempt < 8; attempt++ {
+		lock := NewFileLock(filePath)
+		var locked bool
+		if locked, err = lock.TryLock(0); errors.Is(err, ErrFileLockUnsupported) {
+			lock, locked, err = nil, true, nil
+		}
+		if err != nil {
+			return
+		} else if !locked {
+			pid, _, _ := ReadPidFile(filePath)
+			return nil, &PidFileLockedError{FilePath: filePath, Pid: pid}
+		}
+		if lock != nil && !lock.isCurrent() {
+			//	the PID file got removed (and maybe recreated) by its `Release` in between our opening and locking it
+			lock.Unlock()
+			continue
+		}
+		if lock == nil {
+			if pid, alive, _ := ReadPidFile(filePath); alive && pid != os.Getpid() {
+				return nil, &PidFileLockedError{FilePath: filePath, Pid: pid}
+			}
+			err = WriteFileAtomic(filePath, []byte(strconv.Itoa(os.Getpid())+"\n"), nil)
+		} else {
+			err = writePid(lock.file)
+		}
+		if err != nil {
+			if lock != nil {
+				lock.Unlock()
+			}
+			return nil, err
+		}
+		return &PidFile{filePath: filePath, lock: lock}, nil
+	}
+	return nil, &PidFileLockedError{FilePath: filePath}
+}
+
+//	Returns the process ID recorded in the PID file at `filePath` and whether a process by that ID is currently running.
+//	(Where that can't be determined, any positive ID is reported as running.)
+func ReadPidFile(filePath string) (pid int, alive bool, err error) {
+	var data []byte
+	if data, err = os.ReadFile(filePath); err == nil {
+		if pid, err = strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid > 0 {
+			alive = processExists(pid)
+		}
+	}
+	return
+}
+
+//	Returns the path of the PID file.
+func (me *PidFile) Path() string {
+	return me.filePath
+}
+
+//	Removes the PID file and releases its lock. If the file can't be removed while open (as on Windows), it's emptied instead.
+func (me *PidFile) Release() (err error) {
+	if me.released {
+		return errFileLockNotHeld
+	}
+	if me.released = true; me.lock == nil {
+		return os.Remove(me.filePath)
+	}
+	if os.Remove(me.filePath) != nil {
+		err = me.lock.file.Truncate(0)
+	}
+	if errunlock := me.lock.Unlock(); err == nil {
+		err = errunlock
+	}
+	return
+}
+
+//	Returns whether the locked file is still the one at the lock's path.
+func (me *FileLock) isCurrent() bool {
+	locked, errlocked := me.file.Stat()
+	current, errcurrent := os.Stat(me.filePath)
+	return errlocked == nil && errcurrent == nil && os.SameFile(locked, current)
+}
+
+func writePid(file *os.File) (err error) {
+	if err = file.Truncate(0); err == nil {
+		if _, err = file.Seek(0, io.SeekStart); err == nil {
+			if _, err = file.WriteString(strconv.Itoa(os.Getpid()) + "\n"); err == nil {
+				err = file.Sync()
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,252 @@
+package ufs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testSkipWithoutFileLocks(t *testing.T) {
+	lock := NewFileLock(filepath.Join(t.TempDir(), "probe.lock"))
+	if _, err := lock.TryLock(0); errors.Is(err, ErrFileLockUnsupported) {
+		t.Skip(err)
+	}
+	lock.Unlock()
+}
+
+func TestFileLock(t *testing.T) {
+	testSkipWithoutFileLocks(t)
+	filePath := filepath.Join(t.TempDir(), "not", "yet", "there.lock")
+	for _, test := range []struct {
+		heldExclusive, tryExclusive bool
+		wantLocked                  bool
+	}{
+		{false, false, true},
+		{false, true, false},
+		{true, false, false},
+		{true, true, false},
+	} {
+		held, other := NewFileLock(filePath), NewFileLock(filePath)
+		var err error
+		if test.heldExclusive {
+			err = held.Lock()
+		} else {
+			err = held.RLock()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked, exclusive := held.IsLocked(); !locked || exclusive != test.heldExclusive {
+			t.Errorf("IsLocked: got %v, %v", locked, exclusive)
+		}
+		var locked bool
+		if test.tryExclusive {
+			locked, err = other.TryLock(0)
+		} else {
+			locked, err = other.TryRLock(0)
+		}
+		if err != nil || locked != test.wantLocked {
+			t.Errorf("held exclusive %v, try exclusive %v: got %v (%v), want %v", test.heldExclusive, test.tryExclusive, locked, err, test.wantLocked)
+		}
+		if locked {
+			other.Unlock()
+		}
+		if err = held.Unlock(); err != nil {
+			t.Error(err)
+		}
+		if locked, _ := held.IsLocked(); locked {
+			t.Error("IsLocked after Unlock")
+		}
+	}
+
+	lock := NewFileLock(filePath)
+	if err := lock.Unlock(); err == nil {
+		t.Error("Unlock without a lock held: expected an error")
+	}
+	if err := lock.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lock.TryRLock(0); err == nil {
+		t.Error("locking twice: expected an error")
+	}
+	lock.Unlock()
+	if !FileExists(filePath) {
+		t.Error("lock file was removed")
+	}
+}
+
+func TestFileLockTimeout(t *testing.T) {
+	testSkipWithoutFileLocks(t)
+	filePath := filepath.Join(t.TempDir(), "file.lock")
+	held := NewFileLock(filePath)
+	if err := held.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if locked, err := NewFileLock(filePath).TryLock(50 * time.Millisecond); locked || err != nil {
+		t.Fatalf("TryLock while held: got %v, %v", locked, err)
+	} else if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("TryLock gave up after only %v", waited)
+	}
+
+	time.AfterFunc(30*time.Millisecond, func() { held.Unlock() })
+	other := NewFileLock(filePath)
+	if locked, err := other.TryRLock(testWatchTimeout); !locked || err != nil {
+		t.Fatalf("TryRLock once released: got %v, %v", locked, err)
+	}
+	other.Unlock()
+}
+
+func TestFileLockWaitDoesNotBlock(t *testing.T) {
+	testSkipWithoutFileLocks(t)
+	filePath := filepath.Join(t.TempDir(), "file.lock")
+	held, waiter := NewFileLock(filePath), NewFileLock(filePath)
+	if err := held.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- waiter.Lock() }()
+	time.Sleep(20 * time.Millisecond)
+
+	//	neither querying nor (mistakenly) unlocking the waiting `FileLock` waits along with it
+	answered := make(chan bool, 1)
+	go func() {
+		locked, _ := waiter.IsLocked()
+		answered <- locked || waiter.Unlock() == nil
+	}()
+	select {
+	case wrong := <-answered:
+		if wrong {
+			t.Error("waiting FileLock reported as held")
+		}
+	case <-time.After(testWatchTimeout):
+		t.Fatal("IsLocked blocked while Lock waited")
+	}
+
+	held.Unlock()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if locked, exclusive := waiter.IsLocked(); !locked || !exclusive {
+		t.Errorf("IsLocked once acquired: got %v, %v", locked, exclusive)
+	}
+	waiter.Unlock()
+}
+
+func TestWithFileLock(t *testing.T) {
+	testSkipWithoutFileLocks(t)
+	filePath := filepath.Join(t.TempDir(), "file.lock")
+	var writers, readers, violations int32
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(exclusive bool) {
+			defer wg.Done()
+			err := WithFileLock(filePath, exclusive, func() error {
+				if exclusive {
+					if atomic.AddInt32(&writers, 1) > 1 || atomic.LoadInt32(&readers) > 0 {
+						atomic.AddInt32(&violations, 1)
+					}
+					time.Sleep(time.Millisecond)
+					atomic.AddInt32(&writers, -1)
+				} else {
+					atomic.AddInt32(&readers, 1)
+					if atomic.LoadInt32(&writers) > 0 {
+						atomic.AddInt32(&violations, 1)
+					}
+					time.Sleep(time.Millisecond)
+					atomic.AddInt32(&readers, -1)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i%4 == 0)
+	}
+	if wg.Wait(); violations > 0 {
+		t.Error("an exclusive lock was held alongside another lock")
+	}
+
+	errDo := errors.New("do failed")
+	if err := WithFileLock(filePath, true, func() error { return errDo }); err != errDo {
+		t.Errorf("got %v, want the error of do", err)
+	}
+	func() {
+		defer func() { recover() }()
+		WithFileLock(filePath, true, func() error { panic("do panicked") })
+	}()
+	if locked, err := NewFileLock(filePath).TryLock(0); !locked || err != nil {
+		t.Errorf("lock not released after a panic: got %v, %v", locked, err)
+	}
+}
+
+func TestPidFile(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "run", "daemon.pid")
+	pidFile, err := AcquirePidFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, alive, err := ReadPidFile(filePath); pid != os.Getpid() || !alive || err != nil {
+		t.Errorf("ReadPidFile: got %d, %v, %v", pid, alive, err)
+	}
+
+	testSkipWithoutFileLocks(t)
+	_, err = AcquirePidFile(filePath)
+	var errLocked *PidFileLockedError
+	if !errors.Is(err, ErrFileLocked) || !errors.As(err, &errLocked) || errLocked.Pid != os.Getpid() {
+		t.Errorf("acquiring a held PID file: got %v", err)
+	}
+
+	if err = pidFile.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if FileExists(filePath) {
+		t.Error("PID file not removed on Release")
+	}
+	if err = pidFile.Release(); err == nil {
+		t.Error("second Release: expected an error")
+	}
+
+	//	a PID file left behind by a crashed process gets reclaimed
+	if err = os.WriteFile(filePath, []byte("2147483646\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if pid, alive, _ := ReadPidFile(filePath); pid != 2147483646 || alive {
+		t.Skipf("can't tell whether process %d is running", pid)
+	}
+	if pidFile, err = AcquirePidFile(filePath); err != nil {
+		t.Fatal(err)
+	}
+	defer pidFile.Release()
+	if data, _ := os.ReadFile(filePath); string(data) != strconv.Itoa(os.Getpid())+"\n" {
+		t.Errorf("stale PID file not rewritten: got %q", data)
+	}
+}
+
+func TestReadPidFile(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"garbage.pid": "not a pid", "zero.pid": "0", "self.pid": " " + strconv.Itoa(os.Getpid()) + "\r\n"})
+	for _, test := range []struct {
+		name    string
+		pid     int
+		alive   bool
+		wantErr bool
+	}{
+		{"garbage.pid", 0, false, true},
+		{"zero.pid", 0, false, false},
+		{"self.pid", os.Getpid(), true, false},
+		{"missing.pid", 0, false, true},
+	} {
+		pid, alive, err := ReadPidFile(filepath.Join(dirPath, test.name))
+		if pid != test.pid || alive != test.alive || (err != nil) != test.wantErr {
+			t.Errorf("%s: got %d, %v, %v", test.name, pid, alive, err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package ufs
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wwsheng009/go-util/umisc"
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	Options for `NewManifest`.
+type ManifestOptions struct {
+	//	If `true`, a hash of every file's contents is recorded, so that `Manifest.Diff` can tell modified files
+	//	apart by their contents rather than by their modification times.
+	Hash bool
+
+	//	If set (and `Hash`), files whose size, mode and modification time are unchanged since this earlier
+	//	`Manifest` of the same directory take over their previously recorded hash instead of being re-hashed.
+	Reuse *Manifest
+
+	//	If set, dirs/files whose `/`-separated path relative to the directory is matched
+	//	(as per `ustr.Matcher.IsMatchPath`) are not recorded.
+	Skip *ustr.Matcher
+
+	//	If greater than `1`, up to this many files are hashed concurrently.
+	Workers int
+}
+
+//	A snapshot of the metadata (and optionally the contents' hashes) of all dirs/files in a directory tree,
+//	as returned by `NewManifest` (or `LoadManifest`) and compared via `Diff`.
+type Manifest struct {
+	//	Whether the `ManifestEntry.Hash`es of all files were recorded (or attempted to: see `ManifestEntry.Hash`).
+	Hashed bool `json:"hashed"`
+
+	//	All recorded dirs/files, sorted by `RelPath`.
+	Entries []ManifestEntry `json:"entries"`
+}
+
+//	A dir/file recorded in a `Manifest`.
+type ManifestEntry struct {
+	//	The `/`-separated path relative to the directory of the `Manifest`.
+	RelPath string `json:"path"`
+
+	Size    int64       `json:"size"`
+	Mode    os.FileMode `json:"mode"`
+	ModTime time.Time   `json:"modTime"`
+
+	//	The hex-encoded SHA-256 hash of a file's contents, if `Manifest.Hashed`.
+	//	Empty for all other entries, and for files that failed to be hashed.
+	Hash string `json:"hash,omitempty"`
+
+	//	The target of a symlink.
+	Link string `json:"link,omitempty"`
+}
+
+//	Returned by `Manifest.Diff`, listing `/`-separated relative paths in sorted order.
+type ManifestDiff struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+//	Walks `dirPath` and records all dirs/files in it (symlinks are recorded, not followed).
+//	`opt` may be `nil` to use the defaults.
+func NewManifest(dirPath string, opt *ManifestOptions) (me *Manifest, errs []error) {
+	if opt == nil {
+		opt = &ManifestOptions{}
+	}
+	me = &Manifest{Hashed: opt.Hash}
+	w := NewDirWalker(true, nil, nil)
+	w.VisitSelf, w.Ignore = false, opt.Skip
+	w.EntryVisitor = func(fullPath string, entry os.DirEntry) (keepWalking bool) {
+		relPath, err := filepath.Rel(dirPath, fullPath)
+		var fi os.FileInfo
+		if err == nil {
+			fi, err = entry.Info()
+		}
+		if err != nil {
+			errs = append(errs, err)
+			return true
+		}
+		rec := ManifestEntry{RelPath: filepath.ToSlash(relPath), Size: fi.Size(), Mode: fi.Mode(), ModTime: fi.ModTime()}
+		if rec.Mode&os.ModeSymlink != 0 {
+			if rec.Link, err = os.Readlink(fullPath); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		me.Entries = append(me.Entries, rec)
+		return true
+	}
+	errs = append(errs, w.Walk(dirPath)...)
+	sort.Slice(me.Entries, func(i, j int) bool { return me.Entries[i].RelPath < me.Entries[j].RelPath })
+	if opt.Hash {
+		errs = append(errs, me.hashAll(dirPath, opt)...)
+	}
+	return
+}
+
+//	Loads a `Manifest` previously stored via `Manifest.Save`.
+func LoadManifest(filePath string) (me *Manifest, err error) {
+	me = &Manifest{}
+	if err = umisc.JsonDecodeFromFile(filePath, me); err != nil {
+		me = nil
+	} else {
+		sort.Slice(me.Entries, func(i, j int) bool { return me.Entries[i].RelPath < me.Entries[j].RelPath })
+	}
+	return
+}
+
+//	Stores `me` as JSON at `filePath` (via `JsonEncodeToFile`, so atomically if `WriteAtomically` is set).
+func (me *Manifest) Save(filePath string) error {
+	return JsonEncodeToFile(me, filePath)
+}
+
+//	Returns the entry at the `/`-separated `relPath`, or `nil` if there is none.
+func (me *Manifest) Entry(relPath string) *ManifestEntry {
+	if i := sort.Search(len(me.Entries), func(i int) bool { return me.Entries[i].RelPath >= relPath }); i < len(me.Entries) && me.Entries[i].RelPath == relPath {
+		return &me.Entries[i]
+	}
+	return nil
+}
+
+//	Compares `me` to a `newer` manifest of the same directory. If both are `Hashed`, files count as modified only if
+//	their contents (or their type or permission bits) differ, regardless of their modification times. Otherwise
+//	(and for files lacking a `ManifestEntry.Hash` in either manifest), a file also counts as modified if its size
+//	or modification time differ. Symlinks count as modified if their target differs, too, as do all other non-directory
+//	entries if their size or modification time do. Directories count as modified only if their type or permission bits differ.
+func (me *Manifest) Diff(newer *Manifest) (diff ManifestDiff) {
+	byContents := me.Hashed && newer.Hashed
+	i, j := 0, 0
+	for i < len(me.Entries) || j < len(newer.Entries) {
+		switch {
+		case j == len(newer.Entries) || (i < len(me.Entries) && me.Entries[i].RelPath < newer.Entries[j].RelPath):
+			diff.Removed = append(diff.Removed, me.Entries[i].RelPath)
+			i++
+		case i == len(me.Entries) || newer.Entries[j].RelPath < me.Entries[i].RelPath:
+			diff.Added = append(diff.Added, newer.Entries[j].RelPath)
+			j++
+		default:
+			if old, cur := &me.Entries[i], &newer.Entries[j]; !old.isSame(cur, byContents) {
+				diff.Modified = append(diff.Modified, cur.RelPath)
+			}
+			i, j = i+1, j+1
+		}
+	}
+	return
+}
+
+//	Returns whether there are no differences at all.
+func (me *ManifestDiff) IsEmpty() bool {
+	return len(me.Added) == 0 && len(me.Removed) == 0 && len(me.Modified) == 0
+}
+
+func (me *ManifestEntry) isSame(entry *ManifestEntry, byContents bool) bool {
+	if me.Mode != entry.Mode {
+		return false
+	} else if me.Mode.IsDir() {
+		return true
+	} else if me.Mode&os.ModeSymlink != 0 && me.Link != entry.Link {
+		return false
+	} else if byContents && me.Mode.IsRegular() && me.Hash != "" && entry.Hash != "" {
+		return me.Hash == entry.Hash
+	} else if byContents && me.Mode&os.ModeSymlink != 0 {
+		return true
+	}
+	return me.Size == entry.Size && me.ModTime.Equal(entry.ModTime)
+}
+
+//	Records the `Hash` of every regular file, concurrently if `opt.Workers` is greater than `1`.
+func (me *Manifest) hashAll(dirPath string, opt *ManifestOptions) (errs []error) {
+	var (
+		mutex sync.Mutex
+		wait  sync.WaitGroup
+	)
+	workers := opt.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan *ManifestEntry)
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for entry := range jobs {
+				if sum, err := hashFile(filepath.Join(dirPath, filepath.FromSlash(entry.RelPath)), 0); err == nil {
+					entry.Hash = hex.EncodeToString(sum)
+				} else {
+					mutex.Lock()
+					errs = append(errs, err)
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range me.Entries {
+		if entry := &me.Entries[i]; entry.Mode.IsRegular() {
+			if opt.Reuse != nil && opt.Reuse.Hashed {
+				if old := opt.Reuse.Entry(entry.RelPath); old != nil && old.Hash != "" && old.Size == entry.Size && old.Mode == entry.Mode && old.ModTime.Equal(entry.ModTime) {
+					entry.Hash = old.Hash
+					continue
+				}
+			}
+			jobs <- entry
+		}
+	}
+	close(jobs)
+	wait.Wait()
+	return
+}
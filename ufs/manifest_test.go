@@ -0,0 +1,121 @@
+package ufs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestDiff(t *testing.T) {
+	canSymlink := os.Symlink("a.txt", filepath.Join(t.TempDir(), "l")) == nil
+	for _, test := range []struct {
+		name     string
+		symlinks bool
+		change   func(t *testing.T, dirPath string)
+		want     map[bool]string // by whether hashed
+	}{
+		{name: "nothing", change: func(*testing.T, string) {}, want: map[bool]string{false: "", true: ""}},
+		{name: "add", change: func(t *testing.T, dirPath string) {
+			testWriteFiles(t, dirPath, map[string]string{"new.txt": "new"})
+		}, want: map[bool]string{false: "+new.txt", true: "+new.txt"}},
+		{name: "remove", change: func(t *testing.T, dirPath string) {
+			if err := os.Remove(filepath.Join(dirPath, "a.txt")); err != nil {
+				t.Fatal(err)
+			}
+		}, want: map[bool]string{false: "-a.txt", true: "-a.txt"}},
+		{name: "modify contents", change: func(t *testing.T, dirPath string) {
+			testWriteFiles(t, dirPath, map[string]string{"sub/b.txt": "BBB"})
+		}, want: map[bool]string{false: "~sub/b.txt", true: "~sub/b.txt"}},
+		{name: "touch", change: func(t *testing.T, dirPath string) {
+			later := time.Now().Add(time.Hour)
+			if err := os.Chtimes(filepath.Join(dirPath, "a.txt"), later, later); err != nil {
+				t.Fatal(err)
+			}
+		}, want: map[bool]string{false: "~a.txt", true: ""}},
+		{name: "retarget symlink", symlinks: true, change: func(t *testing.T, dirPath string) {
+			linkPath := filepath.Join(dirPath, "l")
+			if err := os.Remove(linkPath); err != nil {
+				t.Fatal(err)
+			} else if err = os.Symlink("sub/b.txt", linkPath); err != nil {
+				t.Fatal(err)
+			}
+		}, want: map[bool]string{false: "~l", true: "~l"}},
+	} {
+		for _, hashed := range []bool{false, true} {
+			t.Run(test.name, func(t *testing.T) {
+				if test.symlinks && !canSymlink {
+					t.Skip("cannot create symlinks")
+				}
+				dirPath := t.TempDir()
+				testWriteFiles(t, dirPath, map[string]string{"a.txt": "A", "sub/b.txt": "B"})
+				if canSymlink {
+					if err := os.Symlink("a.txt", filepath.Join(dirPath, "l")); err != nil {
+						t.Fatal(err)
+					}
+				}
+				before, errs := NewManifest(dirPath, &ManifestOptions{Hash: hashed})
+				if len(errs) > 0 {
+					t.Fatal(errs)
+				}
+				test.change(t, dirPath)
+				after, errs := NewManifest(dirPath, &ManifestOptions{Hash: hashed, Reuse: before, Workers: 2})
+				if len(errs) > 0 {
+					t.Fatal(errs)
+				}
+				if got := testManifestDiffString(before.Diff(after)); got != test.want[hashed] {
+					t.Errorf("hashed=%v: got %q, want %q", hashed, got, test.want[hashed])
+				}
+			})
+		}
+	}
+}
+
+func TestManifestDiffUnhashedEntries(t *testing.T) {
+	now := time.Now()
+	older := &Manifest{Hashed: true, Entries: []ManifestEntry{
+		{RelPath: "failed.txt", Size: 1, ModTime: now},
+		{RelPath: "same.txt", Size: 1, ModTime: now},
+	}}
+	newer := &Manifest{Hashed: true, Entries: []ManifestEntry{
+		{RelPath: "failed.txt", Size: 2, ModTime: now},
+		{RelPath: "same.txt", Size: 1, ModTime: now},
+	}}
+	if got := testManifestDiffString(older.Diff(newer)); got != "~failed.txt" {
+		t.Errorf("got %q, want %q", got, "~failed.txt")
+	}
+}
+
+func TestManifestSaveLoad(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"a.txt": "A", "sub/b.txt": "B"})
+	manifest, errs := NewManifest(dirPath, &ManifestOptions{Hash: true})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	filePath := filepath.Join(t.TempDir(), "manifest.json")
+	if err := manifest.Save(filePath); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadManifest(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := manifest.Diff(loaded); !diff.IsEmpty() {
+		t.Errorf("got %v, want no differences", diff)
+	}
+	if entry := loaded.Entry("sub/b.txt"); entry == nil || len(entry.Hash) != 64 {
+		t.Errorf("got %v, want a hashed entry", entry)
+	}
+}
+
+func testManifestDiffString(diff ManifestDiff) string {
+	var parts []string
+	for i, paths := range [][]string{diff.Added, diff.Removed, diff.Modified} {
+		for _, p := range paths {
+			parts = append(parts, "+-~"[i:i+1]+p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
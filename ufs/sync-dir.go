@@ -0,0 +1,360 @@
+package ufs
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	How `SyncDir` treats symlinks encountered in the source directory.
+type SyncSymlinks uint8
+
+const (
+	//	Symlinks are recreated as symlinks, pointing to the very same (unmodified) target path.
+	SyncSymlinksCopy SyncSymlinks = iota
+
+	//	Symlinks are followed: the dirs/files they point to are copied instead.
+	SyncSymlinksFollow
+
+	//	Symlinks are neither copied nor followed, and anything at their path in the destination directory is left alone.
+	SyncSymlinksSkip
+)
+
+//	The kind of change described by a `SyncChange`.
+type SyncOp uint8
+
+const (
+	//	A dir/file was (or is to be) created in the destination directory.
+	SyncCreate SyncOp = iota + 1
+
+	//	A dir/file in the destination directory was (or is to be) replaced or had its permission bits changed.
+	SyncUpdate
+
+	//	An extraneous dir/file was (or is to be) removed from the destination directory.
+	SyncDelete
+)
+
+//	Returns a short lower-case name such as "create" or "delete".
+func (me SyncOp) String() string {
+	switch me {
+	case SyncCreate:
+		return "create"
+	case SyncUpdate:
+		return "update"
+	case SyncDelete:
+		return "delete"
+	}
+	return ""
+}
+
+//	Options for `SyncDir`.
+type SyncOptions struct {
+	//	If `true`, nothing is modified: the returned `SyncReport` describes the changes that would be made.
+	DryRun bool
+
+	//	By default, an existing destination file is considered up-to-date if it has the same size as its source file
+	//	and the source file is not `IsNewerThan` it. If `CompareContents`, same-size files are compared by a hash of their contents instead.
+	CompareContents bool
+
+	//	If `true`, dirs/files in the destination directory that do not exist in the source directory are removed.
+	Delete bool
+
+	//	How to treat symlinks in the source directory, defaults to `SyncSymlinksCopy`.
+	Symlinks SyncSymlinks
+
+	//	If greater than `1`, up to this many files are copied concurrently.
+	Workers int
+
+	//	If set, dirs/files whose `/`-separated path relative to the source (or destination) directory is matched
+	//	(as per `ustr.Matcher.IsMatchPath`) are neither copied nor deleted.
+	Skip *ustr.Matcher
+}
+
+//	Describes a single change made (or, if `SyncOptions.DryRun`, to be made) by `SyncDir`.
+type SyncChange struct {
+	//	The `/`-separated path relative to the destination directory.
+	RelPath string
+
+	Op SyncOp
+
+	//	Whether the new (or, for `SyncDelete`, the removed) item is a directory.
+	IsDir bool
+}
+
+//	Returned by `SyncDir`.
+type SyncReport struct {
+	//	All changes in the order they were planned: directories always precede their contents.
+	Changes []SyncChange
+
+	//	The number of dirs/files that were already up-to-date.
+	Unchanged int
+
+	//	The total size of all files copied successfully. For a `DryRun`, that of all files that would be copied.
+	BytesCopied int64
+}
+
+type syncItem struct {
+	relPath string
+	info    os.FileInfo
+}
+
+//	The state of a single `SyncDir` call.
+type dirSync struct {
+	*SyncOptions
+	srcDirPath, dstDirPath string
+	report                 SyncReport
+	copies                 []syncItem
+	gone                   map[string]bool
+	mutex                  sync.Mutex
+	errs                   []error
+}
+
+//	Makes `dstDirPath` a mirror of `srcDirPath`, copying only new or changed files (and, if `SyncOptions.Delete`,
+//	removing extraneous ones). Unlike `CopyAll`, permission bits and modification times are preserved, so that a
+//	subsequent `SyncDir` call (using the default comparison) only needs to copy files that changed in the meantime.
+//
+//	`opt` may be `nil` to use the defaults. If `srcDirPath` cannot be fully read, nothing is done.
+func SyncDir(srcDirPath, dstDirPath string, opt *SyncOptions) (report *SyncReport, errs []error) {
+	if opt == nil {
+		opt = &SyncOptions{}
+	}
+	me := &dirSync{SyncOptions: opt, srcDirPath: srcDirPath, dstDirPath: dstDirPath, gone: map[string]bool{}}
+	srcItems, keep, errs := me.list(srcDirPath, true)
+	if len(errs) > 0 {
+		return &me.report, errs
+	}
+	dstItems := map[string]os.FileInfo{}
+	var dstOrder []syncItem
+	if DirExists(dstDirPath) {
+		if dstOrder, _, errs = me.list(dstDirPath, false); len(errs) > 0 {
+			return &me.report, errs
+		}
+		for _, item := range dstOrder {
+			dstItems[item.relPath] = item.info
+		}
+	} else if !me.DryRun {
+		if err := os.MkdirAll(dstDirPath, ModePerm); err != nil {
+			return &me.report, []error{err}
+		}
+	}
+
+	srcPaths := make(map[string]bool, len(srcItems))
+	for _, item := range srcItems {
+		srcPaths[item.relPath] = true
+		me.plan(item, dstItems[item.relPath])
+	}
+	me.copyAll()
+	if me.Delete {
+		for _, item := range dstOrder {
+			if !(srcPaths[item.relPath] || keep[item.relPath] || me.isGone(item.relPath)) {
+				me.change(item.relPath, SyncDelete, item.info.IsDir())
+				me.remove(item.relPath, item.info.IsDir())
+			}
+		}
+	}
+	if !me.DryRun {
+		//	copying into a directory changes its mtime, so restore those last, deepest first
+		for i := len(srcItems) - 1; i >= 0; i-- {
+			if fi := srcItems[i].info; fi.IsDir() {
+				dstPath := me.dstPath(srcItems[i].relPath)
+				me.onError(os.Chmod(dstPath, fi.Mode().Perm()))
+				me.onError(os.Chtimes(dstPath, fi.ModTime(), fi.ModTime()))
+			}
+		}
+	}
+	return &me.report, me.errs
+}
+
+//	Returns all dirs/files in `dirPath` (parents before their contents) and, if `isSrc`, those to be left alone due to `SyncSymlinksSkip`.
+func (me *dirSync) list(dirPath string, isSrc bool) (items []syncItem, keep map[string]bool, errs []error) {
+	keep = map[string]bool{}
+	w := NewDirWalker(true, nil, nil)
+	w.VisitSelf, w.Ignore, w.FollowSymlinks = false, me.Skip, isSrc && me.Symlinks == SyncSymlinksFollow
+	w.EntryVisitor = func(fullPath string, entry os.DirEntry) (keepWalking bool) {
+		relPath, err := filepath.Rel(dirPath, fullPath)
+		if err != nil {
+			errs = append(errs, err)
+			return true
+		}
+		relPath = filepath.ToSlash(relPath)
+		if entry.Type()&os.ModeSymlink != 0 && isSrc {
+			if me.Symlinks == SyncSymlinksSkip {
+				keep[relPath] = true
+				return true
+			} else if me.Symlinks == SyncSymlinksFollow {
+				//	a symlink to a file: the `DirWalker` only resolves those to directories (dangling ones get copied as-is)
+				if fi, err := os.Stat(fullPath); err == nil {
+					items = append(items, syncItem{relPath: relPath, info: fi})
+					return true
+				}
+			}
+		}
+		if fi, err := entry.Info(); err == nil {
+			items = append(items, syncItem{relPath: relPath, info: fi})
+		} else {
+			errs = append(errs, err)
+		}
+		return true
+	}
+	errs = append(errs, w.Walk(dirPath)...)
+	return
+}
+
+//	Decides what to do about the source `item` given its destination counterpart `dst` (`nil` if it doesn't exist).
+//	Directories are created right away, while files and symlinks are queued up for `copyAll`.
+func (me *dirSync) plan(item syncItem, dst os.FileInfo) {
+	var op SyncOp
+	srcMode := item.info.Mode()
+	switch {
+	case dst == nil:
+		op = SyncCreate
+	case dst.Mode().Type() != srcMode.Type():
+		op = SyncUpdate
+		me.remove(item.relPath, dst.IsDir())
+		dst = nil
+	case srcMode.IsDir():
+		if dst.Mode().Perm() != srcMode.Perm() {
+			op = SyncUpdate
+		}
+	case !me.isUpToDate(item, dst):
+		op = SyncUpdate
+	}
+	if op == 0 {
+		me.report.Unchanged++
+		return
+	}
+	me.change(item.relPath, op, srcMode.IsDir())
+	if !srcMode.IsDir() {
+		me.copies = append(me.copies, item)
+		if me.DryRun && srcMode.IsRegular() {
+			me.report.BytesCopied += item.info.Size()
+		}
+	} else if dst == nil && !me.DryRun {
+		//	permission bits get applied last, in case they'd prevent copying into the directory
+		me.onError(os.Mkdir(me.dstPath(item.relPath), ModePerm))
+	}
+}
+
+//	Returns whether the existing destination counterpart `dst` of the source file or symlink `item` needs no update at all.
+func (me *dirSync) isUpToDate(item syncItem, dst os.FileInfo) bool {
+	srcPath, dstPath := me.srcPath(item.relPath), me.dstPath(item.relPath)
+	if item.info.Mode()&os.ModeSymlink != 0 {
+		srcTarget, err1 := os.Readlink(srcPath)
+		dstTarget, err2 := os.Readlink(dstPath)
+		return err1 == nil && err2 == nil && srcTarget == dstTarget
+	}
+	if dst.Size() != item.info.Size() || dst.Mode().Perm() != item.info.Mode().Perm() {
+		return false
+	}
+	if me.CompareContents {
+		srcHash, err1 := hashFile(srcPath, 0)
+		dstHash, err2 := hashFile(dstPath, 0)
+		return err1 == nil && err2 == nil && bytes.Equal(srcHash, dstHash)
+	}
+	isNewer, _ := IsNewerThan(srcPath, dstPath)
+	return !isNewer
+}
+
+//	Copies all queued-up files and symlinks, concurrently if `Workers` is greater than `1`.
+func (me *dirSync) copyAll() {
+	if me.DryRun {
+		return
+	}
+	workers := me.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var wait sync.WaitGroup
+	jobs := make(chan syncItem)
+	for i := 0; i < workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for item := range jobs {
+				if err := me.copy(item); err != nil {
+					me.onError(err)
+				} else if item.info.Mode().IsRegular() {
+					me.mutex.Lock()
+					me.report.BytesCopied += item.info.Size()
+					me.mutex.Unlock()
+				}
+			}
+		}()
+	}
+	for _, item := range me.copies {
+		jobs <- item
+	}
+	close(jobs)
+	wait.Wait()
+}
+
+func (me *dirSync) copy(item syncItem) error {
+	return copyFileOrSymlink(me.srcPath(item.relPath), me.dstPath(item.relPath), item.info)
+}
+
+//	Copies the file (or recreates the symlink) `fi` at `srcPath` to `dstPath`, preserving its permission bits and modification time.
+func copyFileOrSymlink(srcPath, dstPath string, fi os.FileInfo) (err error) {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		var target string
+		if target, err = os.Readlink(srcPath); err == nil {
+			if err = os.Remove(dstPath); err == nil || os.IsNotExist(err) {
+				err = os.Symlink(target, dstPath)
+			}
+		}
+		return
+	}
+	var src *os.File
+	if src, err = os.Open(srcPath); err == nil {
+		defer src.Close()
+		if err = SaveToFileAtomic(src, dstPath, &AtomicWriteOptions{Perm: fi.Mode().Perm(), NoSync: true}); err == nil {
+			if err = os.Chmod(dstPath, fi.Mode().Perm()); err == nil {
+				err = os.Chtimes(dstPath, fi.ModTime(), fi.ModTime())
+			}
+		}
+	}
+	return
+}
+
+//	Removes the destination counterpart of `relPath` (unless `DryRun`) and notes it as gone.
+func (me *dirSync) remove(relPath string, isDir bool) {
+	if isDir {
+		me.gone[relPath] = true
+	}
+	if !me.DryRun {
+		me.onError(os.RemoveAll(me.dstPath(relPath)))
+	}
+}
+
+//	Returns whether `relPath` is inside a directory already removed from the destination directory.
+func (me *dirSync) isGone(relPath string) bool {
+	for dir := path.Dir(relPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if me.gone[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+func (me *dirSync) change(relPath string, op SyncOp, isDir bool) {
+	me.report.Changes = append(me.report.Changes, SyncChange{RelPath: relPath, Op: op, IsDir: isDir})
+}
+
+func (me *dirSync) onError(err error) {
+	if err != nil {
+		me.mutex.Lock()
+		me.errs = append(me.errs, err)
+		me.mutex.Unlock()
+	}
+}
+
+func (me *dirSync) srcPath(relPath string) string {
+	return filepath.Join(me.srcDirPath, filepath.FromSlash(relPath))
+}
+
+func (me *dirSync) dstPath(relPath string) string {
+	return filepath.Join(me.dstDirPath, filepath.FromSlash(relPath))
+}
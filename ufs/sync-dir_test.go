@@ -0,0 +1,272 @@
+package ufs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	Describes all dirs/files in `dirPath` by their `/`-separated relative paths: directories get a trailing `/`,
+//	symlinks map to `-> target` and files to their contents.
+func testDirTree(t *testing.T, dirPath string) map[string]string {
+	tree := map[string]string{}
+	err := filepath.Walk(dirPath, func(fullPath string, fi os.FileInfo, err error) error {
+		if err != nil || fullPath == dirPath {
+			return err
+		}
+		relPath, _ := filepath.Rel(dirPath, fullPath)
+		relPath = filepath.ToSlash(relPath)
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(fullPath)
+			tree[relPath] = "-> " + target
+			return err
+		case fi.IsDir():
+			tree[relPath+"/"] = ""
+		default:
+			data, err := os.ReadFile(fullPath)
+			tree[relPath] = string(data)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func testSyncChanges(report *SyncReport) (changes []string) {
+	for _, c := range report.Changes {
+		s := c.Op.String() + " " + c.RelPath
+		if c.IsDir {
+			s += "/"
+		}
+		changes = append(changes, s)
+	}
+	sort.Strings(changes)
+	return
+}
+
+func TestSyncDir(t *testing.T) {
+	srcDirPath, dstDirPath := t.TempDir(), filepath.Join(t.TempDir(), "dst")
+	testWriteFiles(t, srcDirPath, map[string]string{"a.txt": "a", "sub/b.txt": "bb", "sub/deep/c.txt": "ccc", "old/x.txt": "x"})
+	if err := os.Chmod(filepath.Join(srcDirPath, "a.txt"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(srcDirPath, "sub"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	report, errs := SyncDir(srcDirPath, dstDirPath, &SyncOptions{Workers: 4})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if want := testDirTree(t, srcDirPath); !reflect.DeepEqual(testDirTree(t, dstDirPath), want) {
+		t.Fatalf("initial sync: got %v, want %v", testDirTree(t, dstDirPath), want)
+	}
+	if got, want := testSyncChanges(report), []string{"create a.txt", "create old/", "create old/x.txt", "create sub/", "create sub/b.txt", "create sub/deep/", "create sub/deep/c.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("initial sync changes: got %v, want %v", got, want)
+	}
+	if report.BytesCopied != 7 {
+		t.Errorf("initial sync copied %d bytes, want 7", report.BytesCopied)
+	}
+	if fi, err := os.Stat(filepath.Join(dstDirPath, "a.txt")); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("permission bits not preserved: %v", fi.Mode())
+	}
+	if fi, err := os.Stat(filepath.Join(dstDirPath, "sub")); err != nil || !fi.ModTime().Equal(mtime) {
+		t.Errorf("directory mtime not preserved: %v", fi.ModTime())
+	}
+
+	if report, errs = SyncDir(srcDirPath, dstDirPath, nil); len(errs) > 0 || len(report.Changes) > 0 || report.Unchanged != 7 {
+		t.Errorf("repeated sync: got %v changes, %d unchanged, errors %v", testSyncChanges(report), report.Unchanged, errs)
+	}
+
+	//	modify the source, then preview and apply the changes
+	later := time.Now().Add(time.Hour)
+	testWriteFiles(t, srcDirPath, map[string]string{"sub/b.txt": "BB", "new.txt": "new"})
+	if err := os.Chtimes(filepath.Join(srcDirPath, "sub", "b.txt"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(srcDirPath, "old")); err != nil {
+		t.Fatal(err)
+	}
+	testWriteFiles(t, dstDirPath, map[string]string{"extra.txt": "extra"})
+	wantChanges := []string{"create new.txt", "delete extra.txt", "delete old/", "update sub/b.txt"}
+	before := testDirTree(t, dstDirPath)
+	if report, errs = SyncDir(srcDirPath, dstDirPath, &SyncOptions{Delete: true, DryRun: true}); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if got := testSyncChanges(report); !reflect.DeepEqual(got, wantChanges) {
+		t.Errorf("dry run: got %v, want %v", got, wantChanges)
+	}
+	if !reflect.DeepEqual(testDirTree(t, dstDirPath), before) {
+		t.Error("dry run modified the destination")
+	}
+	if report, errs = SyncDir(srcDirPath, dstDirPath, &SyncOptions{Delete: true, Workers: 2}); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if got := testSyncChanges(report); !reflect.DeepEqual(got, wantChanges) {
+		t.Errorf("sync with Delete: got %v, want %v", got, wantChanges)
+	}
+	if want := testDirTree(t, srcDirPath); !reflect.DeepEqual(testDirTree(t, dstDirPath), want) {
+		t.Errorf("sync with Delete: got %v, want %v", testDirTree(t, dstDirPath), want)
+	}
+}
+
+func TestSyncDirCompareContents(t *testing.T) {
+	srcDirPath, dstDirPath := t.TempDir(), t.TempDir()
+	testWriteFiles(t, srcDirPath, map[string]string{"f.txt": "new"})
+	testWriteFiles(t, dstDirPath, map[string]string{"f.txt": "old"})
+	mtime := time.Now().Add(-time.Hour)
+	for _, dirPath := range []string{srcDirPath, dstDirPath} {
+		if err := os.Chtimes(filepath.Join(dirPath, "f.txt"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if report, errs := SyncDir(srcDirPath, dstDirPath, nil); len(errs) > 0 || len(report.Changes) > 0 {
+		t.Fatalf("same size and mtime should be up-to-date by default: %v %v", testSyncChanges(report), errs)
+	}
+	report, errs := SyncDir(srcDirPath, dstDirPath, &SyncOptions{CompareContents: true})
+	if got := testSyncChanges(report); len(errs) > 0 || !reflect.DeepEqual(got, []string{"update f.txt"}) {
+		t.Errorf("CompareContents: got %v, %v", got, errs)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dstDirPath, "f.txt")); string(data) != "new" {
+		t.Errorf("CompareContents: got %q", data)
+	}
+}
+
+func TestSyncDirTypeChanges(t *testing.T) {
+	srcDirPath, dstDirPath := t.TempDir(), t.TempDir()
+	testWriteFiles(t, srcDirPath, map[string]string{"was-dir": "now a file", "was-file/inner.txt": "now a dir"})
+	testWriteFiles(t, dstDirPath, map[string]string{"was-dir/stale.txt": "", "was-file": "old"})
+	report, errs := SyncDir(srcDirPath, dstDirPath, &SyncOptions{Delete: true})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if got, want := testSyncChanges(report), []string{"create was-file/inner.txt", "update was-dir", "update was-file/"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if want := testDirTree(t, srcDirPath); !reflect.DeepEqual(testDirTree(t, dstDirPath), want) {
+		t.Errorf("got %v, want %v", testDirTree(t, dstDirPath), want)
+	}
+}
+
+func TestSyncDirSymlinks(t *testing.T) {
+	srcDirPath := t.TempDir()
+	testWriteFiles(t, srcDirPath, map[string]string{"target/t.txt": "t", "file.txt": "f"})
+	for link, target := range map[string]string{"link-dir": "target", "link-file": "file.txt", "dangling": "nowhere"} {
+		if err := os.Symlink(target, filepath.Join(srcDirPath, link)); err != nil {
+			t.Skip("symlinks unavailable:", err)
+		}
+	}
+	for _, test := range []struct {
+		symlinks SyncSymlinks
+		want     map[string]string
+	}{
+		{SyncSymlinksCopy, map[string]string{"link-dir": "-> target", "link-file": "-> file.txt", "dangling": "-> nowhere"}},
+		{SyncSymlinksFollow, map[string]string{"link-dir/": "", "link-dir/t.txt": "t", "link-file": "f", "dangling": "-> nowhere"}},
+		{SyncSymlinksSkip, map[string]string{"link-file": "untouched"}},
+	} {
+		dstDirPath := t.TempDir()
+		testWriteFiles(t, dstDirPath, map[string]string{"link-file": "untouched"})
+		if _, errs := SyncDir(srcDirPath, dstDirPath, &SyncOptions{Symlinks: test.symlinks, Delete: true}); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		want := map[string]string{"target/": "", "target/t.txt": "t", "file.txt": "f"}
+		for k, v := range test.want {
+			want[k] = v
+		}
+		if got := testDirTree(t, dstDirPath); !reflect.DeepEqual(got, want) {
+			t.Errorf("Symlinks %d: got %v, want %v", test.symlinks, got, want)
+		}
+		//	a repeated sync finds everything up-to-date, symlinks included
+		if report, errs := SyncDir(srcDirPath, dstDirPath, &SyncOptions{Symlinks: test.symlinks, Delete: true}); len(errs) > 0 || len(report.Changes) > 0 {
+			t.Errorf("Symlinks %d, repeated: got %v, %v", test.symlinks, testSyncChanges(report), errs)
+		}
+	}
+}
+
+func TestSyncDirSkip(t *testing.T) {
+	srcDirPath, dstDirPath := t.TempDir(), t.TempDir()
+	testWriteFiles(t, srcDirPath, map[string]string{"keep.txt": "", "skip.log": "", "build/out.bin": "", "src/build/x.txt": ""})
+	testWriteFiles(t, dstDirPath, map[string]string{"local.log": "local", "build/cached.bin": "cached"})
+	var skip ustr.Matcher
+	skip.AddPatterns("*.log", ustr.GlobPatternPrefix+"/build/")
+	if _, errs := SyncDir(srcDirPath, dstDirPath, &SyncOptions{Skip: &skip, Delete: true}); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	want := map[string]string{"keep.txt": "", "local.log": "local", "build/": "", "build/cached.bin": "cached", "src/": "", "src/build/": "", "src/build/x.txt": ""}
+	if got := testDirTree(t, dstDirPath); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSyncDirWorkers(t *testing.T) {
+	srcDirPath, dstDirPath := t.TempDir(), t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 200; i++ {
+		files["d"+strconv.Itoa(i%7)+"/f"+strconv.Itoa(i)] = strconv.Itoa(i * i)
+	}
+	testWriteFiles(t, srcDirPath, files)
+	report, errs := SyncDir(srcDirPath, dstDirPath, &SyncOptions{Workers: 16})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if len(report.Changes) != 207 {
+		t.Errorf("got %d changes, want 207", len(report.Changes))
+	}
+	if want := testDirTree(t, srcDirPath); !reflect.DeepEqual(testDirTree(t, dstDirPath), want) {
+		t.Error("concurrent sync produced a different tree")
+	}
+}
+
+func TestSyncDirBytesCopied(t *testing.T) {
+	srcDirPath := t.TempDir()
+	testWriteFiles(t, srcDirPath, map[string]string{"ok.txt": "12345", "fails.txt": "1234567890"})
+	for _, dryRun := range []bool{true, false} {
+		if report, errs := SyncDir(srcDirPath, t.TempDir(), &SyncOptions{DryRun: dryRun}); len(errs) > 0 || report.BytesCopied != 15 {
+			t.Errorf("DryRun %v: got %d bytes (%v), want 15", dryRun, report.BytesCopied, errs)
+		}
+	}
+
+	unreadablePath := filepath.Join(srcDirPath, "fails.txt")
+	if err := os.Chmod(unreadablePath, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(unreadablePath, 0600)
+	if file, err := os.Open(unreadablePath); err == nil {
+		file.Close()
+		t.Skip("can't make a file unreadable (running as root?)")
+	}
+	for _, test := range []struct {
+		dryRun  bool
+		want    int64
+		wantErr bool
+	}{
+		{true, 15, false},
+		{false, 5, true},
+	} {
+		report, errs := SyncDir(srcDirPath, t.TempDir(), &SyncOptions{DryRun: test.dryRun})
+		if (len(errs) > 0) != test.wantErr || report.BytesCopied != test.want {
+			t.Errorf("DryRun %v with an unreadable file: got %d bytes (%v), want %d", test.dryRun, report.BytesCopied, errs, test.want)
+		}
+	}
+}
+
+func TestSyncDirMissingSource(t *testing.T) {
+	dstDirPath := filepath.Join(t.TempDir(), "dst")
+	if _, errs := SyncDir(filepath.Join(t.TempDir(), "nope"), dstDirPath, nil); len(errs) == 0 {
+		t.Error("expected an error for a missing source directory")
+	}
+	if DirExists(dstDirPath) {
+		t.Error("destination directory created despite the error")
+	}
+}
@@ -0,0 +1,397 @@
+package ufs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//	A trash (or recycle-bin) directory that removed dirs/files get moved into, laid out as per the freedesktop.org
+//	Trash specification (as used by most Linux and BSD desktops): the items are kept in its `files` sub-directory,
+//	each with a `.trashinfo` file in its `info` sub-directory recording the original path and deletion date.
+//
+//	Additionally, every operation moving items into the trash is recorded in its `journal` sub-directory
+//	(ignored by desktop trash implementations), so that it can be listed via `Ops` and undone via `Restore`.
+type Trash struct {
+	//	The trash directory, created (with its sub-directories) on first use.
+	DirPath string
+
+	//	If set, `Purge` also deletes trashed items not journaled by this package, such as those
+	//	trashed by desktop file managers or other applications sharing the same (eg. user) trash.
+	PurgeUnjournaled bool
+}
+
+//	An operation (such as a `ClearDirectoryToTrash` call) recorded in the journal of a `Trash`.
+type TrashOp struct {
+	//	Identifies the operation for `Trash.Restore`.
+	ID string `json:"id"`
+
+	//	The kind of operation, such as `"ClearDirectory"`.
+	Name string `json:"name"`
+
+	//	The (absolute) directory that was operated upon, if any.
+	DirPath string `json:"dirPath,omitempty"`
+
+	Time time.Time `json:"time"`
+
+	//	All dirs/files moved into the trash by the operation, in the order they were moved.
+	Items []TrashItem `json:"items"`
+}
+
+//	A dir/file moved into a `Trash`.
+type TrashItem struct {
+	//	The absolute path the item was moved from.
+	OrigPath string `json:"path"`
+
+	//	The name of the item inside the `files` sub-directory of the `Trash`.
+	TrashName string `json:"name"`
+
+	IsDir bool `json:"isDir"`
+}
+
+//	If set, `ClearDirectory` and `ClearEmptyDirectories` move all dirs/files they remove into this `Trash` instead.
+var RemoveToTrash *Trash
+
+var trashOpSeq uint32
+
+const trashInfoDateFormat = "2006-01-02T15:04:05"
+
+//	Returns a project-local (or otherwise custom) `Trash` at `dirPath`.
+func NewTrash(dirPath string) *Trash {
+	return &Trash{DirPath: dirPath}
+}
+
+//	Returns the freedesktop.org home trash of the current user: `$XDG_DATA_HOME/Trash`, defaulting to `~/.local/share/Trash`.
+//	Items moved into it show up in (and can also be restored from) the trash of the user's desktop environment.
+func NewUserTrash() (*Trash, error) {
+	dataDirPath := os.Getenv("XDG_DATA_HOME")
+	if dataDirPath == "" {
+		homeDirPath, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dataDirPath = filepath.Join(homeDirPath, ".local", "share")
+	}
+	return NewTrash(filepath.Join(dataDirPath, "Trash")), nil
+}
+
+//	Like `ClearDirectory`, but moves the removed items into `trash` as a single journaled operation.
+//	If `trash` is inside `dirPath`, it (or the sub-directory of `dirPath` containing it) is kept.
+func ClearDirectoryToTrash(trash *Trash, dirPath string, keepNamePatterns ...string) (op *TrashOp, err error) {
+	if op, err = trash.newOp("ClearDirectory", dirPath); err == nil {
+		err = fsClearDirectory(OsFileSystem, dirPath, func(filePath string) error {
+			return trash.moveIn(op, filePath)
+		}, func(filePath string) bool {
+			return trash.isOwnPath(filePath, true)
+		}, keepNamePatterns...)
+		if errsave := trash.saveOp(op); err == nil {
+			err = errsave
+		}
+	}
+	return
+}
+
+//	Like `ClearEmptyDirectories`, but moves the removed directories into `trash` as a single journaled operation.
+//	If `trash` is inside `dirPath`, it is never considered empty, and neither are the sub-directories of `dirPath` containing it.
+func ClearEmptyDirectoriesToTrash(trash *Trash, dirPath string) (op *TrashOp, canDelete bool, err error) {
+	if op, err = trash.newOp("ClearEmptyDirectories", dirPath); err == nil {
+		canDelete, err = fsClearEmptyDirectories(OsFileSystem, dirPath, func(subDirPath string) error {
+			return trash.moveIn(op, subDirPath)
+		}, func(subDirPath string) bool {
+			return trash.isOwnPath(subDirPath, false)
+		})
+		if errsave := trash.saveOp(op); err == nil {
+			err = errsave
+		}
+	}
+	return
+}
+
+//	Moves the specified dirs/files into the trash as a single journaled operation.
+//	Stops at the first failure, but the items moved until then are still journaled.
+func (me *Trash) Move(paths ...string) (op *TrashOp, err error) {
+	if op, err = me.newOp("Move", ""); err == nil {
+		for _, filePath := range paths {
+			if err = me.moveIn(op, filePath); err != nil {
+				break
+			}
+		}
+		if errsave := me.saveOp(op); err == nil {
+			err = errsave
+		}
+	}
+	return
+}
+
+//	Returns all journaled operations not yet (fully) restored or purged, most recent first.
+func (me *Trash) Ops() (ops []*TrashOp, err error) {
+	var entries []os.DirEntry
+	if entries, err = os.ReadDir(me.journalPath("")); os.IsNotExist(err) {
+		return nil, nil
+	}
+	for _, entry := range entries {
+		if id := strings.TrimSuffix(entry.Name(), ".json"); id != entry.Name() {
+			var op *TrashOp
+			if op, err = me.Op(id); err != nil {
+				return
+			}
+			ops = append(ops, op)
+		}
+	}
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].Time.After(ops[j].Time) })
+	return
+}
+
+//	Returns the journaled operation with the specified `TrashOp.ID`.
+func (me *Trash) Op(id string) (op *TrashOp, err error) {
+	var data []byte
+	if data, err = os.ReadFile(me.journalPath(id)); err == nil {
+		op = &TrashOp{}
+		err = json.Unmarshal(data, op)
+	}
+	return
+}
+
+//	Undoes the journaled operation with the specified `TrashOp.ID` by moving its items back to their original paths
+//	(in reverse order, so that removed directories are back in place before their former contents).
+//	Items whose original path is taken by now are not restored and remain journaled, as do those failing to be restored.
+func (me *Trash) Restore(id string) (errs []error) {
+	op, err := me.Op(id)
+	if err != nil {
+		return []error{err}
+	}
+	var remaining []TrashItem
+	for i := len(op.Items) - 1; i >= 0; i-- {
+		item := op.Items[i]
+		if _, err = os.Lstat(item.OrigPath); err == nil {
+			err = &os.PathError{Op: "restore", Path: item.OrigPath, Err: os.ErrExist}
+		} else if err = EnsureDirExists(filepath.Dir(item.OrigPath)); err == nil {
+			if err = moveFile(me.filesPath(item.TrashName), item.OrigPath, item.IsDir); err == nil {
+				err = os.Remove(me.infoPath(item.TrashName))
+				if os.IsNotExist(err) {
+					err = nil
+				}
+			}
+		}
+		if err != nil {
+			errs, remaining = append(errs, err), append([]TrashItem{item}, remaining...)
+		}
+	}
+	op.Items = remaining
+	if err = me.saveOp(op); err != nil {
+		errs = append(errs, err)
+	}
+	return
+}
+
+//	Permanently deletes all journaled trashed items (or, if `PurgeUnjournaled` is set, all trashed items)
+//	whose deletion date is older than `maxAge`, and drops them from the journal. Journaled operations are removed
+//	once none of their items remain, so items failing to be deleted stay listed (and purgeable) in their operations.
+//	Returns the number of items deleted.
+func (me *Trash) Purge(maxAge time.Duration) (numPurged int, errs []error) {
+	before := time.Now().Add(-maxAge)
+	infos, err := os.ReadDir(me.infoPath(""))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, []error{err}
+	}
+	ops, err := me.Ops()
+	if err != nil {
+		return 0, []error{err}
+	}
+	journaled, gone := map[string]bool{}, map[string]bool{}
+	for _, op := range ops {
+		for _, item := range op.Items {
+			journaled[item.TrashName] = true
+		}
+	}
+	for _, info := range infos {
+		name := strings.TrimSuffix(info.Name(), ".trashinfo")
+		if name == info.Name() || !(journaled[name] || me.PurgeUnjournaled) {
+			continue
+		}
+		if deleted, err := me.deletionDate(name); err != nil {
+			errs = append(errs, err)
+		} else if deleted.Before(before) {
+			if err = os.RemoveAll(me.filesPath(name)); err == nil {
+				err = os.Remove(me.infoPath(name))
+			}
+			if err != nil {
+				errs = append(errs, err)
+			} else {
+				numPurged, gone[name] = numPurged+1, true
+			}
+		}
+	}
+	for _, op := range ops {
+		remaining := make([]TrashItem, 0, len(op.Items))
+		for _, item := range op.Items {
+			//	also drop expired items already deleted (or restored) by other means, such as a desktop trash
+			if !(gone[item.TrashName] || (op.Time.Before(before) && me.isMissing(item.TrashName))) {
+				remaining = append(remaining, item)
+			}
+		}
+		if len(remaining) < len(op.Items) {
+			op.Items = remaining
+			if err = me.saveOp(op); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return
+}
+
+//	Returns whether neither the item `name` nor its `.trashinfo` file exist in the trash.
+func (me *Trash) isMissing(name string) bool {
+	_, errfile := os.Lstat(me.filesPath(name))
+	_, errinfo := os.Lstat(me.infoPath(name))
+	return os.IsNotExist(errfile) && os.IsNotExist(errinfo)
+}
+
+//	Returns whether `fsPath` is the trash directory or inside it or, if `orAncestor`, contains it.
+func (me *Trash) isOwnPath(fsPath string, orAncestor bool) bool {
+	trashDirPath, err := filepath.Abs(me.DirPath)
+	if err == nil {
+		fsPath, err = filepath.Abs(fsPath)
+	}
+	return err != nil || pathIsIn(fsPath, trashDirPath) || (orAncestor && pathIsIn(trashDirPath, fsPath))
+}
+
+func (me *Trash) newOp(name string, dirPath string) (op *TrashOp, err error) {
+	if dirPath != "" {
+		if dirPath, err = filepath.Abs(dirPath); err != nil {
+			return
+		}
+	}
+	for _, subDirPath := range []string{me.filesPath(""), me.infoPath(""), me.journalPath("")} {
+		if err = os.MkdirAll(subDirPath, 0700); err != nil {
+			return
+		}
+	}
+	now := time.Now()
+	id := strconv.FormatInt(now.UnixNano(), 36) + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatUint(uint64(atomic.AddUint32(&trashOpSeq, 1)), 36)
+	return &TrashOp{ID: id, Name: name, DirPath: dirPath, Time: now}, nil
+}
+
+//	Journals `op` or, if it has no `Items` (left), removes it from the journal.
+func (me *Trash) saveOp(op *TrashOp) error {
+	if len(op.Items) == 0 {
+		if err := os.Remove(me.journalPath(op.ID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(op, "", "\t")
+	if err == nil {
+		err = WriteFileAtomic(me.journalPath(op.ID), data, &AtomicWriteOptions{Perm: 0600})
+	}
+	return err
+}
+
+//	Moves the dir/file at `filePath` into the trash and records it in `op`.
+func (me *Trash) moveIn(op *TrashOp, filePath string) (err error) {
+	var fi os.FileInfo
+	if filePath, err = filepath.Abs(filePath); err == nil {
+		if fi, err = os.Lstat(filePath); err == nil {
+			var name string
+			if name, err = me.reserveName(filePath, op.Time); err == nil {
+				if err = moveFile(filePath, me.filesPath(name), fi.IsDir()); err != nil {
+					os.Remove(me.infoPath(name))
+				} else {
+					op.Items = append(op.Items, TrashItem{OrigPath: filePath, TrashName: name, IsDir: fi.IsDir()})
+				}
+			}
+		}
+	}
+	return
+}
+
+//	Picks a name not yet used in the trash and reserves it by exclusively creating its `.trashinfo` file, as per the spec.
+func (me *Trash) reserveName(origPath string, deleted time.Time) (name string, err error) {
+	base, ext := filepath.Base(origPath), filepath.Ext(origPath)
+	info := "[Trash Info]\nPath=" + (&url.URL{Path: filepath.ToSlash(origPath)}).EscapedPath() + "\nDeletionDate=" + deleted.Format(trashInfoDateFormat) + "\n"
+	for i := 1; ; i++ {
+		if name = base; i > 1 {
+			name = strings.TrimSuffix(base, ext) + "." + strconv.Itoa(i) + ext
+		}
+		var file *os.File
+		if file, err = os.OpenFile(me.infoPath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); os.IsExist(err) {
+			continue
+		} else if err != nil {
+			return
+		}
+		if _, err = file.WriteString(info); err == nil {
+			err = file.Close()
+		} else {
+			file.Close()
+		}
+		if _, errstat := os.Lstat(me.filesPath(name)); err != nil || errstat == nil {
+			//	on failure, or if the name is taken by a leftover lacking its `.trashinfo` (which isn't ours to remove)
+			os.Remove(me.infoPath(name))
+			if err == nil {
+				continue
+			}
+		}
+		return
+	}
+}
+
+func (me *Trash) deletionDate(name string) (deleted time.Time, err error) {
+	var data []byte
+	if data, err = os.ReadFile(me.infoPath(name)); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); strings.HasPrefix(line, "DeletionDate=") {
+				return time.ParseInLocation(trashInfoDateFormat, line[len("DeletionDate="):], time.Local)
+			}
+		}
+		err = errors.New("ufs: no DeletionDate in " + me.infoPath(name))
+	}
+	return
+}
+
+func (me *Trash) filesPath(name string) string {
+	return filepath.Join(me.DirPath, "files", name)
+}
+
+func (me *Trash) infoPath(name string) string {
+	if name != "" {
+		name += ".trashinfo"
+	}
+	return filepath.Join(me.DirPath, "info", name)
+}
+
+func (me *Trash) journalPath(id string) string {
+	if id != "" {
+		id += ".json"
+	}
+	return filepath.Join(me.DirPath, "journal", id)
+}
+
+//	Renames `srcPath` to `dstPath` or, if that fails only because they are on different devices, copies it over and then removes it.
+func moveFile(srcPath, dstPath string, isDir bool) (err error) {
+	if err = os.Rename(srcPath, dstPath); err == nil || !isCrossDeviceError(err) {
+		return
+	}
+	var fi os.FileInfo
+	if isDir {
+		if _, errs := SyncDir(srcPath, dstPath, nil); len(errs) > 0 {
+			err = errs[0]
+		} else {
+			err = nil
+		}
+	} else if fi, err = os.Lstat(srcPath); err == nil {
+		err = copyFileOrSymlink(srcPath, dstPath, fi)
+	}
+	if err == nil {
+		err = os.RemoveAll(srcPath)
+	} else {
+		os.RemoveAll(dstPath)
+	}
+	return
+}
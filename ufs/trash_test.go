@@ -0,0 +1,152 @@
+package ufs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClearDirectoryToProjectLocalTrash(t *testing.T) {
+	for _, trashRelPath := range []string{".trash", "var/cache/.trash"} {
+		t.Run(trashRelPath, func(t *testing.T) {
+			dirPath := t.TempDir()
+			testWriteFiles(t, dirPath, map[string]string{"important.txt": "data", "sub/more.txt": "more", "var/log.txt": "log"})
+			trash := NewTrash(filepath.Join(dirPath, filepath.FromSlash(trashRelPath)))
+			op, err := ClearDirectoryToTrash(trash, dirPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi, err := os.Stat(trash.DirPath); err != nil || !fi.IsDir() {
+				t.Fatalf("trash directory is gone: %v", err)
+			}
+			for _, name := range []string{"important.txt", "sub"} {
+				if _, err := os.Lstat(filepath.Join(dirPath, name)); err == nil {
+					t.Errorf("%s was not moved into the trash", name)
+				}
+			}
+			if errs := trash.Restore(op.ID); len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			if data, err := os.ReadFile(filepath.Join(dirPath, "important.txt")); err != nil || string(data) != "data" {
+				t.Errorf("important.txt not restored: %q, %v", data, err)
+			}
+			if data, err := os.ReadFile(filepath.Join(dirPath, "sub", "more.txt")); err != nil || string(data) != "more" {
+				t.Errorf("sub/more.txt not restored: %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestClearEmptyDirectoriesToProjectLocalTrash(t *testing.T) {
+	dirPath := t.TempDir()
+	for _, subDirPath := range []string{"empty/deeper", "nested/empty", "nested/.trash"} {
+		if err := os.MkdirAll(filepath.Join(dirPath, filepath.FromSlash(subDirPath)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	trash := NewTrash(filepath.Join(dirPath, "nested", ".trash"))
+	op, canDelete, err := ClearEmptyDirectoriesToTrash(trash, dirPath)
+	if err != nil {
+		t.Fatal(err)
+	} else if canDelete {
+		t.Error("canDelete: want false since dirPath contains the trash")
+	}
+	for subDirPath, wantExists := range map[string]bool{"empty": false, "nested/empty": false, "nested/.trash/files": true, "nested/.trash/journal": true} {
+		if _, err := os.Stat(filepath.Join(dirPath, filepath.FromSlash(subDirPath))); (err == nil) != wantExists {
+			t.Errorf("%s: exists is %v, want %v", subDirPath, err == nil, wantExists)
+		}
+	}
+	if len(op.Items) != 3 {
+		t.Errorf("got %d trashed items, want 3: %v", len(op.Items), op.Items)
+	}
+}
+
+func TestTrashPurgeKeepsUnjournaled(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"mine.txt": "mine"})
+	trash := NewTrash(filepath.Join(t.TempDir(), "Trash"))
+	if _, err := trash.Move(filepath.Join(dirPath, "mine.txt")); err != nil {
+		t.Fatal(err)
+	}
+	//	as trashed by some other application
+	testWriteFiles(t, trash.DirPath, map[string]string{
+		"files/theirs.txt":          "theirs",
+		"info/theirs.txt.trashinfo": "[Trash Info]\nPath=/tmp/theirs.txt\nDeletionDate=2001-02-03T04:05:06\n",
+	})
+	time.Sleep(10 * time.Millisecond)
+	numPurged, errs := trash.Purge(0)
+	if len(errs) > 0 || numPurged != 1 {
+		t.Fatalf("got %d purged (%v), want 1", numPurged, errs)
+	}
+	if _, err := os.Stat(trash.filesPath("mine.txt")); err == nil {
+		t.Error("journaled item was not purged")
+	}
+	if _, err := os.Stat(trash.filesPath("theirs.txt")); err != nil {
+		t.Errorf("unjournaled item was purged: %v", err)
+	}
+	if ops, err := trash.Ops(); err != nil || len(ops) != 0 {
+		t.Errorf("got ops %v (%v), want none", ops, err)
+	}
+
+	trash.PurgeUnjournaled = true
+	if numPurged, errs = trash.Purge(0); len(errs) > 0 || numPurged != 1 {
+		t.Fatalf("got %d purged (%v), want 1", numPurged, errs)
+	}
+	if _, err := os.Stat(trash.filesPath("theirs.txt")); err == nil {
+		t.Error("unjournaled item was not purged despite PurgeUnjournaled")
+	}
+}
+
+func TestTrashPurgeKeepsJournalOfRemainingItems(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"purged.txt": "", "kept.txt": "", "gone.txt": ""})
+	trash := NewTrash(filepath.Join(t.TempDir(), "Trash"))
+	op, err := trash.Move(filepath.Join(dirPath, "purged.txt"), filepath.Join(dirPath, "kept.txt"), filepath.Join(dirPath, "gone.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//	an item not (yet) due for deletion, as if left over from a failed purge
+	info := "[Trash Info]\nPath=" + filepath.ToSlash(filepath.Join(dirPath, "kept.txt")) + "\nDeletionDate=" + time.Now().Add(time.Hour).Format(trashInfoDateFormat) + "\n"
+	if err = os.WriteFile(trash.infoPath("kept.txt"), []byte(info), 0600); err != nil {
+		t.Fatal(err)
+	}
+	//	an item deleted by other means, such as a desktop trash
+	if err = os.Remove(trash.filesPath("gone.txt")); err == nil {
+		err = os.Remove(trash.infoPath("gone.txt"))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if numPurged, errs := trash.Purge(time.Millisecond); len(errs) > 0 || numPurged != 1 {
+		t.Fatalf("got %d purged (%v), want 1", numPurged, errs)
+	}
+	ops, err := trash.Ops()
+	if err != nil || len(ops) != 1 || ops[0].ID != op.ID {
+		t.Fatalf("got ops %v (%v), want just %s", ops, err, op.ID)
+	}
+	if items := ops[0].Items; len(items) != 1 || items[0].TrashName != "kept.txt" {
+		t.Errorf("got journaled items %v, want only kept.txt", items)
+	}
+	if errs := trash.Restore(op.ID); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if !FileExists(filepath.Join(dirPath, "kept.txt")) {
+		t.Error("remaining item was not restored")
+	}
+}
+
+func TestMoveFileOnlyCopiesAcrossDevices(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"src/file.txt": "data"})
+	srcDirPath := filepath.Join(dirPath, "src")
+	//	renaming a directory into itself fails, but not for being on different devices
+	if err := moveFile(srcDirPath, filepath.Join(srcDirPath, "inside"), true); err == nil {
+		t.Fatal("want an error moving a directory into itself")
+	}
+	if data, err := os.ReadFile(filepath.Join(srcDirPath, "file.txt")); err != nil || string(data) != "data" {
+		t.Errorf("source was removed: %q, %v", data, err)
+	}
+}
@@ -0,0 +1,194 @@
+package ufs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+//	A `FileSystem` restricted to a directory of another one, see `NewBasePathFileSystem`.
+type basePathFileSystem struct {
+	base    FileSystem
+	dirPath string
+}
+
+//	A `File` opened from a `basePathFileSystem`, reporting its name as given rather than its real path.
+type basePathFile struct {
+	File
+	name string
+}
+
+//	Reported (as the `Err` of a `*os.PathError`) by a `NewBasePathFileSystem` for names leading outside of its directory via symlinks.
+var ErrFsPathOutside = errors.New("ufs: path leads outside of the base directory")
+
+//	Returns a chroot-like `FileSystem` exposing only the contents of `dirPath` in `base`: all names are
+//	resolved relative to `dirPath` (whether they begin with a `/` or not), and `..` never leads outside of it.
+//	Paths reported in errors are the names as given, not the real paths inside `base`.
+//
+//	If `base` is the `OsFileSystem`, symlinks are resolved too, and names leading outside of `dirPath` through any of them
+//	fail with `ErrFsPathOutside` (though a symlink swapped in concurrently may still escape). With any other `base`,
+//	containment is lexical only.
+func NewBasePathFileSystem(base FileSystem, dirPath string) FileSystem {
+	return &basePathFileSystem{base: base, dirPath: dirPath}
+}
+
+//	Returns the path in `base` for `name`. With `follow`, a symlink as its last element is resolved too
+//	(for opening), else not (for removing or renaming the symlink itself).
+func (me *basePathFileSystem) realPath(name string, follow bool) (realPath string, err error) {
+	cleanName := fsCleanPath(name)
+	realPath = filepath.Join(me.dirPath, filepath.FromSlash(cleanName))
+	if _, isOs := me.base.(osFileSystem); !isOs {
+		return
+	}
+	var dirPath, checkPath string
+	if dirPath, err = filepath.Abs(me.dirPath); err == nil {
+		dirPath, err = archiveResolvePath(dirPath)
+	}
+	if checkPath = realPath; !(follow || cleanName == "/") {
+		checkPath = filepath.Dir(realPath)
+	}
+	if err == nil {
+		if checkPath, err = filepath.Abs(checkPath); err == nil {
+			checkPath, err = archiveResolvePath(checkPath)
+		}
+	}
+	if err == nil && !pathIsIn(checkPath, dirPath) {
+		err = &os.PathError{Op: "resolve", Path: realPath, Err: ErrFsPathOutside}
+	}
+	return
+}
+
+func (me *basePathFileSystem) Open(name string) (file File, err error) {
+	var realPath string
+	if realPath, err = me.realPath(name, true); err == nil {
+		file, err = me.base.Open(realPath)
+	}
+	return basePathFileOf(file, err, name)
+}
+
+func (me *basePathFileSystem) Create(name string) (file File, err error) {
+	var realPath string
+	if realPath, err = me.realPath(name, true); err == nil {
+		file, err = me.base.Create(realPath)
+	}
+	return basePathFileOf(file, err, name)
+}
+
+func (me *basePathFileSystem) OpenFile(name string, flag int, perm os.FileMode) (file File, err error) {
+	var realPath string
+	if realPath, err = me.realPath(name, true); err == nil {
+		file, err = me.base.OpenFile(realPath, flag, perm)
+	}
+	return basePathFileOf(file, err, name)
+}
+
+func (me *basePathFileSystem) Mkdir(name string, perm os.FileMode) error {
+	realPath, err := me.realPath(name, true)
+	if err == nil {
+		err = me.base.Mkdir(realPath, perm)
+	}
+	return basePathErr(err, name)
+}
+
+func (me *basePathFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	realPath, err := me.realPath(name, true)
+	if err == nil {
+		err = me.base.MkdirAll(realPath, perm)
+	}
+	return basePathErr(err, name)
+}
+
+func (me *basePathFileSystem) Remove(name string) error {
+	realPath, err := me.realPath(name, false)
+	if err == nil {
+		err = me.base.Remove(realPath)
+	}
+	return basePathErr(err, name)
+}
+
+func (me *basePathFileSystem) RemoveAll(name string) error {
+	if fsCleanPath(name) == "/" {
+		//	like `memFileSystem`, clear the root but keep it
+		entries, err := me.base.ReadDir(me.dirPath)
+		for i := 0; err == nil && i < len(entries); i++ {
+			err = me.base.RemoveAll(filepath.Join(me.dirPath, entries[i].Name()))
+		}
+		return basePathErr(err, name)
+	}
+	realPath, err := me.realPath(name, false)
+	if err == nil {
+		err = me.base.RemoveAll(realPath)
+	}
+	return basePathErr(err, name)
+}
+
+func (me *basePathFileSystem) Rename(oldName, newName string) error {
+	oldPath, err := me.realPath(oldName, false)
+	if err != nil {
+		return basePathErr(err, oldName)
+	}
+	newPath, err := me.realPath(newName, false)
+	if err != nil {
+		return basePathErr(err, newName)
+	}
+	err = me.base.Rename(oldPath, newPath)
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		err = &os.LinkError{Op: linkErr.Op, Old: oldName, New: newName, Err: linkErr.Err}
+	}
+	return err
+}
+
+func (me *basePathFileSystem) Stat(name string) (fi os.FileInfo, err error) {
+	var realPath string
+	if realPath, err = me.realPath(name, true); err == nil {
+		fi, err = me.base.Stat(realPath)
+	}
+	return fi, basePathErr(err, name)
+}
+
+func (me *basePathFileSystem) ReadDir(name string) (entries []os.DirEntry, err error) {
+	var realPath string
+	if realPath, err = me.realPath(name, true); err == nil {
+		entries, err = me.base.ReadDir(realPath)
+	}
+	return entries, basePathErr(err, name)
+}
+
+func (me *basePathFileSystem) Chmod(name string, mode os.FileMode) error {
+	realPath, err := me.realPath(name, true)
+	if err == nil {
+		err = me.base.Chmod(realPath, mode)
+	}
+	return basePathErr(err, name)
+}
+
+func (me *basePathFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	realPath, err := me.realPath(name, true)
+	if err == nil {
+		err = me.base.Chtimes(realPath, atime, mtime)
+	}
+	return basePathErr(err, name)
+}
+
+//	Wraps the result of opening a file in the `basePathFileSystem.base` for the specified `name`.
+func basePathFileOf(file File, err error, name string) (File, error) {
+	if err != nil {
+		return nil, basePathErr(err, name)
+	}
+	return &basePathFile{File: file, name: name}, nil
+}
+
+func (me *basePathFile) Name() string {
+	return me.name
+}
+
+//	Replaces the real path in a `*os.PathError` by the `name` given to the `basePathFileSystem`.
+func basePathErr(err error, name string) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		return &os.PathError{Op: pathErr.Op, Path: name, Err: pathErr.Err}
+	}
+	return err
+}
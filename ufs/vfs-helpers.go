@@ -0,0 +1,366 @@
+package ufs
+
+import (
+	"encoding/binary"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wwsheng009/go-util/uslice"
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	Like `AllFilePathsIn`, but for the specified `FileSystem`.
+func FsAllFilePathsIn(fsys FileSystem, dirpath string, ignoresubpath string) (filepaths []string) {
+	if len(ignoresubpath) > 0 && !strings.HasPrefix(ignoresubpath, dirpath) {
+		ignoresubpath = filepath.Join(dirpath, ignoresubpath)
+	}
+	FsWalkAllFiles(fsys, dirpath, func(disfilepath string) (keepWalking bool) {
+		if !strings.HasPrefix(disfilepath, ignoresubpath) {
+			filepaths = append(filepaths, disfilepath)
+		}
+		return true
+	})
+	return
+}
+
+//	Like `ClearDirectory`, but for the specified `FileSystem`.
+func FsClearDirectory(fsys FileSystem, dirPath string, keepNamePatterns ...string) (err error) {
+	return fsClearDirectory(fsys, dirPath, fsys.RemoveAll, nil, keepNamePatterns...)
+}
+
+//	Implements `FsClearDirectory`, removing via `remove` and additionally keeping all items for which `keep` (if any) returns `true`.
+func fsClearDirectory(fsys FileSystem, dirPath string, remove func(string) error, keep func(string) bool, keepNamePatterns ...string) (err error) {
+	var entries []os.DirEntry
+	var matcher ustr.Matcher
+	matcher.AddPatterns(keepNamePatterns...)
+	if entries, err = fsys.ReadDir(dirPath); err == nil {
+		for _, entry := range entries {
+			if fn := entry.Name(); !matcher.IsMatchPath(fn, entry.IsDir()) {
+				if fsPath := filepath.Join(dirPath, fn); keep == nil || !keep(fsPath) {
+					if err = remove(fsPath); err != nil {
+						return
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+//	Like `ClearEmptyDirectories`, but for the specified `FileSystem`.
+func FsClearEmptyDirectories(fsys FileSystem, dirPath string) (canDelete bool, err error) {
+	return fsClearEmptyDirectories(fsys, dirPath, fsys.RemoveAll, nil)
+}
+
+//	Implements `FsClearEmptyDirectories`, removing via `remove` and treating all sub-directories for which `keep` (if any) returns `true` as non-empty.
+func fsClearEmptyDirectories(fsys FileSystem, dirPath string, remove func(string) error, keep func(string) bool) (canDelete bool, err error) {
+	var (
+		subs   []os.DirEntry
+		canDel bool
+		subDir string
+	)
+	canDelete = true
+	if subs, err = fsys.ReadDir(dirPath); err == nil {
+		for _, sub := range subs {
+			if subDir = filepath.Join(dirPath, sub.Name()); sub.IsDir() && !(keep != nil && keep(subDir)) {
+				if canDel, err = fsClearEmptyDirectories(fsys, subDir, remove, keep); err != nil {
+					break
+				} else if !canDel {
+					canDelete = false
+				} else if err = remove(subDir); err != nil {
+					break
+				}
+			} else {
+				canDelete = false
+			}
+		}
+	}
+	if err != nil {
+		canDelete = false
+	}
+	return
+}
+
+//	Like `CopyAll`, but for the specified `FileSystem`. (Does not use `WriteAtomically`.)
+func FsCopyAll(fsys FileSystem, srcDirPath, dstDirPath string, skipDirs *ustr.Matcher, skipFileSuffix string) (err error) {
+	return fsCopyAll(fsys, srcDirPath, dstDirPath, "", skipDirs, skipFileSuffix, func(srcFilePath, dstFilePath string) error {
+		return FsCopyFile(fsys, srcFilePath, dstFilePath)
+	})
+}
+
+func fsCopyAll(fsys FileSystem, srcDirPath, dstDirPath, relDirPath string, skipDirs *ustr.Matcher, skipFileSuffix string, copyFile func(string, string) error) (err error) {
+	var (
+		srcPath, destPath, relPath string
+		entries                    []os.DirEntry
+	)
+	if entries, err = fsys.ReadDir(srcDirPath); err == nil {
+		FsEnsureDirExists(fsys, dstDirPath)
+		for _, entry := range entries {
+			if srcPath, destPath = filepath.Join(srcDirPath, entry.Name()), filepath.Join(dstDirPath, entry.Name()); entry.IsDir() {
+				if relPath = path.Join(relDirPath, entry.Name()); skipDirs == nil || !skipDirs.IsMatchPath(relPath, true) {
+					if skipFileSuffix == "" || !strings.HasSuffix(srcPath, skipFileSuffix) {
+						fsCopyAll(fsys, srcPath, destPath, relPath, skipDirs, skipFileSuffix, copyFile)
+					}
+				}
+			} else {
+				copyFile(srcPath, destPath)
+			}
+		}
+	}
+	return
+}
+
+//	Like `CopyFile`, but for the specified `FileSystem`. (Does not use `WriteAtomically`.)
+func FsCopyFile(fsys FileSystem, srcFilePath, dstFilePath string) (err error) {
+	var src File
+	if src, err = fsys.Open(srcFilePath); err != nil {
+		return
+	}
+	defer src.Close()
+	err = FsSaveToFile(fsys, src, dstFilePath)
+	return
+}
+
+//	Like `DirExists`, but for the specified `FileSystem`.
+func FsDirExists(fsys FileSystem, dirPath string) bool {
+	if len(dirPath) == 0 {
+		return false
+	}
+	stat, err := fsys.Stat(dirPath)
+	return err == nil && stat.IsDir()
+}
+
+//	Like `DirsOrFilesExistIn`, but for the specified `FileSystem`.
+func FsDirsOrFilesExistIn(fsys FileSystem, dirPath string, dirOrFileNames ...string) bool {
+	for _, name := range dirOrFileNames {
+		if stat, err := fsys.Stat(filepath.Join(dirPath, name)); err != nil || stat == nil {
+			return false
+		}
+	}
+	return true
+}
+
+//	Like `EnsureDirExists`, but for the specified `FileSystem`.
+func FsEnsureDirExists(fsys FileSystem, dirPath string) (err error) {
+	if !FsDirExists(fsys, dirPath) {
+		if err = FsEnsureDirExists(fsys, filepath.Dir(dirPath)); err == nil {
+			err = fsys.Mkdir(dirPath, ModePerm)
+		}
+	}
+	return
+}
+
+//	Like `FileExists`, but for the specified `FileSystem`.
+func FsFileExists(fsys FileSystem, filePath string) bool {
+	stat, err := fsys.Stat(filePath)
+	return err == nil && stat.Mode().IsRegular()
+}
+
+//	Like `FindFileInfo`, but for the specified `FileSystem`.
+func FsFindFileInfo(fsys FileSystem, dirPath string, fileBaseName string, fileExts []string, tryLower bool, tryUpper bool) (fullFilePath string, fileInfo *os.FileInfo) {
+	var (
+		stat        os.FileInfo
+		err         error
+		fext, fpath string
+	)
+	for _, fext = range fileExts {
+		fpath = filepath.Join(dirPath, fileBaseName+fext)
+		if stat, err = fsys.Stat(fpath); err != nil {
+			if tryUpper {
+				fpath = filepath.Join(dirPath, strings.ToUpper(fileBaseName)+fext)
+				stat, err = fsys.Stat(fpath)
+			}
+			if (err != nil) && tryLower {
+				fpath = filepath.Join(dirPath, strings.ToLower(fileBaseName)+fext)
+				stat, err = fsys.Stat(fpath)
+			}
+		}
+		if (err == nil) && !stat.IsDir() {
+			return fpath, &stat
+		}
+	}
+	return "", nil
+}
+
+//	Like `IsAnyInNewerThanAnyOf`, but for the specified `FileSystem`.
+func FsIsAnyInNewerThanAnyOf(fsys FileSystem, dirpath string, filepaths ...string) (isAnyNewer bool) {
+	var cmpfiletimeoldest int64 = 0
+	if len(filepaths) == 0 {
+		return true
+	}
+	for _, fp := range filepaths {
+		if cmpfile, err := fsys.Stat(fp); err != nil || cmpfile == nil {
+			return true
+		} else if modtime := cmpfile.ModTime().UnixNano(); modtime > 0 && (cmpfiletimeoldest == 0 || modtime < cmpfiletimeoldest) {
+			cmpfiletimeoldest = modtime
+		}
+	}
+	if errs := FsWalkAllFiles(fsys, dirpath, func(disfilepath string) (keepWalking bool) {
+		if !uslice.StrHas(filepaths, disfilepath) {
+			disfile, err := fsys.Stat(disfilepath)
+			if err != nil || disfile == nil || disfile.ModTime().UnixNano() > cmpfiletimeoldest {
+				isAnyNewer = true
+			}
+		}
+		keepWalking = !isAnyNewer
+		return
+	}); len(errs) > 0 {
+		return true
+	}
+	return
+}
+
+//	Like `IsNewerThan`, but for the specified `FileSystem`.
+func FsIsNewerThan(fsys FileSystem, srcFilePath, dstFilePath string) (newer bool, err error) {
+	var out, src os.FileInfo
+	newer = true
+	if out, err = fsys.Stat(dstFilePath); err == nil && out != nil {
+		if src, err = fsys.Stat(srcFilePath); err == nil && src != nil {
+			newer = src.ModTime().UnixNano() > out.ModTime().UnixNano()
+		}
+	}
+	return
+}
+
+//	Like `IsNewerThanTime`, but for the specified `FileSystem`.
+func FsIsNewerThanTime(fsys FileSystem, srcFilePath string, time int64) (newer bool, err error) {
+	var src os.FileInfo
+	if newer = true; time > 0 {
+		if src, err = fsys.Stat(srcFilePath); err == nil && src != nil {
+			newer = src.ModTime().UnixNano() > time
+		}
+	}
+	return
+}
+
+//	Like `ReadBinaryFile`, but for the specified `FileSystem`.
+func FsReadBinaryFile(fsys FileSystem, filePath string, panicOnError bool) []byte {
+	bytes, err := fsReadFile(fsys, filePath)
+	if panicOnError && (err != nil) {
+		panic(err)
+	}
+	return bytes
+}
+
+//	Like `ReadFromBinary`, but reading from the file at `filePath` in the specified `FileSystem`.
+func FsReadFromBinary(fsys FileSystem, filePath string, offset int64, byteOrder binary.ByteOrder, ptr interface{}) bool {
+	file, err := fsys.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	return ReadFromBinary(file, offset, byteOrder, ptr)
+}
+
+//	Like `ReadTextFile`, but for the specified `FileSystem`.
+func FsReadTextFile(fsys FileSystem, filePath string, panicOnError bool, defaultValue string) string {
+	bytes, err := fsReadFile(fsys, filePath)
+	if err == nil {
+		return string(bytes)
+	}
+	if panicOnError {
+		panic(err)
+	}
+	return defaultValue
+}
+
+//	Like `ReadFileIntoStr`, but for the specified `FileSystem`.
+func FsReadFileIntoStr(fsys FileSystem, filePath string, contents *string) error {
+	bytes, err := fsReadFile(fsys, filePath)
+	if err == nil {
+		*contents = string(bytes)
+	}
+	return err
+}
+
+//	Like `SaveToFile`, but for the specified `FileSystem`. (Does not use `WriteAtomically`.)
+func FsSaveToFile(fsys FileSystem, src io.Reader, dstFilePath string) (err error) {
+	var file File
+	if file, err = fsys.Create(dstFilePath); err == nil {
+		defer file.Close()
+		_, err = io.Copy(file, src)
+	}
+	return
+}
+
+//	Like `WalkAllDirs`, but for the specified `FileSystem`.
+func FsWalkAllDirs(fsys FileSystem, dirPath string, visitor WalkerVisitor) []error {
+	return fsNewDirWalker(fsys, true, visitor, nil).Walk(dirPath)
+}
+
+//	Like `WalkAllFiles`, but for the specified `FileSystem`.
+func FsWalkAllFiles(fsys FileSystem, dirPath string, visitor WalkerVisitor) []error {
+	return fsNewDirWalker(fsys, true, nil, visitor).Walk(dirPath)
+}
+
+//	Like `WalkDirsIn`, but for the specified `FileSystem`.
+func FsWalkDirsIn(fsys FileSystem, dirPath string, visitor WalkerVisitor) []error {
+	w := fsNewDirWalker(fsys, false, visitor, nil)
+	w.VisitSelf = false
+	return w.Walk(dirPath)
+}
+
+//	Like `WalkFilesIn`, but for the specified `FileSystem`.
+func FsWalkFilesIn(fsys FileSystem, dirPath string, visitor WalkerVisitor) []error {
+	w := fsNewDirWalker(fsys, false, nil, visitor)
+	w.VisitSelf = false
+	return w.Walk(dirPath)
+}
+
+//	Like `WriteBinaryFile`, but for the specified `FileSystem`. (Does not use `WriteAtomically`.)
+func FsWriteBinaryFile(fsys FileSystem, filePath string, contents []byte) (err error) {
+	FsEnsureDirExists(fsys, filepath.Dir(filePath))
+	var file File
+	if file, err = fsys.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, ModePerm); err == nil {
+		if _, err = file.Write(contents); err == nil {
+			err = file.Close()
+		} else {
+			file.Close()
+		}
+	}
+	return
+}
+
+//	Like `WriteTextFile`, but for the specified `FileSystem`. (Does not use `WriteAtomically`.)
+func FsWriteTextFile(fsys FileSystem, filePath, contents string) error {
+	return FsWriteBinaryFile(fsys, filePath, []byte(contents))
+}
+
+func fsReadFile(fsys FileSystem, filePath string) (data []byte, err error) {
+	var file File
+	if file, err = fsys.Open(filePath); err == nil {
+		defer file.Close()
+		data, err = io.ReadAll(file)
+	}
+	return
+}
+
+//	Returns a `NewDirWalker` walking `fsys`. Unless that is `OsFileSystem`, the `DirWalker.FS` is set
+//	to a `walkerFS` (rather than an `IoFS`) so that rooted names, as walked by the `Fs*` helpers, are permitted.
+func fsNewDirWalker(fsys FileSystem, deep bool, dirVisitor, fileVisitor WalkerVisitor) (w *DirWalker) {
+	if w = NewDirWalker(deep, dirVisitor, fileVisitor); fsys != OsFileSystem {
+		w.FS = walkerFS{fsys}
+	}
+	return
+}
+
+//	An `io/fs.FS` view of a `FileSystem` that, unlike `IoFS`, does not restrict names to `fs.ValidPath` ones.
+type walkerFS struct {
+	fsys FileSystem
+}
+
+func (me walkerFS) Open(name string) (fs.File, error) {
+	return me.fsys.Open(name)
+}
+
+func (me walkerFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return me.fsys.ReadDir(name)
+}
+
+func (me walkerFS) Stat(name string) (fs.FileInfo, error) {
+	return me.fsys.Stat(name)
+}
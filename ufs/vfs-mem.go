@@ -0,0 +1,438 @@
+package ufs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//	A `FileSystem` entirely held in memory, see `NewMemFileSystem`.
+type memFileSystem struct {
+	mutex sync.Mutex
+	root  *memNode
+}
+
+//	A dir or file in a `memFileSystem`.
+type memNode struct {
+	fsFileInfo
+	data     []byte
+	children map[string]*memNode
+}
+
+//	A `File` opened from a `memFileSystem`.
+type memFile struct {
+	fs     *memFileSystem
+	node   *memNode
+	name   string
+	flag   int
+	offset int64
+	dirPos int
+	closed bool
+}
+
+//	Returns a new, empty `FileSystem` that exists only in memory and is safe for concurrent use, mostly useful for tests.
+//	Names are `/`-separated (although `\` is accepted on Windows), and relative ones are resolved against its root `/`.
+func NewMemFileSystem() FileSystem {
+	return &memFileSystem{root: newMemNode("/", os.ModeDir|ModePerm)}
+}
+
+func newMemNode(name string, mode os.FileMode) (me *memNode) {
+	me = &memNode{fsFileInfo: fsFileInfo{name: name, mode: mode, modTime: time.Now()}}
+	if mode.IsDir() {
+		me.children = map[string]*memNode{}
+	}
+	return
+}
+
+//	Returns the node at `name`, or the `os.ErrNotExist` or `errFsNotDir` encountered. The caller must hold `me.mutex`.
+func (me *memFileSystem) lookup(name string) (node *memNode, err error) {
+	node = me.root
+	for _, part := range strings.Split(fsCleanPath(name), "/") {
+		if len(part) > 0 {
+			if !node.mode.IsDir() {
+				return nil, errFsNotDir
+			} else if node = node.children[part]; node == nil {
+				return nil, os.ErrNotExist
+			}
+		}
+	}
+	return
+}
+
+//	Returns the directory node containing `name` and the base name of `name`. The caller must hold `me.mutex`.
+func (me *memFileSystem) lookupParent(name string) (parent *memNode, baseName string, err error) {
+	cleaned := fsCleanPath(name)
+	if cleaned == "/" {
+		return nil, "", os.ErrInvalid
+	}
+	if parent, err = me.lookup(path.Dir(cleaned)); err == nil && !parent.mode.IsDir() {
+		err = errFsNotDir
+	}
+	return parent, path.Base(cleaned), err
+}
+
+func (me *memFileSystem) Open(name string) (File, error) {
+	return me.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (me *memFileSystem) Create(name string) (File, error) {
+	return me.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (me *memFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	node, err := me.lookup(name)
+	if err == os.ErrNotExist && flag&os.O_CREATE != 0 {
+		var parent *memNode
+		var baseName string
+		if parent, baseName, err = me.lookupParent(name); err == nil {
+			node = newMemNode(baseName, perm.Perm())
+			parent.children[baseName], parent.modTime = node, node.modTime
+		}
+	} else if err == nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		err = os.ErrExist
+	}
+	if err == nil && node.mode.IsDir() && flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_TRUNC) != 0 {
+		err = errFsIsDir
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if flag&os.O_TRUNC != 0 && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		node.data, node.modTime = nil, time.Now()
+	}
+	return &memFile{fs: me, node: node, name: name, flag: flag}, nil
+}
+
+func (me *memFileSystem) Mkdir(name string, perm os.FileMode) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	parent, baseName, err := me.lookupParent(name)
+	if err == nil && parent.children[baseName] != nil {
+		err = os.ErrExist
+	}
+	if err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	node := newMemNode(baseName, os.ModeDir|perm.Perm())
+	parent.children[baseName], parent.modTime = node, node.modTime
+	return nil
+}
+
+func (me *memFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	node := me.root
+	for _, part := range strings.Split(fsCleanPath(name), "/") {
+		if len(part) > 0 {
+			if !node.mode.IsDir() {
+				return &os.PathError{Op: "mkdir", Path: name, Err: errFsNotDir}
+			} else if child := node.children[part]; child != nil {
+				node = child
+			} else {
+				child = newMemNode(part, os.ModeDir|perm.Perm())
+				node.children[part], node.modTime, node = child, child.modTime, child
+			}
+		}
+	}
+	if !node.mode.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: name, Err: errFsNotDir}
+	}
+	return nil
+}
+
+func (me *memFileSystem) Remove(name string) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	parent, baseName, err := me.lookupParent(name)
+	if err == nil {
+		if node := parent.children[baseName]; node == nil {
+			err = os.ErrNotExist
+		} else if len(node.children) > 0 {
+			err = errFsNotEmpty
+		} else {
+			delete(parent.children, baseName)
+			parent.modTime = time.Now()
+		}
+	}
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (me *memFileSystem) RemoveAll(name string) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if fsCleanPath(name) == "/" {
+		me.root.children = map[string]*memNode{}
+		return nil
+	}
+	parent, baseName, err := me.lookupParent(name)
+	if err == nil && parent.children[baseName] != nil {
+		delete(parent.children, baseName)
+		parent.modTime = time.Now()
+	} else if err != nil && err != os.ErrNotExist {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (me *memFileSystem) Rename(oldName, newName string) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	oldParent, oldBase, err := me.lookupParent(oldName)
+	var newParent, node *memNode
+	var newBase string
+	if err == nil {
+		if node = oldParent.children[oldBase]; node == nil {
+			err = os.ErrNotExist
+		} else if newParent, newBase, err = me.lookupParent(newName); err == nil {
+			if oldPath, newPath := fsCleanPath(oldName), fsCleanPath(newName); node.mode.IsDir() && strings.HasPrefix(newPath+"/", oldPath+"/") && newPath != oldPath {
+				err = os.ErrInvalid
+			} else if existing := newParent.children[newBase]; existing != nil && existing != node {
+				if existing.mode.IsDir() != node.mode.IsDir() {
+					err = errFsIsDir
+					if !existing.mode.IsDir() {
+						err = errFsNotDir
+					}
+				} else if len(existing.children) > 0 {
+					err = errFsNotEmpty
+				}
+			}
+		}
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: err}
+	}
+	delete(oldParent.children, oldBase)
+	node.name, newParent.children[newBase] = newBase, node
+	oldParent.modTime = time.Now()
+	newParent.modTime = oldParent.modTime
+	return nil
+}
+
+func (me *memFileSystem) Stat(name string) (os.FileInfo, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	node, err := me.lookup(name)
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return node.info(), nil
+}
+
+func (me *memFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	node, err := me.lookup(name)
+	if err == nil && !node.mode.IsDir() {
+		err = errFsNotDir
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return node.entries(), nil
+}
+
+func (me *memFileSystem) Chmod(name string, mode os.FileMode) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	node, err := me.lookup(name)
+	if err != nil {
+		return &os.PathError{Op: "chmod", Path: name, Err: err}
+	}
+	node.mode = node.mode.Type() | mode.Perm()
+	return nil
+}
+
+func (me *memFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	node, err := me.lookup(name)
+	if err != nil {
+		return &os.PathError{Op: "chtimes", Path: name, Err: err}
+	}
+	node.modTime = mtime
+	return nil
+}
+
+//	Returns a snapshot of the current `os.FileInfo` of `me`. The caller must hold the `memFileSystem.mutex`.
+func (me *memNode) info() os.FileInfo {
+	info := me.fsFileInfo
+	info.size = int64(len(me.data))
+	return &info
+}
+
+//	Returns all children of `me`, sorted by name. The caller must hold the `memFileSystem.mutex`.
+func (me *memNode) entries() (entries []os.DirEntry) {
+	entries = make([]os.DirEntry, 0, len(me.children))
+	for _, child := range me.children {
+		entries = append(entries, fs.FileInfoToDirEntry(child.info()))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return
+}
+
+func (me *memFile) Read(p []byte) (n int, err error) {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if n, err = me.readAt(p, me.offset, "read"); n > 0 {
+		me.offset += int64(n)
+	}
+	return
+}
+
+func (me *memFile) ReadAt(p []byte, off int64) (n int, err error) {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if n, err = me.readAt(p, off, "readat"); err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return
+}
+
+func (me *memFile) readAt(p []byte, off int64, op string) (n int, err error) {
+	if err = me.check(op, os.O_WRONLY); err == nil {
+		if me.node.mode.IsDir() {
+			err = &os.PathError{Op: op, Path: me.name, Err: errFsIsDir}
+		} else if off >= int64(len(me.node.data)) {
+			err = io.EOF
+		} else {
+			n = copy(p, me.node.data[off:])
+		}
+	}
+	return
+}
+
+func (me *memFile) Seek(offset int64, whence int) (int64, error) {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if err := me.check("seek", 0); err != nil {
+		return 0, err
+	}
+	switch whence {
+	case io.SeekCurrent:
+		offset += me.offset
+	case io.SeekEnd:
+		offset += int64(len(me.node.data))
+	}
+	if offset < 0 {
+		return 0, &os.PathError{Op: "seek", Path: me.name, Err: os.ErrInvalid}
+	}
+	me.offset = offset
+	return offset, nil
+}
+
+func (me *memFile) Write(p []byte) (n int, err error) {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if me.flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return 0, &os.PathError{Op: "write", Path: me.name, Err: os.ErrPermission}
+	} else if err = me.check("write", 0); err != nil {
+		return
+	}
+	if me.flag&os.O_APPEND != 0 {
+		me.offset = int64(len(me.node.data))
+	}
+	if end := me.offset + int64(len(p)); end > int64(len(me.node.data)) {
+		if end > int64(cap(me.node.data)) {
+			data := make([]byte, end, 2*end)
+			copy(data, me.node.data)
+			me.node.data = data
+		} else {
+			//	clear whatever a previous `Truncate` left behind
+			oldLen := len(me.node.data)
+			me.node.data = me.node.data[:end]
+			for i := oldLen; i < int(end); i++ {
+				me.node.data[i] = 0
+			}
+		}
+	}
+	n = copy(me.node.data[me.offset:], p)
+	me.offset, me.node.modTime = me.offset+int64(n), time.Now()
+	return
+}
+
+func (me *memFile) Close() error {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if err := me.check("close", 0); err != nil {
+		return err
+	}
+	me.closed = true
+	return nil
+}
+
+func (me *memFile) Name() string {
+	return me.name
+}
+
+func (me *memFile) Stat() (os.FileInfo, error) {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if err := me.check("stat", 0); err != nil {
+		return nil, err
+	}
+	return me.node.info(), nil
+}
+
+func (me *memFile) ReadDir(n int) (entries []os.DirEntry, err error) {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if err = me.check("readdir", 0); err != nil {
+		return
+	} else if !me.node.mode.IsDir() {
+		return nil, &os.PathError{Op: "readdir", Path: me.name, Err: errFsNotDir}
+	}
+	all := me.node.entries()
+	if me.dirPos > len(all) {
+		me.dirPos = len(all)
+	}
+	if entries = all[me.dirPos:]; n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		} else if len(entries) > n {
+			entries = entries[:n]
+		}
+	}
+	me.dirPos += len(entries)
+	return
+}
+
+func (me *memFile) Sync() error {
+	return nil
+}
+
+func (me *memFile) Truncate(size int64) error {
+	me.fs.mutex.Lock()
+	defer me.fs.mutex.Unlock()
+	if me.flag&(os.O_WRONLY|os.O_RDWR) == 0 || size < 0 {
+		return &os.PathError{Op: "truncate", Path: me.name, Err: os.ErrInvalid}
+	} else if err := me.check("truncate", 0); err != nil {
+		return err
+	}
+	if size <= int64(len(me.node.data)) {
+		me.node.data = me.node.data[:size]
+	} else {
+		me.node.data = append(me.node.data, make([]byte, size-int64(len(me.node.data)))...)
+	}
+	me.node.modTime = time.Now()
+	return nil
+}
+
+//	Returns an `*os.PathError` if `me` is closed or was opened with any of the `deniedFlags`. The caller must hold the `memFileSystem.mutex`.
+func (me *memFile) check(op string, deniedFlags int) error {
+	if me.closed {
+		return &os.PathError{Op: op, Path: me.name, Err: os.ErrClosed}
+	} else if deniedFlags != 0 && me.flag&deniedFlags != 0 {
+		return &os.PathError{Op: op, Path: me.name, Err: os.ErrPermission}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package ufs
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+//	A read-only `FileSystem` layering multiple others, see `NewUnionFileSystem`.
+type unionFileSystem struct {
+	readOnlyFileSystem
+	layers []FileSystem
+}
+
+//	A directory opened from a `unionFileSystem`, listing the merged entries of all layers.
+type unionDir struct {
+	File
+	entries []os.DirEntry
+	pos     int
+}
+
+//	Returns a read-only `FileSystem` that overlays the specified `layers`: a name resolves to the dir/file in the
+//	first layer containing it, while directory listings merge the entries of that directory in all layers (with
+//	those from earlier layers shadowing same-named ones from later layers). All modifications fail with `os.ErrPermission`.
+func NewUnionFileSystem(layers ...FileSystem) FileSystem {
+	return &unionFileSystem{layers: layers}
+}
+
+func (me *unionFileSystem) Open(name string) (File, error) {
+	return me.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (me *unionFileSystem) OpenFile(name string, flag int, perm os.FileMode) (file File, err error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, fsErrPermission("open", name)
+	}
+	err = &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	for _, layer := range me.layers {
+		if file, err = layer.OpenFile(name, flag, perm); err == nil {
+			var fi os.FileInfo
+			if fi, err = file.Stat(); err == nil && fi.IsDir() {
+				dir := &unionDir{File: file}
+				if dir.entries, err = me.ReadDir(name); err == nil {
+					return dir, nil
+				}
+			}
+			if err != nil {
+				file.Close()
+				file = nil
+			}
+			return
+		} else if !os.IsNotExist(err) {
+			return
+		}
+	}
+	return
+}
+
+func (me *unionFileSystem) Stat(name string) (fi os.FileInfo, err error) {
+	err = &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	for _, layer := range me.layers {
+		if fi, err = layer.Stat(name); err == nil || !os.IsNotExist(err) {
+			return
+		}
+	}
+	return
+}
+
+func (me *unionFileSystem) ReadDir(name string) (entries []os.DirEntry, err error) {
+	var found bool
+	seen := map[string]bool{}
+	for _, layer := range me.layers {
+		var layerEntries []os.DirEntry
+		if fi, e := layer.Stat(name); e != nil || !fi.IsDir() {
+			if !found && (e == nil || !os.IsNotExist(e)) {
+				//	the first layer having `name` has a file there (or failed)
+				if err = e; err == nil {
+					err = &os.PathError{Op: "readdir", Path: name, Err: errFsNotDir}
+				}
+				return nil, err
+			}
+			continue
+		}
+		found = true
+		if layerEntries, err = layer.ReadDir(name); err != nil {
+			return nil, err
+		}
+		for _, entry := range layerEntries {
+			if !seen[entry.Name()] {
+				seen[entry.Name()], entries = true, append(entries, entry)
+			}
+		}
+	}
+	if !found {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return
+}
+
+func (me *unionDir) ReadDir(n int) (entries []os.DirEntry, err error) {
+	if entries = me.entries[me.pos:]; n > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		} else if len(entries) > n {
+			entries = entries[:n]
+		}
+	}
+	me.pos += len(entries)
+	return
+}
@@ -0,0 +1,228 @@
+package ufs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+//	A writable file system, as accepted by the `Fs`-prefixed variants of the `ufs` helpers (such as `FsCopyFile`).
+//	Implementations: `OsFileSystem`, `NewMemFileSystem`, `NewUnionFileSystem` and `NewBasePathFileSystem`.
+//
+//	All methods behave like their namesakes in package `os`, and all errors returned are `*os.PathError`s
+//	(or `*os.LinkError`s) wrapping `os.ErrNotExist`, `os.ErrExist`, `os.ErrPermission` etc. as appropriate.
+type FileSystem interface {
+	Open(name string) (File, error)
+	Create(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(name string, perm os.FileMode) error
+	Remove(name string) error
+	RemoveAll(name string) error
+	Rename(oldName, newName string) error
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	Chmod(name string, mode os.FileMode) error
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+//	A file (or directory) opened via a `FileSystem`. Satisfied by `*os.File`.
+type File interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Writer
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	ReadDir(n int) ([]os.DirEntry, error)
+	Sync() error
+	Truncate(size int64) error
+}
+
+type osFileSystem struct{}
+
+//	The `FileSystem` backed by the real OS file system (via package `os`).
+var OsFileSystem FileSystem = osFileSystem{}
+
+var (
+	errFsIsDir    = errors.New("is a directory")
+	errFsNotDir   = errors.New("not a directory")
+	errFsNotEmpty = errors.New("directory not empty")
+)
+
+func (osFileSystem) Open(name string) (File, error) {
+	return osFile(os.Open(name))
+}
+
+func (osFileSystem) Create(name string) (File, error) {
+	return osFile(os.Create(name))
+}
+
+func (osFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return osFile(os.OpenFile(name, flag, perm))
+}
+
+func (osFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return os.Mkdir(name, perm)
+}
+
+func (osFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	return os.MkdirAll(name, perm)
+}
+
+func (osFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (osFileSystem) RemoveAll(name string) error {
+	return os.RemoveAll(name)
+}
+
+func (osFileSystem) Rename(oldName, newName string) error {
+	return os.Rename(oldName, newName)
+}
+
+func (osFileSystem) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (osFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+//	Avoids returning a non-`nil` `File` interface holding a `nil` `*os.File`.
+func osFile(file *os.File, err error) (File, error) {
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+//	Returns an `io/fs.FS` view of `fsys`, such as for `DirWalker.FS`. Names are resolved as by `fsys`, so
+//	with `OsFileSystem` the `fs.ValidPath` names are relative to the current working directory (`"."`).
+func IoFS(fsys FileSystem) fs.FS {
+	return ioFS{fsys}
+}
+
+type ioFS struct {
+	fsys FileSystem
+}
+
+func (me ioFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return me.fsys.Open(name)
+}
+
+func (me ioFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	return me.fsys.ReadDir(name)
+}
+
+func (me ioFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	return me.fsys.Stat(name)
+}
+
+//	A `FileSystem` wrapper that only permits read access.
+type readOnlyFileSystem struct {
+	fsys FileSystem
+}
+
+//	Returns a `FileSystem` that provides read access to `fsys`, but fails all modifications with `os.ErrPermission`.
+func NewReadOnlyFileSystem(fsys FileSystem) FileSystem {
+	return readOnlyFileSystem{fsys}
+}
+
+func (me readOnlyFileSystem) Open(name string) (File, error) {
+	return me.fsys.Open(name)
+}
+
+func (me readOnlyFileSystem) Create(name string) (File, error) {
+	return nil, fsErrPermission("open", name)
+}
+
+func (me readOnlyFileSystem) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		return nil, fsErrPermission("open", name)
+	}
+	return me.fsys.OpenFile(name, flag, perm)
+}
+
+func (me readOnlyFileSystem) Mkdir(name string, perm os.FileMode) error {
+	return fsErrPermission("mkdir", name)
+}
+
+func (me readOnlyFileSystem) MkdirAll(name string, perm os.FileMode) error {
+	return fsErrPermission("mkdir", name)
+}
+
+func (me readOnlyFileSystem) Remove(name string) error {
+	return fsErrPermission("remove", name)
+}
+
+func (me readOnlyFileSystem) RemoveAll(name string) error {
+	return fsErrPermission("remove", name)
+}
+
+func (me readOnlyFileSystem) Rename(oldName, newName string) error {
+	return &os.LinkError{Op: "rename", Old: oldName, New: newName, Err: os.ErrPermission}
+}
+
+func (me readOnlyFileSystem) Stat(name string) (os.FileInfo, error) {
+	return me.fsys.Stat(name)
+}
+
+func (me readOnlyFileSystem) ReadDir(name string) ([]os.DirEntry, error) {
+	return me.fsys.ReadDir(name)
+}
+
+func (me readOnlyFileSystem) Chmod(name string, mode os.FileMode) error {
+	return fsErrPermission("chmod", name)
+}
+
+func (me readOnlyFileSystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return fsErrPermission("chtimes", name)
+}
+
+func fsErrPermission(op, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: os.ErrPermission}
+}
+
+//	A minimal `os.FileInfo` implementation for `FileSystem`s not backed by the OS.
+type fsFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (me *fsFileInfo) Name() string       { return me.name }
+func (me *fsFileInfo) Size() int64        { return me.size }
+func (me *fsFileInfo) Mode() os.FileMode  { return me.mode }
+func (me *fsFileInfo) ModTime() time.Time { return me.modTime }
+func (me *fsFileInfo) IsDir() bool        { return me.mode.IsDir() }
+func (me *fsFileInfo) Sys() interface{}   { return nil }
+
+//	Returns the `/`-separated, `/`-rooted and cleaned form of `name` used by the non-OS `FileSystem`s.
+func fsCleanPath(name string) string {
+	return path.Clean("/" + filepath.ToSlash(name))
+}
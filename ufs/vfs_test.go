@@ -0,0 +1,176 @@
+package ufs
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMemFS(t *testing.T, files map[string]string) FileSystem {
+	fsys := NewMemFileSystem()
+	for name, contents := range files {
+		if err := FsWriteTextFile(fsys, name, contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fsys
+}
+
+func TestFsWalk(t *testing.T) {
+	fsys := testMemFS(t, map[string]string{"/root/a.txt": "a", "/root/sub/b.txt": "b", "/root/sub/deeper/c.txt": "c"})
+	for _, test := range []struct {
+		name string
+		walk func(FileSystem, string, WalkerVisitor) []error
+		want string
+	}{
+		{name: "all dirs", walk: FsWalkAllDirs, want: "/root /root/sub /root/sub/deeper"},
+		{name: "all files", walk: FsWalkAllFiles, want: "/root/a.txt /root/sub/b.txt /root/sub/deeper/c.txt"},
+		{name: "dirs in", walk: FsWalkDirsIn, want: "/root/sub"},
+		{name: "files in", walk: FsWalkFilesIn, want: "/root/a.txt"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			if errs := test.walk(fsys, "/root", func(fullPath string) bool {
+				got = append(got, fullPath)
+				return true
+			}); len(errs) > 0 {
+				t.Fatal(errs)
+			}
+			sort.Strings(got)
+			if strings.Join(got, " ") != test.want {
+				t.Errorf("got %v, want %q", got, test.want)
+			}
+		})
+	}
+	got := FsAllFilePathsIn(fsys, "/root", "sub/deeper")
+	if sort.Strings(got); strings.Join(got, " ") != "/root/a.txt /root/sub/b.txt" {
+		t.Errorf("FsAllFilePathsIn: got %v", got)
+	}
+}
+
+func TestFsFindFileInfo(t *testing.T) {
+	fsys := testMemFS(t, map[string]string{"/dir/README.md": "readme", "/dir/notes.txt/x": "a dir named like a file"})
+	for _, test := range []struct {
+		baseName           string
+		exts               []string
+		tryLower, tryUpper bool
+		want               string
+	}{
+		{"README", []string{".txt", ".md"}, false, false, "/dir/README.md"},
+		{"readme", []string{".md"}, false, false, ""},
+		{"readme", []string{".md"}, false, true, "/dir/README.md"},
+		{"ReadMe", []string{".md"}, true, false, ""},
+		{"notes", []string{".txt"}, true, true, ""},
+	} {
+		fullFilePath, fileInfo := FsFindFileInfo(fsys, "/dir", test.baseName, test.exts, test.tryLower, test.tryUpper)
+		if fullFilePath != test.want || (fileInfo != nil) != (test.want != "") {
+			t.Errorf("%s%v: got %q, want %q", test.baseName, test.exts, fullFilePath, test.want)
+		}
+	}
+}
+
+func TestFsIsNewer(t *testing.T) {
+	fsys := testMemFS(t, map[string]string{"/src/a.txt": "a", "/src/b.txt": "b", "/out/bin": "bin"})
+	old, now := time.Now().Add(-time.Hour), time.Now()
+	for _, name := range []string{"/src/a.txt", "/src/b.txt"} {
+		if err := fsys.Chtimes(name, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if newer, err := FsIsNewerThanTime(fsys, "/src/a.txt", now.UnixNano()); err != nil || newer {
+		t.Errorf("FsIsNewerThanTime: got %v (%v), want false", newer, err)
+	}
+	if newer, err := FsIsNewerThanTime(fsys, "/src/missing.txt", now.UnixNano()); err == nil || !newer {
+		t.Errorf("FsIsNewerThanTime of missing file: got %v (%v), want true with error", newer, err)
+	}
+	if FsIsAnyInNewerThanAnyOf(fsys, "/src", "/out/bin") {
+		t.Error("FsIsAnyInNewerThanAnyOf: got true, want false")
+	}
+	if later := now.Add(time.Hour); fsys.Chtimes("/src/b.txt", later, later) != nil || !FsIsAnyInNewerThanAnyOf(fsys, "/src", "/out/bin") {
+		t.Error("FsIsAnyInNewerThanAnyOf: got false, want true")
+	}
+	if !FsIsAnyInNewerThanAnyOf(fsys, "/src", "/out/missing") {
+		t.Error("FsIsAnyInNewerThanAnyOf with missing file: got false, want true")
+	}
+}
+
+func TestFsReadFromBinary(t *testing.T) {
+	fsys := NewMemFileSystem()
+	if err := FsWriteBinaryFile(fsys, "/data.bin", []byte{0xff, 0x01, 0x02, 0x03, 0x04}); err != nil {
+		t.Fatal(err)
+	}
+	var u32 uint32
+	if !FsReadFromBinary(fsys, "/data.bin", 1, binary.BigEndian, &u32) || u32 != 0x01020304 {
+		t.Errorf("got %#x, want 0x01020304", u32)
+	}
+	if FsReadFromBinary(fsys, "/data.bin", 2, binary.BigEndian, &u32) {
+		t.Error("want false reading past the end")
+	}
+	if FsReadFromBinary(fsys, "/missing.bin", 0, binary.BigEndian, &u32) {
+		t.Error("want false for a missing file")
+	}
+}
+
+func TestBasePathFileSystemSymlinks(t *testing.T) {
+	outsidePath, dirPath := t.TempDir(), t.TempDir()
+	testWriteFiles(t, outsidePath, map[string]string{"secret.txt": "secret"})
+	testWriteFiles(t, dirPath, map[string]string{"inside/a.txt": "a"})
+	for link, target := range map[string]string{"out": outsidePath, "out-rel": filepath.Join("..", filepath.Base(outsidePath)), "in": "inside", "in/file.txt": "a.txt"} {
+		if err := os.Symlink(target, filepath.Join(dirPath, filepath.FromSlash(link))); err != nil {
+			t.Skip("symlinks unavailable:", err)
+		}
+	}
+	fsys := NewBasePathFileSystem(OsFileSystem, dirPath)
+	for _, test := range []struct {
+		name    string
+		outside bool
+	}{
+		{"/../inside/a.txt", false},
+		{"in/a.txt", false},
+		{"in/file.txt", false},
+		{"out/secret.txt", true},
+		{"/out-rel/secret.txt", true},
+		{"out", true},
+		{"in/../out/secret.txt", true},
+	} {
+		var data string
+		err := FsReadFileIntoStr(fsys, test.name, &data)
+		if test.outside {
+			var pathErr *os.PathError
+			if !errors.Is(err, ErrFsPathOutside) || !errors.As(err, &pathErr) || pathErr.Path != test.name {
+				t.Errorf("%s: got %q (%v), want ErrFsPathOutside", test.name, data, err)
+			}
+		} else if err != nil || data != "a" {
+			t.Errorf("%s: got %q (%v)", test.name, data, err)
+		}
+	}
+	if err := FsWriteTextFile(fsys, "out/new.txt", "escaped"); !errors.Is(err, ErrFsPathOutside) || FileExists(filepath.Join(outsidePath, "new.txt")) {
+		t.Errorf("writing through a symlink to outside: got %v", err)
+	}
+
+	//	symlinks themselves can still be removed (or renamed), not their targets
+	if err := fsys.Rename("out", "gone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.Remove("gone"); err != nil {
+		t.Fatal(err)
+	}
+	if !FileExists(filepath.Join(outsidePath, "secret.txt")) {
+		t.Error("removed the symlink target")
+	}
+}
+
+func TestAllFilePathsInOs(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"a.txt": "a", "sub/b.txt": "b", "skip/c.txt": "c"})
+	got := AllFilePathsIn(dirPath, "skip")
+	sort.Strings(got)
+	if want := []string{filepath.Join(dirPath, "a.txt"), filepath.Join(dirPath, "sub", "b.txt")}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
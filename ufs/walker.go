@@ -1,15 +1,25 @@
 package ufs
 
 import (
-	"io/ioutil"
+	"context"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sync"
+
+	"github.com/wwsheng009/go-util/ustr"
 )
 
 //	Used for `DirWalker.DirVisitor` and `DirWalker.FileVisitor`.
 //	Always return `keepWalking` as true unless you want to immediately terminate a `Walk` early.
 type WalkerVisitor func(fullPath string) (keepWalking bool)
 
+//	Used for `DirWalker.EntryVisitor`. The `entry` is the `fs.DirEntry` (or, for a followed symlink, the
+//	`fs.DirEntry` of its target) obtained from reading the parent directory, so no additional `os.Stat` is needed.
+//	Always return `keepWalking` as true unless you want to immediately terminate a `Walk` early.
+type WalkerEntryVisitor func(fullPath string, entry fs.DirEntry) (keepWalking bool)
+
 //	An empty `WalkerVisitor` used in place of a `nil` directory or file visitor during a `DirWalker.Walk`. Always returns `true`.
 func walkerVisitorNoop(_ string) bool {
 	return true
@@ -39,6 +49,62 @@ type DirWalker struct {
 
 	//	Called for every file being visited during a `Walk`.
 	FileVisitor WalkerVisitor
+
+	//	If set, called for every dir and file being visited during a `Walk`, right after `DirVisitor` or `FileVisitor`.
+	EntryVisitor WalkerEntryVisitor
+
+	//	If set, dirs/files whose `/`-separated path relative to the `Walk`ed `dirPath` is matched
+	//	(as per `ustr.Matcher.IsMatchPath`) are not visited --- and neither is anything inside such dirs.
+	//	See also `LoadGitIgnore`.
+	Ignore *ustr.Matcher
+
+	//	If set, `Walk` reads this file system (eg. an `embed.FS`, a `zip.Reader` or an `os.DirFS`) instead of the
+	//	OS one. All paths (including the `dirPath` to `Walk`, such as `"."`) are then `/`-separated `fs.ValidPath`s.
+	FS fs.FS
+
+	//	If greater than `0`, limits how deep below the `Walk`ed `dirPath` dirs/files are visited:
+	//	`1` visits only its direct items (like `VisitSubDirs` being `false`), `2` also those of its sub-directories etc.
+	MaxDepth int
+
+	//	If `true`, symlinks to directories are walked into just like directories (and visited by `DirVisitor`
+	//	rather than `FileVisitor`). Symlinks leading back to a directory currently being walked are not followed.
+	FollowSymlinks bool
+
+	//	If greater than `1`, up to this many directories are read concurrently.
+	//	Visitors are still invoked one at a time, in the same order as for a sequential `Walk`, unless `Unordered`.
+	Workers int
+
+	//	If `true` (and `Workers` is greater than `1`), visitors get invoked from the worker go-routines
+	//	as soon as their directory has been read: this is faster for large trees, but visitors must then
+	//	be safe for concurrent use and the order of visits is only deterministic within each directory.
+	Unordered bool
+}
+
+//	The state of a single `DirWalker.WalkContext` call.
+type dirWalk struct {
+	*DirWalker
+	ctx                     context.Context
+	dirVisitor, fileVisitor WalkerVisitor
+	workers                 chan struct{}
+	pending                 sync.WaitGroup
+	mutex                   sync.Mutex
+	stopped                 bool
+	errs                    []error
+}
+
+//	A directory being (or having been) read by a `dirWalk`.
+type dirWalkListing struct {
+	done    chan struct{}
+	entries []fs.DirEntry
+	err     error
+}
+
+//	An item of a `dirWalkListing` about to be visited.
+type dirWalkItem struct {
+	fullPath, relPath string
+	entry             fs.DirEntry
+	isDir             bool
+	info              fs.FileInfo
 }
 
 //	Initializes and returns a new `DirWalker` with the specified (optional) `WalkerVisitor`s.
@@ -50,55 +116,257 @@ func NewDirWalker(deep bool, dirVisitor, fileVisitor WalkerVisitor) (me *DirWalk
 
 //	Initiates a walk starting at the specified `dirPath`.
 func (me *DirWalker) Walk(dirPath string) (errs []error) {
-	me.walk(me.VisitSelf, dirPath, &errs)
-	return
+	return me.WalkContext(context.Background(), dirPath)
 }
 
-func (me *DirWalker) walk(walkSelf bool, dirPath string, errs *[]error) {
-	dirVisitor, fileVisitor := me.DirVisitor, me.FileVisitor
-	if dirVisitor == nil {
-		dirVisitor = walkerVisitorNoop
+//	Like `Walk`, but stops early (as soon as any currently running visitors return) once `ctx` is done,
+//	in which case `ctx.Err()` is the last of the returned `errs`.
+func (me *DirWalker) WalkContext(ctx context.Context, dirPath string) (errs []error) {
+	w := &dirWalk{DirWalker: me, ctx: ctx, dirVisitor: me.DirVisitor, fileVisitor: me.FileVisitor}
+	if w.dirVisitor == nil {
+		w.dirVisitor = walkerVisitorNoop
 	}
-	if fileVisitor == nil {
-		fileVisitor = walkerVisitorNoop
+	if w.fileVisitor == nil {
+		w.fileVisitor = walkerVisitorNoop
 	}
-	if walkSelf {
-		walkSelf = dirVisitor(dirPath)
+	if me.Workers > 1 {
+		w.workers = make(chan struct{}, me.Workers)
+	}
+	w.walkRoot(dirPath)
+	w.pending.Wait()
+	if err := ctx.Err(); err != nil {
+		w.errs = append(w.errs, err)
+	}
+	return w.errs
+}
+
+func (me *dirWalk) walkRoot(dirPath string) {
+	root := dirWalkItem{fullPath: dirPath, isDir: true}
+	if me.FollowSymlinks || (me.VisitSelf && me.EntryVisitor != nil) {
+		fi, err := me.stat(dirPath)
+		if err != nil {
+			me.onError(err)
+			return
+		}
+		root.info, root.entry = fi, fs.FileInfoToDirEntry(fi)
+	}
+	if me.VisitSelf && !me.visit(&root) {
+		return
+	}
+	if ancestors := me.ancestorsOf(&root, nil); me.workers != nil && me.Unordered {
+		me.pending.Add(1)
+		go me.walkDirAsync(&root, 0, ancestors)
 	} else {
-		walkSelf = true
+		me.walkDir(&root, 0, ancestors, me.list(dirPath))
 	}
-	if walkSelf {
-		if fileInfos, err := ioutil.ReadDir(dirPath); err == nil {
-			if me.VisitDirsFirst {
-				if !me.walkInfos(dirPath, fileInfos, true, dirVisitor, errs) {
-					return
-				}
-			}
-			if !me.walkInfos(dirPath, fileInfos, false, fileVisitor, errs) {
-				return
+}
+
+//	Visits the items of the directory `dir` at the specified `depth` (`0` for the `Walk`ed `dirPath`) once `listing` is done,
+//	recursing into sub-directories (whose listings get read ahead by the `Workers`, if any).
+func (me *dirWalk) walkDir(dir *dirWalkItem, depth int, ancestors []fs.FileInfo, listing *dirWalkListing) (keepWalking bool) {
+	select {
+	case <-listing.done:
+	case <-me.ctx.Done():
+		return false
+	}
+	if listing.err != nil {
+		return me.onError(listing.err)
+	}
+	items, deeper := me.itemsOf(dir, listing.entries, ancestors), me.isDeeper(depth)
+	var listings []*dirWalkListing
+	if deeper && me.workers != nil {
+		listings = make([]*dirWalkListing, len(items))
+		for i := range items {
+			if items[i].isDir {
+				listings[i] = me.list(items[i].fullPath)
 			}
-			if !me.VisitDirsFirst {
-				if !me.walkInfos(dirPath, fileInfos, true, dirVisitor, errs) {
-					return
+		}
+	}
+	return me.visitItems(items, deeper, func(i int, item *dirWalkItem) bool {
+		var sub *dirWalkListing
+		if listings != nil {
+			sub = listings[i]
+		} else {
+			sub = me.list(item.fullPath)
+		}
+		return me.walkDir(item, depth+1, me.ancestorsOf(item, ancestors), sub)
+	})
+}
+
+//	Like `walkDir` but for `Unordered` walks: reads and visits the directory `dir` in the current go-routine
+//	and spawns a new one for each of its sub-directories.
+func (me *dirWalk) walkDirAsync(dir *dirWalkItem, depth int, ancestors []fs.FileInfo) {
+	defer me.pending.Done()
+	if !me.acquireWorker() {
+		return
+	}
+	defer me.releaseWorker()
+	entries, err := me.readDir(dir.fullPath)
+	if err != nil {
+		me.onError(err)
+		return
+	}
+	items, deeper := me.itemsOf(dir, entries, ancestors), me.isDeeper(depth)
+	me.visitItems(items, deeper, func(_ int, item *dirWalkItem) bool {
+		me.pending.Add(1)
+		go me.walkDirAsync(item, depth+1, me.ancestorsOf(item, ancestors))
+		return true
+	})
+}
+
+//	Visits `items` (files first, unless `VisitDirsFirst`) and calls `walkInto` for each directory if `deeper`.
+func (me *dirWalk) visitItems(items []dirWalkItem, deeper bool, walkInto func(int, *dirWalkItem) bool) (keepWalking bool) {
+	for pass := 0; pass < 2; pass++ {
+		dirsPass := (pass == 0) == me.VisitDirsFirst
+		for i := range items {
+			if item := &items[i]; item.isDir == dirsPass {
+				if !me.visit(item) {
+					return false
+				} else if item.isDir && deeper && !walkInto(i, item) {
+					return false
 				}
 			}
-		} else if *errs = append(*errs, err); me.BreakOnError {
-			return
 		}
 	}
+	return true
 }
 
-func (me *DirWalker) walkInfos(dirPath string, fileInfos []os.FileInfo, isDir bool, visitor WalkerVisitor, errs *[]error) (keepWalking bool) {
-	var fullPath string
-	keepWalking = true
-	for _, fi := range fileInfos {
-		if fullPath = filepath.Join(dirPath, fi.Name()); fi.IsDir() == isDir {
-			if keepWalking = visitor(fullPath); !keepWalking {
-				break
-			} else if isDir && me.VisitSubDirs {
-				me.walk(false, fullPath, errs)
+//	Returns the not-`Ignore`d items for the `entries` of `dir`, resolving symlinks to directories if `FollowSymlinks`.
+func (me *dirWalk) itemsOf(dir *dirWalkItem, entries []fs.DirEntry, ancestors []fs.FileInfo) (items []dirWalkItem) {
+	items = make([]dirWalkItem, 0, len(entries))
+	for _, entry := range entries {
+		item := dirWalkItem{fullPath: me.join(dir.fullPath, entry.Name()), relPath: path.Join(dir.relPath, entry.Name()), entry: entry, isDir: entry.IsDir()}
+		if me.FollowSymlinks {
+			if item.isDir {
+				item.info, _ = entry.Info()
+			} else if entry.Type()&fs.ModeSymlink != 0 {
+				if fi, err := me.stat(item.fullPath); err == nil && fi.IsDir() && !isWalkAncestor(fi, ancestors) {
+					item.isDir, item.info, item.entry = true, fi, fs.FileInfoToDirEntry(fi)
+				}
 			}
 		}
+		if me.Ignore == nil || !me.Ignore.IsMatchPath(item.relPath, item.isDir) {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+//	Returns whether the items of a directory at the specified `depth` are to be walked into.
+func (me *dirWalk) isDeeper(depth int) bool {
+	return me.VisitSubDirs && (me.MaxDepth <= 0 || depth+1 < me.MaxDepth)
+}
+
+//	Returns the directory infos needed for detecting symlink cycles inside `dir`.
+func (me *dirWalk) ancestorsOf(dir *dirWalkItem, ancestors []fs.FileInfo) []fs.FileInfo {
+	if !me.FollowSymlinks {
+		return nil
+	}
+	return append(ancestors[:len(ancestors):len(ancestors)], dir.info)
+}
+
+func isWalkAncestor(fi fs.FileInfo, ancestors []fs.FileInfo) bool {
+	for _, anc := range ancestors {
+		if anc != nil && os.SameFile(fi, anc) {
+			return true
+		}
+	}
+	return false
+}
+
+func (me *dirWalk) visit(item *dirWalkItem) (keepWalking bool) {
+	if me.isStopped() {
+		return false
+	}
+	visitor := me.fileVisitor
+	if item.isDir {
+		visitor = me.dirVisitor
+	}
+	if keepWalking = visitor(item.fullPath); keepWalking && me.EntryVisitor != nil {
+		keepWalking = me.EntryVisitor(item.fullPath, item.entry)
+	}
+	if !keepWalking {
+		me.mutex.Lock()
+		me.stopped = true
+		me.mutex.Unlock()
 	}
 	return
 }
+
+//	Records `err` and returns whether to keep walking.
+func (me *dirWalk) onError(err error) (keepWalking bool) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.errs = append(me.errs, err); me.BreakOnError {
+		me.stopped = true
+	}
+	return !me.stopped
+}
+
+func (me *dirWalk) isStopped() bool {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return me.stopped || me.ctx.Err() != nil
+}
+
+//	Starts reading the directory at `dirPath`: right away if there are no `Workers`, otherwise as soon as one is available.
+func (me *dirWalk) list(dirPath string) (listing *dirWalkListing) {
+	listing = &dirWalkListing{done: make(chan struct{})}
+	if me.workers == nil {
+		listing.entries, listing.err = me.readDir(dirPath)
+		close(listing.done)
+		return
+	}
+	me.pending.Add(1)
+	go func() {
+		defer me.pending.Done()
+		defer close(listing.done)
+		if me.acquireWorker() {
+			defer me.releaseWorker()
+			if !me.isStopped() {
+				listing.entries, listing.err = me.readDir(dirPath)
+			}
+		}
+	}()
+	return
+}
+
+//	Waits for a free worker slot and takes it, unless the walk is (or meanwhile gets) stopped.
+//	Only if `true` is returned must the slot later be handed back via `releaseWorker`.
+func (me *dirWalk) acquireWorker() bool {
+	select {
+	case me.workers <- struct{}{}:
+		if me.isStopped() {
+			me.releaseWorker()
+			return false
+		}
+		return true
+	case <-me.ctx.Done():
+		return false
+	}
+}
+
+func (me *dirWalk) releaseWorker() {
+	<-me.workers
+}
+
+func (me *dirWalk) readDir(dirPath string) ([]fs.DirEntry, error) {
+	if me.FS != nil {
+		return fs.ReadDir(me.FS, dirPath)
+	}
+	return os.ReadDir(dirPath)
+}
+
+func (me *dirWalk) stat(fullPath string) (fs.FileInfo, error) {
+	if me.FS != nil {
+		return fs.Stat(me.FS, fullPath)
+	}
+	return os.Stat(fullPath)
+}
+
+func (me *dirWalk) join(dirPath, name string) string {
+	if me.FS != nil {
+		return path.Join(dirPath, name)
+	}
+	return filepath.Join(dirPath, name)
+}
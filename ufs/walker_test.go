@@ -0,0 +1,159 @@
+package ufs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+//	An `fs.FS` failing to read every directory named `bad*`.
+type testFailingFS struct {
+	fstest.MapFS
+}
+
+func (me testFailingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if strings.HasPrefix(path.Base(name), "bad") {
+		return nil, errors.New("unreadable: " + name)
+	}
+	return me.MapFS.ReadDir(name)
+}
+
+func testWideFS(numDirs int, badDirs bool) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for i := 0; i < numDirs; i++ {
+		name := "dir" + strconv.Itoa(i)
+		if badDirs {
+			name = "bad" + strconv.Itoa(i)
+		}
+		fsys[name+"/file.txt"] = &fstest.MapFile{Data: []byte("x")}
+		fsys[name+"/sub/file.txt"] = &fstest.MapFile{Data: []byte("y")}
+	}
+	return fsys
+}
+
+func testWalkWithin(t *testing.T, timeout time.Duration, walk func() []error) (errs []error) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		errs = walk()
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatal("Walk did not return")
+	}
+	return
+}
+
+func TestDirWalkerStopsWithWorkers(t *testing.T) {
+	for _, test := range []struct {
+		name         string
+		unordered    bool
+		breakOnError bool
+		fsys         fs.FS
+	}{
+		{name: "visitor stops", fsys: testWideFS(50, false)},
+		{name: "visitor stops unordered", unordered: true, fsys: testWideFS(50, false)},
+		{name: "break on error", breakOnError: true, fsys: testFailingFS{testWideFS(50, true)}},
+		{name: "break on error unordered", unordered: true, breakOnError: true, fsys: testFailingFS{testWideFS(50, true)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			numVisits := 0
+			w := NewDirWalker(true, func(string) bool {
+				mutex.Lock()
+				defer mutex.Unlock()
+				numVisits++
+				return test.breakOnError || numVisits < 2
+			}, nil)
+			w.FS, w.Workers, w.Unordered, w.BreakOnError = test.fsys, 2, test.unordered, test.breakOnError
+			errs := testWalkWithin(t, 10*time.Second, func() []error { return w.Walk(".") })
+			if test.breakOnError && len(errs) == 0 {
+				t.Error("want errors")
+			}
+		})
+	}
+}
+
+func TestDirWalkerOrder(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":          {},
+		"b/c.txt":        {},
+		"b/d/e.txt":      {},
+		"b/d/f/g.txt":    {},
+		"h/i.txt":        {},
+		"h/.git/config":  {},
+		"h/node_modules": {Mode: fs.ModeDir},
+	}
+	walk := func(configure func(*DirWalker)) (visited []string) {
+		record := func(fullPath string) bool {
+			visited = append(visited, fullPath)
+			return true
+		}
+		w := NewDirWalker(true, record, record)
+		w.FS = fsys
+		configure(w)
+		if errs := w.Walk("."); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		return
+	}
+	sequential := walk(func(*DirWalker) {})
+	for _, test := range []struct {
+		name      string
+		configure func(*DirWalker)
+		want      []string
+	}{
+		{name: "workers", configure: func(w *DirWalker) { w.Workers = 4 }, want: sequential},
+		{name: "max depth", configure: func(w *DirWalker) { w.MaxDepth = 1 }, want: []string{".", "a.txt", "b", "h"}},
+		{name: "no self", configure: func(w *DirWalker) { w.VisitSelf, w.MaxDepth = false, 1 }, want: []string{"a.txt", "b", "h"}},
+		{name: "dirs first", configure: func(w *DirWalker) { w.VisitDirsFirst, w.MaxDepth = true, 1 }, want: []string{".", "b", "h", "a.txt"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if got := walk(test.configure); strings.Join(got, " ") != strings.Join(test.want, " ") {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+	t.Run("unordered", func(t *testing.T) {
+		var mutex sync.Mutex
+		got := walk(func(w *DirWalker) {
+			w.Workers, w.Unordered = 4, true
+			dirVisitor, fileVisitor := w.DirVisitor, w.FileVisitor
+			w.DirVisitor = func(fullPath string) bool { mutex.Lock(); defer mutex.Unlock(); return dirVisitor(fullPath) }
+			w.FileVisitor = func(fullPath string) bool { mutex.Lock(); defer mutex.Unlock(); return fileVisitor(fullPath) }
+		})
+		want := append([]string{}, sequential...)
+		sort.Strings(got)
+		sort.Strings(want)
+		if strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestDirWalkerFollowSymlinksCycle(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"sub/file.txt": "x"})
+	if err := os.Symlink(dirPath, filepath.Join(dirPath, "sub", "loop")); err != nil {
+		t.Skip("cannot create symlinks:", err)
+	}
+	numDirs := 0
+	w := NewDirWalker(true, func(string) bool { numDirs++; return true }, nil)
+	w.FollowSymlinks = true
+	errs := testWalkWithin(t, 10*time.Second, func() []error { return w.Walk(dirPath) })
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	} else if numDirs != 2 {
+		t.Errorf("got %d dirs visited, want 2", numDirs)
+	}
+}
@@ -4,131 +4,156 @@
 package ufs
 
 import (
-	"log"
-	"path/filepath"
-	"runtime"
-	"time"
+	"context"
+	"sync"
 
 	"github.com/go-forks/fsnotify"
-
-	"github.com/wwsheng009/go-util/ustr"
 )
 
-//	A convenient wrapper around `go-forks/fsnotify.Watcher`.
+//	A convenient wrapper around `go-forks/fsnotify.Watcher`, falling back to polling where that is unavailable.
 //
 //	Usage:
-//		var w ufs.Watcher
+//		w, _ := ufs.NewWatcher()
 //		w.WatchIn(dir, pattern, runNow, handler)
-//		go w.Go()
+//		go w.Run(ctx)
 //		otherCode(laterOn...)
 //		w.WatchIn(anotherDir...)
+//		w.WatchAllIn(aDirTree...)
+//		w.WatchBatchesIn(dirOrTree...)
+//		w.Close()
 type Watcher struct {
+	//	Is `nil` if `IsPolling`.
 	*fsnotify.Watcher
 
 	//	Defaults to a `time.Duration` of 250 milliseconds
 	DebounceNano int64
 
+	//	The quiet period after the most recent change event before all change events accumulated
+	//	since the last batch get delivered to the handlers specified in your `Watcher.WatchBatchesIn` calls.
+	//	Defaults to a `time.Duration` of 250 milliseconds
+	BatchNano int64
+
 	//	A collection of custom `fsnotify.FileEvent` handlers.
 	//	Not related to the handlers specified in your `Watcher.WatchIn` calls.
+	//	If `IsPolling`, these receive equivalent `fsnotify.Event`s synthesized from the polling results.
 	OnEvent []func(evt fsnotify.Event)
 
 	//	A collection of custom `error` handlers.
 	OnError []func(err error)
 
-	closed       chan bool
-	dirsWatching map[string]bool
-	allHandlers  map[string][]WatcherHandler
+	//	Guards `dirsWatching` and all handler maps, which may be modified while `Run` is active.
+	mutex         sync.Mutex
+	closing       sync.Once
+	closed        chan struct{}
+	backend       watcherBackend
+	poller        *watchPoller
+	dirsWatching  map[string]bool
+	allHandlers   map[watchTree][]WatcherHandler
+	treeHandlers  map[watchTree][]WatcherHandler
+	batchHandlers map[watchBatch][]WatcherBatchHandler
 }
 
-//	Always returns a new `Watcher`, even if `err` is not `nil` (in which case, however, `me.Watcher` might be `nil`).
+//	Returns a new `Watcher` receiving OS file-system notifications via `fsnotify`.
+//
+//	Should `fsnotify.NewWatcher` fail, falls back to a polling `Watcher` (see `NewPollingWatcher`)
+//	and returns that `fsnotify` error in `err` only for information (`me` is fully usable regardless).
 func NewWatcher() (me *Watcher, err error) {
-	me = &Watcher{dirsWatching: map[string]bool{}, allHandlers: map[string][]WatcherHandler{}, closed: make(chan bool)}
-	me.DebounceNano = (250 * time.Millisecond).Nanoseconds()
-	me.Watcher, err = fsnotify.NewWatcher()
-	return
-}
-
-//	Closes the underlying `me.Watcher`.
-func (me *Watcher) Close() (err error) {
-	me.closed <- true
-	if me.Watcher != nil {
-		err = me.Watcher.Close()
+	var fsw *fsnotify.Watcher
+	if fsw, err = fsnotify.NewWatcher(); err != nil || fsw == nil {
+		me = NewPollingWatcher(0)
+	} else {
+		me = newWatcher()
+		me.Watcher, me.backend = fsw, fsw
 	}
 	return
 }
 
 //	Starts watching. A loop designed to be called in a new go-routine, as in `go myWatcher.Go`.
-//	This function returns when `me.Close()` is called.
+//	This function returns when `me.Close()` is called. Equivalent to `Run(context.Background())`.
 func (me *Watcher) Go() {
-	defer log.Println("BYEBYE!!")
+	me.Run(context.Background())
+}
+
+//	Starts watching, blocking until either `ctx` is done (returning `ctx.Err()`) or `me.Close()` is called (returning `nil`).
+//	The loop sleeps while there are no change events. Only one `Run` should be active per `Watcher` at any time,
+//	but `WatchIn`, `WatchAllIn` and `WatchBatchesIn` may be called concurrently from any go-routine.
+func (me *Watcher) Run(ctx context.Context) error {
 	var (
-		evt                            fsnotify.Event
-		err                            error
-		hasLast                        bool
-		dif                            int64
-		dirPath, dirPathAndNamePattern string
-		on                             WatcherHandler
-		ons                            []WatcherHandler
-		onErr                          func(err error)
-		onEvt                          func(evt fsnotify.Event)
+		fsEvents <-chan fsnotify.Event
+		polled   <-chan WatchEvent
+		errs     <-chan error
 	)
-	lastEvt := map[string]int64{}
+	if me.poller != nil {
+		polled, errs = me.poller.events, me.poller.errors
+	} else if me.Watcher != nil {
+		fsEvents, errs = me.Events, me.Errors
+	}
+	loop := watchLoop{lastEvt: map[string]int64{}}
+	defer func() {
+		if loop.batchTimer != nil {
+			loop.batchTimer.Stop()
+		}
+	}()
 	for {
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-me.closed:
-			return
-		case evt = <-me.Events:
-			// if evt != nil {
-			_, hasLast = lastEvt[evt.Name]
-			if dif = time.Now().UnixNano() - lastEvt[evt.Name]; dif > me.DebounceNano || !hasLast {
-				for _, onEvt = range me.OnEvent {
+			return nil
+		case <-loop.batchDue:
+			me.onBatchDue(&loop)
+		case evt, ok := <-fsEvents:
+			if !ok {
+				return nil
+			}
+			me.onEvent(&loop, watchEventOf(evt), func() {
+				for _, onEvt := range me.OnEvent {
 					onEvt(evt)
 				}
-				dirPath = filepath.Dir(evt.Name)
-				for dirPathAndNamePattern, ons = range me.allHandlers {
-					if filepath.Dir(dirPathAndNamePattern) == dirPath && ustr.MatchesAny(filepath.Base(evt.Name), filepath.Base(dirPathAndNamePattern)) {
-						for _, on = range ons {
-							on(evt.Name)
-						}
-					}
+			})
+		case evt := <-polled:
+			me.onEvent(&loop, evt, func() {
+				for _, onEvt := range me.OnEvent {
+					onEvt(fsnotifyEventOf(evt))
 				}
-				lastEvt[evt.Name] = time.Now().UnixNano()
+			})
+		case err, ok := <-errs:
+			if !ok {
+				return nil
 			}
-			// }
-		case err = <-me.Errors:
 			if err != nil {
-				for _, onErr = range me.OnError {
-					onErr(err)
-				}
+				me.onError(err)
 			}
-		default:
-			runtime.Gosched()
 		}
 	}
 }
 
-//	Watches dirs/files (whose `filepath.Base` names match the specified `namePattern`) inside the specified `dirPath` for change event notifications.
-//
-//	`handler` is invoked whenever a change event is observed, providing the full path.
-//
-//	`runHandlerNow` allows immediate one-off invokation of `handler`. This will `DirWalker.Walk` the `dirPath`.
-//
-//	An empty `namePattern` is equivalent to `*`.
-func (me *Watcher) WatchIn(dirPath string, namePattern ustr.Pattern, runHandlerNow bool, handler WatcherHandler) (errs []error) {
-	dirPath = filepath.Clean(dirPath)
-	if _, ok := me.dirsWatching[dirPath]; !ok {
-		if err := me.Add(dirPath); err != nil {
-			errs = append(errs, err)
-		} else {
-			me.dirsWatching[dirPath] = true
-		}
+//	Converts `evt` into a raw (not yet coalesced) `WatchEvent`.
+func watchEventOf(evt fsnotify.Event) WatchEvent {
+	switch {
+	case evt.Op&fsnotify.Create != 0:
+		return WatchEvent{Path: evt.Name, Op: WatchCreate}
+	case evt.Op&fsnotify.Remove != 0:
+		return WatchEvent{Path: evt.Name, Op: WatchRemove}
+	case evt.Op&fsnotify.Rename != 0:
+		return WatchEvent{Path: evt.Name, Op: WatchRename}
+	case evt.Op&fsnotify.Write != 0:
+		return WatchEvent{Path: evt.Name, Op: WatchWrite}
 	}
-	if len(errs) == 0 {
-		fullPath := filepath.Join(dirPath, string(namePattern))
-		me.allHandlers[fullPath] = append(me.allHandlers[fullPath], handler)
-		if runHandlerNow {
-			errs = append(errs, watchRunHandler(dirPath, namePattern, handler)...)
-		}
+	return WatchEvent{Path: evt.Name, Op: WatchChmod}
+}
+
+//	Converts a raw `WatchEvent` into the equivalent `fsnotify.Event`.
+func fsnotifyEventOf(evt WatchEvent) fsnotify.Event {
+	switch evt.Op {
+	case WatchCreate:
+		return fsnotify.Event{Name: evt.Path, Op: fsnotify.Create}
+	case WatchRemove:
+		return fsnotify.Event{Name: evt.Path, Op: fsnotify.Remove}
+	case WatchRename:
+		return fsnotify.Event{Name: evt.Path, Op: fsnotify.Rename}
+	case WatchWrite:
+		return fsnotify.Event{Name: evt.Path, Op: fsnotify.Write}
 	}
-	return
+	return fsnotify.Event{Name: evt.Path, Op: fsnotify.Chmod}
 }
@@ -0,0 +1,165 @@
+package ufs
+
+import (
+	"path/filepath"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+//	The kind of change described by a `WatchEvent`.
+type WatchOp uint8
+
+const (
+	//	A dir/file was created (or moved in from outside the watched directories).
+	WatchCreate WatchOp = iota + 1
+
+	//	A file's contents were written to (or a dir/file was replaced by a new one).
+	WatchWrite
+
+	//	A dir/file was removed (or moved out of the watched directories).
+	WatchRemove
+
+	//	A dir/file was moved from `WatchEvent.OldPath` to `WatchEvent.Path`.
+	WatchRename
+
+	//	A dir/file had its permission bits or other attributes changed.
+	WatchChmod
+)
+
+//	Returns a short lower-case name such as "create" or "rename".
+func (me WatchOp) String() string {
+	switch me {
+	case WatchCreate:
+		return "create"
+	case WatchWrite:
+		return "write"
+	case WatchRemove:
+		return "remove"
+	case WatchRename:
+		return "rename"
+	case WatchChmod:
+		return "chmod"
+	}
+	return ""
+}
+
+//	A single (possibly coalesced) change delivered to a `WatcherBatchHandler`.
+type WatchEvent struct {
+	//	The full path of the changed dir/file.
+	Path string
+
+	//	Only set for `WatchRename`: the full path the dir/file was moved from.
+	OldPath string
+
+	Op WatchOp
+}
+
+//	Identifies a `Watcher.WatchBatchesIn` registration.
+type watchBatch struct {
+	dirPath     string
+	namePattern ustr.Pattern
+	deep        bool
+}
+
+//	Returns whether `path` is a dir/file (but not `dirPath` itself) matched by `me`.
+func (me *watchBatch) isMatch(path string) bool {
+	if dirPath := filepath.Dir(path); dirPath != me.dirPath && !(me.deep && pathIsIn(dirPath, me.dirPath)) {
+		return false
+	}
+	return watchIsMatch(me.dirPath, me.namePattern, path)
+}
+
+//	Returns those of `evts` that concern dirs/files matched by `me`.
+func (me *watchBatch) filter(evts []WatchEvent) (matches []WatchEvent) {
+	for _, evt := range evts {
+		if me.isMatch(evt.Path) || (len(evt.OldPath) > 0 && me.isMatch(evt.OldPath)) {
+			matches = append(matches, evt)
+		}
+	}
+	return
+}
+
+//	Reduces the raw `evts` (in order of occurrence) to at most one `WatchEvent` per path:
+//
+//	- a `WatchRename` without `OldPath` directly followed by a `WatchCreate` is merged into one `WatchRename` pair,
+//	while an unpaired one becomes a `WatchRemove`;
+//
+//	- a created-then-removed path is dropped entirely, a removed-then-created one becomes a `WatchWrite`;
+//
+//	- renaming a dir/file that was itself created (or renamed) within `evts` yields a `WatchCreate` (or a single `WatchRename`) of the final path.
+func coalesceWatchEvents(evts []WatchEvent) (coalesced []WatchEvent) {
+	var order []string
+	merged := make(map[string]*WatchEvent, len(evts))
+	put := func(evt WatchEvent) {
+		if cur := merged[evt.Path]; cur == nil {
+			order = append(order, evt.Path)
+			merged[evt.Path] = &evt
+		} else {
+			switch evt.Op {
+			case WatchRemove:
+				if cur.Op == WatchCreate {
+					cur.Op = 0
+				} else {
+					cur.Op, cur.OldPath = WatchRemove, ""
+				}
+			case WatchCreate:
+				if cur.Op == 0 {
+					cur.Op = WatchCreate
+				} else if cur.Op == WatchRemove {
+					cur.Op = WatchWrite
+				}
+			case WatchWrite:
+				if cur.Op == 0 || cur.Op == WatchRemove || cur.Op == WatchChmod {
+					cur.Op = WatchWrite
+				}
+			case WatchChmod:
+				if cur.Op == 0 {
+					cur.Op = WatchChmod
+				}
+			case WatchRename:
+				*cur = evt
+			}
+		}
+	}
+	for i := 0; i < len(evts); i++ {
+		evt := evts[i]
+		if evt.Op == WatchRename && len(evt.OldPath) == 0 {
+			if i+1 < len(evts) && evts[i+1].Op == WatchCreate {
+				i, evt.OldPath, evt.Path = i+1, evt.Path, evts[i+1].Path
+			} else {
+				evt.Op = WatchRemove
+			}
+		}
+		if evt.Op == WatchRename {
+			if prev := merged[evt.OldPath]; prev != nil && prev.Op != 0 {
+				switch prev.Op {
+				case WatchCreate:
+					evt.Op, evt.OldPath = WatchCreate, ""
+				case WatchRename:
+					evt.OldPath = prev.OldPath
+				}
+				prev.Op = 0
+			}
+			if cur := merged[evt.Path]; cur != nil && cur.Op == WatchRename {
+				//	the dir/file previously moved here is now gone again
+				cur.Op = 0
+				put(WatchEvent{Path: cur.OldPath, Op: WatchRemove})
+			}
+			if evt.OldPath == evt.Path {
+				continue
+			}
+		}
+		if cur := merged[evt.Path]; evt.Op == WatchRemove && cur != nil && cur.Op == WatchRename {
+			//	moved here, then removed: so effectively the original got removed
+			cur.Op = 0
+			evt.Path = cur.OldPath
+		}
+		put(evt)
+	}
+	for _, path := range order {
+		if evt := merged[path]; evt.Op != 0 {
+			coalesced = append(coalesced, *evt)
+		}
+	}
+	return
+}
@@ -0,0 +1,197 @@
+package ufs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+//	The state of a dir/file recorded by a `watchPoller` snapshot.
+type watchPollStat struct {
+	isDir   bool
+	mode    os.FileMode
+	size    int64
+	modTime int64
+	inode   uint64
+}
+
+//	A pure-Go `watcherBackend` that re-scans all watched directories every `interval`
+//	and emits raw `WatchEvent`s for the differences between consecutive snapshots.
+type watchPoller struct {
+	interval time.Duration
+	events   chan WatchEvent
+	errors   chan error
+
+	mutex   sync.Mutex
+	dirs    map[string]map[string]watchPollStat
+	closing sync.Once
+	closed  chan struct{}
+}
+
+func newWatchPoller(interval time.Duration) (me *watchPoller) {
+	me = &watchPoller{interval: interval, events: make(chan WatchEvent), errors: make(chan error), dirs: map[string]map[string]watchPollStat{}, closed: make(chan struct{})}
+	go me.loop()
+	return
+}
+
+//	Takes an initial snapshot of `dirPath`, which from then on gets re-scanned every `me.interval`.
+func (me *watchPoller) Add(dirPath string) error {
+	snap, err := watchPollSnapshot(dirPath)
+	if err == nil {
+		me.mutex.Lock()
+		me.dirs[dirPath] = snap
+		me.mutex.Unlock()
+	}
+	return err
+}
+
+//	Stops re-scanning `dirPath`.
+func (me *watchPoller) Remove(dirPath string) (err error) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if _, ok := me.dirs[dirPath]; !ok {
+		err = errors.New("ufs: can't remove non-existent poll watch for: " + dirPath)
+	}
+	delete(me.dirs, dirPath)
+	return
+}
+
+//	Stops polling. Always returns `nil`.
+func (me *watchPoller) Close() error {
+	me.closing.Do(func() { close(me.closed) })
+	return nil
+}
+
+func (me *watchPoller) loop() {
+	ticker := time.NewTicker(me.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-me.closed:
+			return
+		case <-ticker.C:
+			evts, errs := me.poll()
+			for _, err := range errs {
+				select {
+				case me.errors <- err:
+				case <-me.closed:
+					return
+				}
+			}
+			for _, evt := range evts {
+				select {
+				case me.events <- evt:
+				case <-me.closed:
+					return
+				}
+			}
+		}
+	}
+}
+
+//	Re-scans all watched directories and returns the raw `WatchEvent`s describing all differences
+//	to the previous snapshots. A removal and a creation of the same inode are reported as a rename,
+//	in the same manner as `fsnotify` does: a `WatchRename` of the old path directly followed by a `WatchCreate` of the new one.
+func (me *watchPoller) poll() (evts []WatchEvent, errs []error) {
+	type entry struct {
+		path string
+		stat watchPollStat
+	}
+	var created, removed []entry
+	me.mutex.Lock()
+	dirPaths := make([]string, 0, len(me.dirs))
+	for dirPath := range me.dirs {
+		dirPaths = append(dirPaths, dirPath)
+	}
+	sort.Strings(dirPaths)
+	for _, dirPath := range dirPaths {
+		old := me.dirs[dirPath]
+		snap, err := watchPollSnapshot(dirPath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+				continue
+			}
+			//	the directory itself is gone: all its contents are, too
+			if _, parentPolled := me.dirs[filepath.Dir(dirPath)]; !parentPolled {
+				evts = append(evts, WatchEvent{Path: dirPath, Op: WatchRemove})
+			}
+			delete(me.dirs, dirPath)
+		} else {
+			me.dirs[dirPath] = snap
+		}
+		for _, name := range sortedWatchPollNames(old) {
+			cur, ok := snap[name]
+			if path, prev := filepath.Join(dirPath, name), old[name]; !ok {
+				removed = append(removed, entry{path: path, stat: prev})
+			} else if cur.inode != prev.inode || cur.isDir != prev.isDir {
+				evts = append(evts, WatchEvent{Path: path, Op: WatchWrite})
+			} else if !cur.isDir && (cur.size != prev.size || cur.modTime != prev.modTime) {
+				evts = append(evts, WatchEvent{Path: path, Op: WatchWrite})
+			} else if cur.mode != prev.mode {
+				evts = append(evts, WatchEvent{Path: path, Op: WatchChmod})
+			}
+		}
+		for _, name := range sortedWatchPollNames(snap) {
+			if _, ok := old[name]; !ok {
+				created = append(created, entry{path: filepath.Join(dirPath, name), stat: snap[name]})
+			}
+		}
+	}
+	me.mutex.Unlock()
+
+	renamedTo := map[int]bool{}
+	for _, rem := range removed {
+		isRename := false
+		if rem.stat.inode != 0 {
+			for i, cre := range created {
+				if !renamedTo[i] && cre.stat.inode == rem.stat.inode && cre.stat.isDir == rem.stat.isDir {
+					renamedTo[i], isRename = true, true
+					evts = append(evts, WatchEvent{Path: rem.path, Op: WatchRename}, WatchEvent{Path: cre.path, Op: WatchCreate})
+					break
+				}
+			}
+		}
+		if !isRename {
+			evts = append(evts, WatchEvent{Path: rem.path, Op: WatchRemove})
+		}
+	}
+	for i, cre := range created {
+		if !renamedTo[i] {
+			evts = append(evts, WatchEvent{Path: cre.path, Op: WatchCreate})
+		}
+	}
+	return
+}
+
+//	Records the current state of all dirs/files directly inside `dirPath`.
+func watchPollSnapshot(dirPath string) (snap map[string]watchPollStat, err error) {
+	if _, err = os.Stat(dirPath); err != nil {
+		return
+	}
+	snap = map[string]watchPollStat{}
+	vis := func(fullPath string) (keepWalking bool) {
+		if fi, e := os.Lstat(fullPath); e == nil {
+			snap[fi.Name()] = watchPollStat{isDir: fi.IsDir(), mode: fi.Mode(), size: fi.Size(), modTime: fi.ModTime().UnixNano(), inode: fileInode(fi)}
+		}
+		return true
+	}
+	w := NewDirWalker(false, vis, vis)
+	w.VisitSelf = false
+	if errs := w.Walk(dirPath); len(errs) > 0 {
+		err = errs[0]
+	}
+	return
+}
+
+func sortedWatchPollNames(snap map[string]watchPollStat) (names []string) {
+	names = make([]string, 0, len(snap))
+	for name := range snap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
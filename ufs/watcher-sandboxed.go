@@ -4,35 +4,66 @@
 package ufs
 
 import (
-	"path/filepath"
-
-	"github.com/wwsheng009/go-util/ustr"
+	"context"
+	"sync"
 )
 
-//	A convenient wrapper around `go-forks/fsnotify.Watcher`.
+//	A polling-only stand-in for the `go-forks/fsnotify.Watcher` wrapper, since OS file-system notifications are unavailable in the sandbox.
 //
 //	**NOTE**: `godocdown` picked `watcher-sandboxed.go` shim instead of `watcher-default.go`:
 //	Refer to http://godoc.org/github.com/wwsheng009/go-util/fs#Watcher for *actual* docs on `Watcher`.
 type Watcher struct {
-}
+	//	Defaults to a `time.Duration` of 250 milliseconds
+	DebounceNano int64
 
-//	Returns a new `Watcher`, `err` is always nil.
-func NewWatcher() (me *Watcher, err error) {
-	me = &Watcher{}
-	return
+	//	The quiet period after the most recent change event before all change events accumulated
+	//	since the last batch get delivered to the handlers specified in your `Watcher.WatchBatchesIn` calls.
+	//	Defaults to a `time.Duration` of 250 milliseconds
+	BatchNano int64
+
+	//	A collection of custom `error` handlers.
+	OnError []func(err error)
+
+	mutex         sync.Mutex
+	closing       sync.Once
+	closed        chan struct{}
+	backend       watcherBackend
+	poller        *watchPoller
+	dirsWatching  map[string]bool
+	allHandlers   map[watchTree][]WatcherHandler
+	treeHandlers  map[watchTree][]WatcherHandler
+	batchHandlers map[watchBatch][]WatcherBatchHandler
 }
 
-//	Closes the underlying `me.Watcher`.
-func (me *Watcher) Close() (err error) {
+//	Returns a new polling `Watcher` (see `NewPollingWatcher`), `err` is always nil.
+func NewWatcher() (me *Watcher, err error) {
+	me = NewPollingWatcher(0)
 	return
 }
 
 func (me *Watcher) Go() {
+	me.Run(context.Background())
 }
 
-func (me *Watcher) WatchIn(dirPath string, namePattern ustr.Pattern, runHandlerNow bool, handler WatcherHandler) (errs []error) {
-	if runHandlerNow {
-		errs = watchRunHandler(filepath.Clean(dirPath), namePattern, handler)
+func (me *Watcher) Run(ctx context.Context) error {
+	loop := watchLoop{lastEvt: map[string]int64{}}
+	defer func() {
+		if loop.batchTimer != nil {
+			loop.batchTimer.Stop()
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-me.closed:
+			return nil
+		case <-loop.batchDue:
+			me.onBatchDue(&loop)
+		case evt := <-me.poller.events:
+			me.onEvent(&loop, evt, nil)
+		case err := <-me.poller.errors:
+			me.onError(err)
+		}
 	}
-	return
 }
@@ -0,0 +1,323 @@
+package ufs
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+var errWatcherUnavailable = errors.New("ufs: no file-system watcher available")
+
+//	The source of raw change events for a `Watcher`: either `go-forks/fsnotify.Watcher` or a polling `watchPoller`.
+type watcherBackend interface {
+	Add(dirPath string) error
+	Remove(dirPath string) error
+	Close() error
+}
+
+//	Identifies a `Watcher.WatchIn` or `Watcher.WatchAllIn` registration.
+type watchTree struct {
+	dirPath     string
+	namePattern ustr.Pattern
+}
+
+//	The state of a running `Watcher.Run` loop.
+type watchLoop struct {
+	lastEvt    map[string]int64
+	batch      []WatchEvent
+	batchTimer *time.Timer
+	batchDue   <-chan time.Time
+}
+
+//	Returns a new `Watcher` that does not rely on OS file-system notifications, but instead re-scans all watched
+//	directories every `interval` (defaults to 1 second if `0`) and reports differences in mtime, size, mode or inode.
+//
+//	Useful for network mounts, FUSE and other file systems not supported by `fsnotify` (or when running out of OS watch handles).
+//	The `WatchIn`, `WatchAllIn` and `WatchBatchesIn` API remains exactly the same.
+func NewPollingWatcher(interval time.Duration) (me *Watcher) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	me = newWatcher()
+	me.poller = newWatchPoller(interval)
+	me.backend = me.poller
+	return
+}
+
+func newWatcher() *Watcher {
+	return &Watcher{
+		DebounceNano:  (250 * time.Millisecond).Nanoseconds(),
+		BatchNano:     (250 * time.Millisecond).Nanoseconds(),
+		closed:        make(chan struct{}),
+		dirsWatching:  map[string]bool{},
+		allHandlers:   map[watchTree][]WatcherHandler{},
+		treeHandlers:  map[watchTree][]WatcherHandler{},
+		batchHandlers: map[watchBatch][]WatcherBatchHandler{},
+	}
+}
+
+//	Makes any active `Run` (or `Go`) return and closes the underlying `fsnotify` watcher or poller.
+//	Safe to call from any go-routine and any number of times, whether or not `Run` was ever started.
+//	Only the first call returns the error (if any) from closing the underlying watcher.
+func (me *Watcher) Close() (err error) {
+	me.closing.Do(func() {
+		close(me.closed)
+		if me.backend != nil {
+			err = me.backend.Close()
+		}
+	})
+	return
+}
+
+//	Returns whether `me` re-scans watched directories periodically (see `NewPollingWatcher`)
+//	rather than receiving OS file-system notifications.
+func (me *Watcher) IsPolling() bool {
+	return me.poller != nil
+}
+
+//	Watches dirs/files (whose `filepath.Base` names match the specified `namePattern`) inside the specified `dirPath` for change event notifications.
+//	The `namePattern` is a simple-pattern or, if prefixed with `ustr.GlobPatternPrefix`, a `ustr.Glob` pattern (see `ustr.Matcher`).
+//
+//	`handler` is invoked whenever a change event is observed, providing the full path.
+//
+//	`runHandlerNow` allows immediate one-off invokation of `handler`. This will `DirWalker.Walk` the `dirPath`.
+//
+//	An empty `namePattern` is equivalent to `*`.
+func (me *Watcher) WatchIn(dirPath string, namePattern ustr.Pattern, runHandlerNow bool, handler WatcherHandler) (errs []error) {
+	dirPath = filepath.Clean(dirPath)
+	me.mutex.Lock()
+	if err := me.addDir(dirPath); err != nil {
+		errs = append(errs, err)
+	} else {
+		tree := watchTree{dirPath: dirPath, namePattern: namePattern}
+		me.allHandlers[tree] = append(me.allHandlers[tree], handler)
+	}
+	me.mutex.Unlock()
+	if len(errs) == 0 && runHandlerNow {
+		errs = append(errs, watchRunHandler(dirPath, namePattern, false, handler)...)
+	}
+	return
+}
+
+//	Like `WatchIn`, but watches `dirPath` and all its sub-directories recursively.
+//
+//	Sub-directories created later on are registered automatically (and removed ones are dropped again),
+//	so `handler` is invoked for matching dirs/files at any depth below `dirPath`. A `ustr.Glob` `namePattern`
+//	is matched against the `/`-separated path relative to `dirPath`, so that eg. `glob:src/**/*.go` is possible.
+//
+//	`runHandlerNow` allows immediate one-off invokation of `handler` for all matching dirs/files in the tree.
+func (me *Watcher) WatchAllIn(dirPath string, namePattern ustr.Pattern, runHandlerNow bool, handler WatcherHandler) (errs []error) {
+	dirPath = filepath.Clean(dirPath)
+	me.mutex.Lock()
+	if errs = me.addDirsIn(dirPath); len(errs) == 0 {
+		tree := watchTree{dirPath: dirPath, namePattern: namePattern}
+		me.treeHandlers[tree] = append(me.treeHandlers[tree], handler)
+	}
+	me.mutex.Unlock()
+	if len(errs) == 0 && runHandlerNow {
+		errs = append(errs, watchRunHandler(dirPath, namePattern, true, handler)...)
+	}
+	return
+}
+
+//	Like `WatchIn` (or `WatchAllIn` if `deep`), but instead of being invoked once per changed dir/file,
+//	`handler` receives all matching changes accumulated until no further ones occurred for `BatchNano`.
+//
+//	Each batch is coalesced to at most one `WatchEvent` per path: rename pairs are merged into one `WatchRename`,
+//	and dirs/files both created and removed again within the same batch are omitted.
+func (me *Watcher) WatchBatchesIn(dirPath string, namePattern ustr.Pattern, deep bool, handler WatcherBatchHandler) (errs []error) {
+	dirPath = filepath.Clean(dirPath)
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if deep {
+		errs = me.addDirsIn(dirPath)
+	} else if err := me.addDir(dirPath); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		reg := watchBatch{dirPath: dirPath, namePattern: namePattern, deep: deep}
+		me.batchHandlers[reg] = append(me.batchHandlers[reg], handler)
+	}
+	return
+}
+
+//	Registers `dirPath` unless it is already being watched. The caller must hold `me.mutex`.
+func (me *Watcher) addDir(dirPath string) (err error) {
+	if !me.dirsWatching[dirPath] {
+		if me.backend == nil {
+			err = errWatcherUnavailable
+		} else if err = me.backend.Add(dirPath); err == nil {
+			me.dirsWatching[dirPath] = true
+		}
+	}
+	return
+}
+
+//	Registers `dirPath` and all its descendent directories not yet being watched. The caller must hold `me.mutex`.
+func (me *Watcher) addDirsIn(dirPath string) (errs []error) {
+	errs = WalkAllDirs(dirPath, func(fullPath string) (keepWalking bool) {
+		if err := me.addDir(fullPath); err != nil {
+			errs = append(errs, err)
+		}
+		return true
+	})
+	return
+}
+
+//	Unregisters `dirPath` and all its descendent directories currently being watched. The caller must hold `me.mutex`.
+func (me *Watcher) dropDirsIn(dirPath string) {
+	for dp := range me.dirsWatching {
+		if pathIsIn(dp, dirPath) {
+			delete(me.dirsWatching, dp)
+			//	the underlying OS watch is usually gone already, so any error here is of no interest
+			_ = me.backend.Remove(dp)
+		}
+	}
+}
+
+//	Processes a raw change event received by `Run`: feeds the current batch (if any `WatchBatchesIn`
+//	handlers exist), calls `notify` and all matching `WatchIn` and `WatchAllIn` handlers (unless debounced)
+//	and keeps track of directories created or removed.
+func (me *Watcher) onEvent(loop *watchLoop, evt WatchEvent, notify func()) {
+	if me.hasBatchHandlers() {
+		loop.addToBatch(evt, me.BatchNano)
+	}
+	_, hasLast := loop.lastEvt[evt.Path]
+	if dif := time.Now().UnixNano() - loop.lastEvt[evt.Path]; dif > me.DebounceNano || !hasLast {
+		if notify != nil {
+			notify()
+		}
+		for _, on := range me.handlersFor(evt.Path) {
+			on(evt.Path)
+		}
+		loop.lastEvt[evt.Path] = time.Now().UnixNano()
+	}
+	//	anything created in a new directory before it got registered would otherwise go unnoticed
+	for _, path := range me.trackDirs(evt) {
+		if me.hasBatchHandlers() {
+			loop.addToBatch(WatchEvent{Path: path, Op: WatchCreate}, me.BatchNano)
+		}
+		for _, on := range me.handlersFor(path) {
+			on(path)
+		}
+	}
+}
+
+//	Appends `evt` to the current batch and (re)starts the quiet period before its delivery.
+func (me *watchLoop) addToBatch(evt WatchEvent, batchNano int64) {
+	me.batch = append(me.batch, evt)
+	if me.batchTimer == nil {
+		me.batchTimer = time.NewTimer(time.Duration(batchNano))
+	} else {
+		if !me.batchTimer.Stop() {
+			select {
+			case <-me.batchTimer.C:
+			default:
+			}
+		}
+		me.batchTimer.Reset(time.Duration(batchNano))
+	}
+	me.batchDue = me.batchTimer.C
+}
+
+//	Delivers the current batch once `loop.batchDue` fired.
+func (me *Watcher) onBatchDue(loop *watchLoop) {
+	loop.batchDue = nil
+	me.runBatchHandlers(coalesceWatchEvents(loop.batch))
+	loop.batch = nil
+}
+
+//	Keeps `dirsWatching` in sync with directories created or removed inside any `WatchAllIn` tree.
+//	For a newly created directory, returns the full paths of all dirs/files already inside it.
+func (me *Watcher) trackDirs(evt WatchEvent) (newPaths []string) {
+	var errs []error
+	isNewDir := false
+	me.mutex.Lock()
+	if evt.Op == WatchRemove || evt.Op == WatchRename {
+		me.dropDirsIn(evt.Path)
+	}
+	if evt.Op == WatchCreate && me.isInTree(evt.Path) && DirExists(evt.Path) {
+		isNewDir, errs = true, me.addDirsIn(evt.Path)
+	}
+	me.mutex.Unlock()
+	for _, err := range errs {
+		me.onError(err)
+	}
+	if isNewDir {
+		vis := func(fullPath string) bool {
+			newPaths = append(newPaths, fullPath)
+			return true
+		}
+		w := NewDirWalker(true, vis, vis)
+		w.VisitSelf = false
+		w.Walk(evt.Path)
+	}
+	return
+}
+
+//	Returns whether `path` is located inside any `WatchAllIn` (or deep `WatchBatchesIn`) tree. The caller must hold `me.mutex`.
+func (me *Watcher) isInTree(path string) bool {
+	for tree := range me.treeHandlers {
+		if pathIsIn(path, tree.dirPath) {
+			return true
+		}
+	}
+	for reg := range me.batchHandlers {
+		if reg.deep && pathIsIn(path, reg.dirPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func (me *Watcher) hasBatchHandlers() bool {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	return len(me.batchHandlers) > 0
+}
+
+//	Returns all `WatchIn` and `WatchAllIn` handlers interested in `path`.
+func (me *Watcher) handlersFor(path string) (handlers []WatcherHandler) {
+	dirPath := filepath.Dir(path)
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for tree, ons := range me.allHandlers {
+		if tree.dirPath == dirPath && watchIsMatch(tree.dirPath, tree.namePattern, path) {
+			handlers = append(handlers, ons...)
+		}
+	}
+	for tree, ons := range me.treeHandlers {
+		if path != tree.dirPath && pathIsIn(path, tree.dirPath) && watchIsMatch(tree.dirPath, tree.namePattern, path) {
+			handlers = append(handlers, ons...)
+		}
+	}
+	return
+}
+
+func (me *Watcher) runBatchHandlers(evts []WatchEvent) {
+	type delivery struct {
+		on   WatcherBatchHandler
+		evts []WatchEvent
+	}
+	var deliveries []delivery
+	me.mutex.Lock()
+	for reg, ons := range me.batchHandlers {
+		if matches := reg.filter(evts); len(matches) > 0 {
+			for _, on := range ons {
+				deliveries = append(deliveries, delivery{on: on, evts: matches})
+			}
+		}
+	}
+	me.mutex.Unlock()
+	for _, d := range deliveries {
+		d.on(d.evts)
+	}
+}
+
+func (me *Watcher) onError(err error) {
+	for _, onErr := range me.OnError {
+		onErr(err)
+	}
+}
@@ -0,0 +1,405 @@
+package ufs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wwsheng009/go-util/ustr"
+)
+
+const testWatchTimeout = 5 * time.Second
+
+//	Writes all `files` (by `/`-separated path relative to `dirPath`) into `dirPath`, creating directories as needed.
+func testWriteFiles(t *testing.T, dirPath string, files map[string]string) {
+	for name, contents := range files {
+		filePath := filepath.Join(dirPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//	Runs `test` against a polling `Watcher` and, where OS notifications are available, an `fsnotify`-based one.
+func testWatcherBackends(t *testing.T, test func(t *testing.T, w *Watcher)) {
+	t.Run("poll", func(t *testing.T) {
+		test(t, NewPollingWatcher(10*time.Millisecond))
+	})
+	t.Run("notify", func(t *testing.T) {
+		w, err := NewWatcher()
+		if err != nil || w.IsPolling() {
+			w.Close()
+			t.Skip("no OS file-system notifications available:", err)
+		}
+		test(t, w)
+	})
+}
+
+//	Starts `w.Run` and makes sure it has returned before `t` completes.
+func testRunWatcher(t *testing.T, w *Watcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		w.Close()
+		<-done
+	})
+}
+
+//	Receives from `paths` until all of `want` were seen, and returns everything received meanwhile.
+func testWaitForPaths(t *testing.T, paths <-chan string, want ...string) (got []string) {
+	t.Helper()
+	missing := map[string]bool{}
+	for _, path := range want {
+		missing[path] = true
+	}
+	timeout := time.After(testWatchTimeout)
+	for len(missing) > 0 {
+		select {
+		case path := <-paths:
+			got = append(got, path)
+			delete(missing, path)
+		case <-timeout:
+			t.Fatalf("timed out waiting for %v, got %v", missing, got)
+		}
+	}
+	return
+}
+
+func testWaitUntil(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(testWatchTimeout); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting until " + what)
+		}
+	}
+}
+
+func testIsWatching(w *Watcher, dirPath string) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.dirsWatching[dirPath]
+}
+
+func TestWatchAllInRunHandlerNow(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"top.txt": "", "top.md": "", "a/x.txt": "", "a/b/y.txt": "", "a/b/z.md": ""})
+	for _, test := range []struct {
+		pattern ustr.Pattern
+		deep    bool
+		want    []string
+	}{
+		{"*.txt", false, []string{"top.txt"}},
+		{"*.txt", true, []string{"a/b/y.txt", "a/x.txt", "top.txt"}},
+		{"", true, []string{"a", "a/b", "a/b/y.txt", "a/b/z.md", "a/x.txt", "top.md", "top.txt"}},
+		{ustr.GlobPatternPrefix + "a/**/*.md", true, []string{"a/b/z.md"}},
+	} {
+		w := NewPollingWatcher(time.Hour)
+		var got []string
+		handler := func(path string) {
+			relPath, _ := filepath.Rel(dirPath, path)
+			got = append(got, filepath.ToSlash(relPath))
+		}
+		var errs []error
+		if test.deep {
+			errs = w.WatchAllIn(dirPath, test.pattern, true, handler)
+		} else {
+			errs = w.WatchIn(dirPath, test.pattern, true, handler)
+		}
+		if w.Close(); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if sort.Strings(got); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("pattern %q, deep %v: got %v, want %v", test.pattern, test.deep, got, test.want)
+		}
+	}
+}
+
+func TestWatchAllIn(t *testing.T) {
+	testWatcherBackends(t, func(t *testing.T, w *Watcher) {
+		dirPath := t.TempDir()
+		testWriteFiles(t, dirPath, map[string]string{"a/b/old.txt": ""})
+		w.DebounceNano = 0
+		paths := make(chan string, 128)
+		if errs := w.WatchAllIn(dirPath, "*.txt", false, func(path string) { paths <- path }); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		if !testIsWatching(w, filepath.Join(dirPath, "a", "b")) {
+			t.Fatal("existing sub-directory is not being watched")
+		}
+		testRunWatcher(t, w)
+
+		testWriteFiles(t, dirPath, map[string]string{"a/b/new.txt": "new"})
+		testWaitForPaths(t, paths, filepath.Join(dirPath, "a", "b", "new.txt"))
+
+		//	files created in new directories right away must not go unnoticed
+		testWriteFiles(t, dirPath, map[string]string{"a/c/d/deep.txt": "deep"})
+		testWaitForPaths(t, paths, filepath.Join(dirPath, "a", "c", "d", "deep.txt"))
+		testWaitUntil(t, "new sub-directory is watched", func() bool { return testIsWatching(w, filepath.Join(dirPath, "a", "c", "d")) })
+
+		if err := os.RemoveAll(filepath.Join(dirPath, "a", "c")); err != nil {
+			t.Fatal(err)
+		}
+		testWaitUntil(t, "removed sub-directory is dropped", func() bool { return !testIsWatching(w, filepath.Join(dirPath, "a", "c", "d")) })
+	})
+}
+
+func TestWatchInIsShallow(t *testing.T) {
+	testWatcherBackends(t, func(t *testing.T, w *Watcher) {
+		dirPath := t.TempDir()
+		testWriteFiles(t, dirPath, map[string]string{"sub/keep.md": ""})
+		w.DebounceNano = 0
+		paths := make(chan string, 128)
+		if errs := w.WatchIn(dirPath, "*.txt", false, func(path string) { paths <- path }); len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		testRunWatcher(t, w)
+
+		testWriteFiles(t, dirPath, map[string]string{"sub/ignored.txt": "", "other.md": ""})
+		testWriteFiles(t, dirPath, map[string]string{"top.txt": ""})
+		for _, path := range testWaitForPaths(t, paths, filepath.Join(dirPath, "top.txt")) {
+			if path != filepath.Join(dirPath, "top.txt") {
+				t.Errorf("unexpected change notification for %s", path)
+			}
+		}
+	})
+}
+
+func TestWatchBatchesIn(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"sub/keep.txt": "1", "sub/old.txt": "", "sub/gone.txt": "", "top.txt": ""})
+	w := NewPollingWatcher(10 * time.Millisecond)
+	w.BatchNano = (50 * time.Millisecond).Nanoseconds()
+	batches, shallow := make(chan []WatchEvent, 8), make(chan []WatchEvent, 8)
+	if errs := w.WatchBatchesIn(dirPath, "*.txt", true, func(evts []WatchEvent) { batches <- evts }); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if errs := w.WatchBatchesIn(dirPath, "*.txt", false, func(evts []WatchEvent) { shallow <- evts }); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	testRunWatcher(t, w)
+
+	//	holding the poller's lock ensures that all changes are picked up by the very same re-scan
+	sub := filepath.Join(dirPath, "sub")
+	w.poller.mutex.Lock()
+	testWriteFiles(t, dirPath, map[string]string{"sub/keep.txt": "22"})
+	errRename, errRemove := os.Rename(filepath.Join(sub, "old.txt"), filepath.Join(sub, "new.txt")), os.Remove(filepath.Join(sub, "gone.txt"))
+	w.poller.mutex.Unlock()
+	if errRename != nil || errRemove != nil {
+		t.Fatal(errRename, errRemove)
+	}
+	if fi, err := os.Stat(filepath.Join(sub, "new.txt")); err != nil || fileInode(fi) == 0 {
+		t.Skip("renames can't be detected without inodes")
+	}
+
+	select {
+	case evts := <-batches:
+		sort.Slice(evts, func(i, j int) bool { return evts[i].Path < evts[j].Path })
+		want := []WatchEvent{
+			{Path: filepath.Join(sub, "gone.txt"), Op: WatchRemove},
+			{Path: filepath.Join(sub, "keep.txt"), Op: WatchWrite},
+			{Path: filepath.Join(sub, "new.txt"), OldPath: filepath.Join(sub, "old.txt"), Op: WatchRename},
+		}
+		if !reflect.DeepEqual(evts, want) {
+			t.Errorf("got batch %v, want %v", evts, want)
+		}
+	case <-time.After(testWatchTimeout):
+		t.Fatal("timed out waiting for a batch")
+	}
+	select {
+	case evts := <-shallow:
+		t.Errorf("shallow batch handler got changes below its directory: %v", evts)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	runAsync := func(w *Watcher, ctx context.Context) <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- w.Run(ctx) }()
+		return done
+	}
+	waitRun := func(done <-chan error) error {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(testWatchTimeout):
+			t.Fatal("Run did not return")
+		}
+		return nil
+	}
+
+	w := NewPollingWatcher(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := runAsync(w, ctx)
+	if cancel(); !errors.Is(waitRun(done), context.Canceled) {
+		t.Error("Run did not return the context's error")
+	}
+	w.Close()
+
+	w = NewPollingWatcher(time.Hour)
+	done = runAsync(w, context.Background())
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Close()
+		}()
+	}
+	if wg.Wait(); waitRun(done) != nil {
+		t.Error("Run did not return nil after Close")
+	}
+
+	//	Close before Run, and the (ignored) result of a second Close
+	w = NewPollingWatcher(time.Hour)
+	w.Close()
+	if err := waitRun(runAsync(w, context.Background())); err != nil {
+		t.Errorf("Run after Close: got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: got %v", err)
+	}
+}
+
+func TestWatchInWhileRunning(t *testing.T) {
+	dirPath := t.TempDir()
+	const numDirs = 16
+	for i := 0; i < numDirs; i++ {
+		testWriteFiles(t, dirPath, map[string]string{"d" + strconv.Itoa(i) + "/f.md": ""})
+	}
+	w := NewPollingWatcher(10 * time.Millisecond)
+	w.DebounceNano = 0
+	testRunWatcher(t, w)
+
+	paths := make(chan string, 2*numDirs)
+	var wg sync.WaitGroup
+	for i := 0; i < numDirs; i++ {
+		wg.Add(1)
+		go func(subDirPath string) {
+			defer wg.Done()
+			if errs := w.WatchAllIn(subDirPath, "*.txt", false, func(path string) { paths <- path }); len(errs) > 0 {
+				t.Error(errs)
+			}
+		}(filepath.Join(dirPath, "d"+strconv.Itoa(i)))
+	}
+	wg.Wait()
+
+	var want []string
+	for i := 0; i < numDirs; i++ {
+		filePath := filepath.Join(dirPath, "d"+strconv.Itoa(i), "f.txt")
+		want = append(want, filePath)
+		testWriteFiles(t, filepath.Dir(filePath), map[string]string{"f.txt": ""})
+	}
+	testWaitForPaths(t, paths, want...)
+}
+
+func TestWatchPoller(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(t, dirPath, map[string]string{"keep.txt": "1", "old.txt": "", "gone.txt": "", "mode.txt": "", "sub/x.txt": ""})
+	me := newWatchPoller(time.Hour)
+	defer me.Close()
+	if err := me.Add(dirPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := me.Remove(filepath.Join(dirPath, "sub")); err == nil {
+		t.Error("Remove of an unwatched directory: expected an error")
+	}
+	if evts, errs := me.poll(); len(evts) > 0 || len(errs) > 0 {
+		t.Fatalf("no changes yet: got %v, %v", evts, errs)
+	}
+
+	testWriteFiles(t, dirPath, map[string]string{"keep.txt": "22", "made.txt": ""})
+	for _, err := range []error{
+		os.Rename(filepath.Join(dirPath, "old.txt"), filepath.Join(dirPath, "new.txt")),
+		os.Remove(filepath.Join(dirPath, "gone.txt")),
+		os.Chmod(filepath.Join(dirPath, "mode.txt"), 0600),
+		os.RemoveAll(filepath.Join(dirPath, "sub")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dirPath, "new.txt")); err != nil || fileInode(fi) == 0 {
+		t.Skip("renames can't be detected without inodes")
+	}
+	evts, errs := me.poll()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	got := map[string]string{}
+	for _, evt := range evts {
+		got[filepath.Base(evt.Path)] += evt.Op.String()
+	}
+	want := map[string]string{"keep.txt": "write", "gone.txt": "remove", "sub": "remove", "old.txt": "rename", "new.txt": "create", "made.txt": "create"}
+	if fi, err := os.Stat(filepath.Join(dirPath, "mode.txt")); err == nil && fi.Mode().Perm() == 0600 {
+		want["mode.txt"] = "chmod"
+	}
+	//	a freshly created file may well get the inode just freed by another removal
+	if got["gone.txt"] == "rename" && got["made.txt"] == "create" {
+		got["gone.txt"] = "remove"
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for i, evt := range evts {
+		if evt.Op == WatchRename && (i+1 == len(evts) || evts[i+1].Op != WatchCreate) {
+			t.Errorf("rename of %s is not directly followed by its creation: %v", evt.Path, evts)
+		}
+	}
+}
+
+func TestCoalesceWatchEvents(t *testing.T) {
+	create := func(path string) WatchEvent { return WatchEvent{Path: path, Op: WatchCreate} }
+	write := func(path string) WatchEvent { return WatchEvent{Path: path, Op: WatchWrite} }
+	remove := func(path string) WatchEvent { return WatchEvent{Path: path, Op: WatchRemove} }
+	chmod := func(path string) WatchEvent { return WatchEvent{Path: path, Op: WatchChmod} }
+	rename := func(oldPath, path string) []WatchEvent {
+		return []WatchEvent{{Path: oldPath, Op: WatchRename}, create(path)}
+	}
+	cat := func(evtss ...[]WatchEvent) (all []WatchEvent) {
+		for _, evts := range evtss {
+			all = append(all, evts...)
+		}
+		return
+	}
+	for _, test := range []struct {
+		name string
+		evts []WatchEvent
+		want []WatchEvent
+	}{
+		{"empty", nil, nil},
+		{"distinct paths keep their order", []WatchEvent{write("b"), create("a"), chmod("c")}, []WatchEvent{write("b"), create("a"), chmod("c")}},
+		{"created then removed", []WatchEvent{create("a"), write("a"), remove("a")}, nil},
+		{"removed then created", []WatchEvent{remove("a"), create("a")}, []WatchEvent{write("a")}},
+		{"created, removed, created", []WatchEvent{create("a"), remove("a"), create("a")}, []WatchEvent{create("a")}},
+		{"created then written", []WatchEvent{create("a"), write("a")}, []WatchEvent{create("a")}},
+		{"chmod then write", []WatchEvent{chmod("a"), write("a"), chmod("a")}, []WatchEvent{write("a")}},
+		{"rename pair", rename("a", "b"), []WatchEvent{{Path: "b", OldPath: "a", Op: WatchRename}}},
+		{"unpaired rename", []WatchEvent{{Path: "a", Op: WatchRename}, write("b")}, []WatchEvent{remove("a"), write("b")}},
+		{"unpaired trailing rename", []WatchEvent{{Path: "a", Op: WatchRename}}, []WatchEvent{remove("a")}},
+		{"created then renamed", cat([]WatchEvent{create("a")}, rename("a", "b")), []WatchEvent{create("b")}},
+		{"renamed twice", cat(rename("a", "b"), rename("b", "c")), []WatchEvent{{Path: "c", OldPath: "a", Op: WatchRename}}},
+		{"renamed back and forth", cat(rename("a", "b"), rename("b", "a")), nil},
+		{"renamed then removed", cat(rename("a", "b"), []WatchEvent{remove("b")}), []WatchEvent{remove("a")}},
+		{"renamed onto a renamed one", cat(rename("a", "b"), rename("c", "b")), []WatchEvent{{Path: "b", OldPath: "c", Op: WatchRename}, remove("a")}},
+	} {
+		if got := coalesceWatchEvents(test.evts); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package ufs
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+//	Options for `WriteAtomic`, `WriteFileAtomic` and `SaveToFileAtomic`.
+type AtomicWriteOptions struct {
+	//	The permission bits for newly created files (before the umask), defaults to `ModePerm` if `0`.
+	//	When replacing an existing file, its permission bits are kept instead.
+	Perm os.FileMode
+
+	//	If not empty, the previous version of a replaced file is kept at its path suffixed with this, such as `"~"` or `".bak"`.
+	//	Any older backup there gets overwritten.
+	BackupSuffix string
+
+	//	If `true`, skips all `fsync`s: readers still never see a partially written file,
+	//	but the new contents may be lost (with the old ones gone) if the system crashes shortly after.
+	NoSync bool
+}
+
+var (
+	//	If set, `WriteBinaryFile`, `WriteTextFile`, `SaveToFile` (and thus `CopyFile` and `CopyAll`)
+	//	as well as `JsonEncodeToFile` all write via `WriteAtomic` with these options.
+	WriteAtomically *AtomicWriteOptions
+
+	atomicWriteSeq uint32
+)
+
+//	Calls `write` with a temporary file in the same directory as `filePath`, which then (after an `fsync`)
+//	replaces `filePath` via an atomic rename, followed by an `fsync` of the directory. So at all times
+//	`filePath` either does not exist (if it didn't before), or has its complete previous or complete new contents.
+//
+//	If `filePath` is a symlink, its target gets replaced instead. `opt` may be `nil` to use the defaults.
+//	If `write` (or anything else up to the rename) fails, the temporary file is removed and `filePath` remains untouched.
+//	Only if the final `fsync` of the directory fails, its error is returned with `filePath` already replaced:
+//	the new contents are in place but may not survive a system crash.
+func WriteAtomic(filePath string, opt *AtomicWriteOptions, write func(io.Writer) error) (err error) {
+	if opt == nil {
+		opt = &AtomicWriteOptions{}
+	}
+	if realPath, e := filepath.EvalSymlinks(filePath); e == nil {
+		filePath = realPath
+	}
+	perm, dirPath := opt.Perm, filepath.Dir(filePath)
+	if perm == 0 {
+		perm = ModePerm
+	}
+	prev, _ := os.Stat(filePath)
+	if prev != nil {
+		perm = prev.Mode().Perm()
+	}
+	var tmp *os.File
+	var renamed bool
+	if tmp, err = createAtomicTemp(dirPath, filepath.Base(filePath), perm); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil && !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if prev != nil {
+		//	the umask may have applied during creation
+		err = os.Chmod(tmp.Name(), perm)
+	}
+	if err == nil {
+		err = write(tmp)
+	}
+	if err == nil && !opt.NoSync {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err == nil && prev != nil && len(opt.BackupSuffix) > 0 {
+		err = backupFile(filePath, filePath+opt.BackupSuffix)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filePath)
+		renamed = err == nil
+	}
+	if err == nil && !opt.NoSync {
+		err = syncDir(dirPath)
+	}
+	return
+}
+
+//	Like `WriteBinaryFile`, but always writes via `WriteAtomic`.
+func WriteFileAtomic(filePath string, contents []byte, opt *AtomicWriteOptions) error {
+	return WriteAtomic(filePath, opt, func(w io.Writer) (err error) {
+		_, err = w.Write(contents)
+		return
+	})
+}
+
+//	Like `SaveToFile`, but always writes via `WriteAtomic`.
+func SaveToFileAtomic(src io.Reader, dstFilePath string, opt *AtomicWriteOptions) error {
+	return WriteAtomic(dstFilePath, opt, func(w io.Writer) (err error) {
+		_, err = io.Copy(w, src)
+		return
+	})
+}
+
+//	Creates a new, uniquely named hidden file next to where `baseName` is to be written.
+func createAtomicTemp(dirPath, baseName string, perm os.FileMode) (file *os.File, err error) {
+	for i := 0; i < 10000; i++ {
+		seq := atomic.AddUint32(&atomicWriteSeq, 1)
+		name := "." + baseName + "." + strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatUint(uint64(seq), 36) + ".tmp"
+		if file, err = os.OpenFile(filepath.Join(dirPath, name), os.O_RDWR|os.O_CREATE|os.O_EXCL, perm); !os.IsExist(err) {
+			break
+		}
+	}
+	return
+}
+
+//	Makes `backupFilePath` a hard link to (or, if not supported, a copy of) `filePath`.
+func backupFile(filePath, backupFilePath string) (err error) {
+	if err = os.Remove(backupFilePath); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	if err = os.Link(filePath, backupFilePath); err != nil {
+		var src *os.File
+		if src, err = os.Open(filePath); err == nil {
+			defer src.Close()
+			err = writeDirect(backupFilePath, func(w io.Writer) (err error) {
+				_, err = io.Copy(w, src)
+				return
+			})
+		}
+	}
+	return
+}
+
+//	Ensures a preceding rename or creation inside `dirPath` is durable. (Not supported on Windows, where it's a no-op.)
+func syncDir(dirPath string) (err error) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	var dir *os.File
+	if dir, err = os.Open(dirPath); err == nil {
+		defer dir.Close()
+		err = dir.Sync()
+	}
+	return
+}
+
+//	Calls `write` via `WriteAtomic` if `WriteAtomically` is set, else via `writeDirect`.
+func writeFile(filePath string, write func(io.Writer) error) error {
+	if WriteAtomically != nil {
+		return WriteAtomic(filePath, WriteAtomically, write)
+	}
+	return writeDirect(filePath, write)
+}
+
+//	Creates (or truncates) `filePath` and calls `write` with it.
+func writeDirect(filePath string, write func(io.Writer) error) (err error) {
+	var file *os.File
+	if file, err = os.Create(filePath); err == nil {
+		if err = write(file); err == nil {
+			err = file.Close()
+		} else {
+			file.Close()
+		}
+	}
+	return
+}
@@ -0,0 +1,150 @@
+package ufs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteAtomicFailure(t *testing.T) {
+	dirPath := t.TempDir()
+	filePath := filepath.Join(dirPath, "file.txt")
+	if err := WriteTextFile(filePath, "old"); err != nil {
+		t.Fatal(err)
+	}
+	errWrite := errors.New("write failed")
+	err := WriteAtomic(filePath, nil, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errWrite
+	})
+	if err != errWrite {
+		t.Errorf("got %v, want the error of write", err)
+	}
+	if data, err := os.ReadFile(filePath); err != nil || string(data) != "old" {
+		t.Errorf("original not intact: got %q (%v)", data, err)
+	}
+	if entries, err := os.ReadDir(dirPath); err != nil || len(entries) != 1 {
+		t.Errorf("temporary file left behind: got %v (%v)", entries, err)
+	}
+}
+
+func TestWriteAtomicPerm(t *testing.T) {
+	dirPath := t.TempDir()
+	//	newly created files are subject to the umask, so compare against an equally created one
+	umasked := func(perm os.FileMode) os.FileMode {
+		probe := filepath.Join(dirPath, "probe-"+perm.String())
+		if err := os.WriteFile(probe, nil, perm); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(probe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Mode().Perm()
+	}
+	for _, test := range []struct {
+		existing, perm, want os.FileMode
+	}{
+		{0, 0, umasked(ModePerm)},
+		{0, 0600, umasked(0600)},
+		{0640, 0, 0640},
+		{0600, 0644, 0600},
+		{0604, 0, 0604},
+	} {
+		filePath := filepath.Join(dirPath, "file-"+test.existing.String()+test.perm.String())
+		if test.existing != 0 {
+			if err := os.WriteFile(filePath, []byte("old"), test.existing); err != nil {
+				t.Fatal(err)
+			} else if err = os.Chmod(filePath, test.existing); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := WriteFileAtomic(filePath, []byte("new"), &AtomicWriteOptions{Perm: test.perm}); err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := os.Stat(filePath); err != nil || fi.Mode().Perm() != test.want {
+			t.Errorf("existing %v, Perm %v: got %v (%v), want %v", test.existing, test.perm, fi, err, test.want)
+		}
+	}
+}
+
+func TestWriteAtomicSymlink(t *testing.T) {
+	dirPath := t.TempDir()
+	targetPath, linkPath := filepath.Join(dirPath, "data", "target.txt"), filepath.Join(dirPath, "link.txt")
+	testWriteFiles(t, dirPath, map[string]string{"data/target.txt": "old"})
+	if err := os.Symlink(filepath.Join("data", "target.txt"), linkPath); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+	if err := WriteFileAtomic(linkPath, []byte("new"), &AtomicWriteOptions{BackupSuffix: ".bak"}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(linkPath); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink replaced: got %v (%v)", fi, err)
+	}
+	if data, err := os.ReadFile(targetPath); err != nil || string(data) != "new" {
+		t.Errorf("target: got %q (%v)", data, err)
+	}
+	if data, err := os.ReadFile(targetPath + ".bak"); err != nil || string(data) != "old" {
+		t.Errorf("backup of the target: got %q (%v)", data, err)
+	}
+}
+
+func TestWriteAtomicBackup(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		write func(filePath string, contents string, opt *AtomicWriteOptions) error
+	}{
+		{"WriteFileAtomic", func(filePath string, contents string, opt *AtomicWriteOptions) error {
+			return WriteFileAtomic(filePath, []byte(contents), opt)
+		}},
+		{"SaveToFileAtomic", func(filePath string, contents string, opt *AtomicWriteOptions) error {
+			return SaveToFileAtomic(bytes.NewBufferString(contents), filePath, opt)
+		}},
+	} {
+		dirPath := t.TempDir()
+		filePath, opt := filepath.Join(dirPath, "file.txt"), &AtomicWriteOptions{BackupSuffix: "~"}
+		if err := test.write(filePath, "v1", opt); err != nil {
+			t.Fatal(err)
+		}
+		if FileExists(filePath + "~") {
+			t.Errorf("%s: backup of a newly created file", test.name)
+		}
+		testWriteFiles(t, dirPath, map[string]string{"file.txt~": "older backup"})
+		for _, contents := range []string{"v2", "v3"} {
+			if err := test.write(filePath, contents, opt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if data, err := os.ReadFile(filePath); err != nil || string(data) != "v3" {
+			t.Errorf("%s: got %q (%v)", test.name, data, err)
+		}
+		if data, err := os.ReadFile(filePath + "~"); err != nil || string(data) != "v2" {
+			t.Errorf("%s: backup: got %q (%v), want the previous version", test.name, data, err)
+		}
+	}
+}
+
+func TestJsonEncodeToFile(t *testing.T) {
+	for _, atomically := range []*AtomicWriteOptions{nil, {BackupSuffix: "~"}} {
+		filePath := filepath.Join(t.TempDir(), "file.json")
+		if err := WriteTextFile(filePath, "old"); err != nil {
+			t.Fatal(err)
+		}
+		func() {
+			defer func(prev *AtomicWriteOptions) { WriteAtomically = prev }(WriteAtomically)
+			WriteAtomically = atomically
+			if err := JsonEncodeToFile(map[string]int{"a": 1}, filePath); err != nil {
+				t.Fatal(err)
+			}
+		}()
+		if data, err := os.ReadFile(filePath); err != nil || string(data) != "{\"a\":1}\n" {
+			t.Errorf("got %q (%v)", data, err)
+		}
+		if data, err := os.ReadFile(filePath + "~"); (atomically != nil) != (err == nil) || (err == nil && string(data) != "old") {
+			t.Errorf("atomically=%v: backup %q (%v)", atomically != nil, data, err)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build plan9
+// +build plan9
+
+package ufs
+
+import (
+	"errors"
+	"os"
+)
+
+//	Returns whether `err` (such as from `os.Rename`) is due to source and destination being on different devices.
+//	On Plan 9, `os.Rename` cannot move between directories at all, failing with `os.ErrInvalid`.
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, os.ErrInvalid)
+}
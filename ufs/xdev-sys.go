@@ -0,0 +1,14 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ufs
+
+import (
+	"errors"
+	"syscall"
+)
+
+//	Returns whether `err` (such as from `os.Rename`) is due to source and destination being on different devices.
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
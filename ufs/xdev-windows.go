@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package ufs
+
+import (
+	"errors"
+	"syscall"
+)
+
+//	Returns whether `err` (such as from `os.Rename`) is due to source and destination being on different devices.
+func isCrossDeviceError(err error) bool {
+	const errorNotSameDevice = syscall.Errno(17) // ERROR_NOT_SAME_DEVICE
+	return errors.Is(err, errorNotSameDevice) || errors.Is(err, syscall.EXDEV)
+}
@@ -141,6 +141,14 @@ func JsonEncodeToFile(from interface{}, tofilepath string) (err error) {
 	return
 }
 
+//	Like `JsonEncodeToFile`, but has `fileWriter` create and write the file instead of doing so directly,
+//	such as one that writes atomically. `fileWriter` must call `write` with the file to be written.
+func JsonEncodeToFileWith(from interface{}, tofilepath string, fileWriter func(filePath string, write func(io.Writer) error) error) error {
+	return fileWriter(tofilepath, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(from)
+	})
+}
+
 //	A convenience short-hand for `log.Println(fmt.Sprintf(LogErrorFormat, err))` if `err` isn't `nil`.
 func LogError(err error) {
 	if err != nil {
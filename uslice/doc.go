@@ -1,2 +1,3 @@
-// Go programming helpers for common 'pseudo-generic' typed-slice needs.
+// Go programming helpers for common slice needs: generic lookups and edits, functional and
+// set-algebra operations, plus the older typed (`Int*`, `F64*`, `Bool*`, `Str*`) variants.
 package uslice
@@ -0,0 +1,269 @@
+package uslice
+
+import (
+	"sort"
+)
+
+//	A pair of values, as produced by `Zip` and consumed by `Unzip`.
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+//	Returns a new slice holding the result of `fn` for each value in `sl`, in order.
+//
+//	O(n). Allocates exactly once, a result of `len(sl)`. For mapping a slice onto itself in-place, see `Each`.
+func Map[T, R any](sl []T, fn func(T) R) []R {
+	ret := make([]R, len(sl))
+	for i, v := range sl {
+		ret[i] = fn(v)
+	}
+	return ret
+}
+
+//	Returns a new slice of all values in `sl` for which `keep` returns `true`, in order.
+//
+//	O(n), with `keep` called exactly once per value. The result grows by `append`, so it is `nil`
+//	if nothing matches and is otherwise allocated O(log n) times. To avoid allocating, see `FilterInPlace`.
+func Filter[T any](sl []T, keep func(T) bool) (ret []T) {
+	for _, v := range sl {
+		if keep(v) {
+			ret = append(ret, v)
+		}
+	}
+	return
+}
+
+//	Like `Filter`, but reuses and overwrites the backing array of `sl` instead of allocating.
+//	The vacated tail of `sl` is zeroed so that it holds on to no garbage.
+//
+//	O(n), with no allocations. `keep` is called exactly once per value.
+func FilterInPlace[T any](sl []T, keep func(T) bool) []T {
+	n := 0
+	for _, v := range sl {
+		if keep(v) {
+			sl[n], n = v, n+1
+		}
+	}
+	clearTail(sl, n)
+	return sl[:n]
+}
+
+//	Folds `sl` into a single value, starting from `initial` and passing the running result
+//	along with each value in order to `fn`.
+//
+//	O(n), with no allocations of its own.
+func Reduce[T, A any](sl []T, initial A, fn func(A, T) A) A {
+	acc := initial
+	for _, v := range sl {
+		acc = fn(acc, v)
+	}
+	return acc
+}
+
+//	Groups the values in `sl` by the result of `key`. Each group keeps the original order
+//	of its values, and `keys` lists the distinct keys in the order of their first occurrence.
+//
+//	O(n) expected, with `key` called exactly once per value.
+func GroupBy[T any, K comparable](sl []T, key func(T) K) (groups map[K][]T, keys []K) {
+	groups = make(map[K][]T)
+	for _, v := range sl {
+		k := key(v)
+		group, exists := groups[k]
+		if !exists {
+			keys = append(keys, k)
+		}
+		groups[k] = append(group, v)
+	}
+	return
+}
+
+//	Splits `sl` into consecutive chunks of `size` values; the last chunk may be shorter.
+//
+//	O(n / size). The chunks are sub-slices of `sl` sharing its backing array, so only the
+//	outer slice is allocated. Their capacity is capped, so that `append`ing to one chunk
+//	never overwrites the next. Panics if `size` is less than `1`.
+func Chunk[T any](sl []T, size int) [][]T {
+	if size < 1 {
+		panic("uslice.Chunk: size must be positive")
+	}
+	ret := make([][]T, 0, (len(sl)+size-1)/size)
+	for i := 0; i < len(sl); i += size {
+		end := minInt(i+size, len(sl))
+		ret = append(ret, sl[i:end:end])
+	}
+	return ret
+}
+
+//	Returns the sliding windows of `size` consecutive values in `sl`, each starting `step` values
+//	after the previous one. Trailing values that do not fill a whole window are not included,
+//	so `sl` shorter than `size` results in no windows at all.
+//
+//	O(n / step). As with `Chunk`, the windows share the backing array of `sl` and have their
+//	capacity capped. Panics if `size` or `step` is less than `1`.
+func Window[T any](sl []T, size int, step int) [][]T {
+	if size < 1 || step < 1 {
+		panic("uslice.Window: size and step must be positive")
+	}
+	if len(sl) < size {
+		return nil
+	}
+	ret := make([][]T, 0, (len(sl)-size)/step+1)
+	for i := 0; i+size <= len(sl); i += step {
+		ret = append(ret, sl[i:i+size:i+size])
+	}
+	return ret
+}
+
+//	Pairs up the values of `a` and `b` by their index. The result is as long as the shorter of both.
+//
+//	O(n), allocating exactly once.
+func Zip[A, B any](a []A, b []B) []Pair[A, B] {
+	ret := make([]Pair[A, B], minInt(len(a), len(b)))
+	for i := range ret {
+		ret[i] = Pair[A, B]{First: a[i], Second: b[i]}
+	}
+	return ret
+}
+
+//	The inverse of `Zip`: splits `pairs` into their first and second values.
+//
+//	O(n), allocating exactly twice.
+func Unzip[A, B any](pairs []Pair[A, B]) (a []A, b []B) {
+	a, b = make([]A, len(pairs)), make([]B, len(pairs))
+	for i, p := range pairs {
+		a[i], b[i] = p.First, p.Second
+	}
+	return
+}
+
+//	Splits `sl` into the values for which `pred` returns `true` and those for which it returns `false`,
+//	both in their original order.
+//
+//	O(n), with `pred` called exactly once per value. Allocates once: both results share a single new
+//	backing array of `len(sl)`, with capacities capped so that `append`ing to `matching` never overwrites `rest`.
+func Partition[T any](sl []T, pred func(T) bool) (matching []T, rest []T) {
+	all, l, r := make([]T, len(sl)), 0, len(sl)
+	for _, v := range sl {
+		if pred(v) {
+			all[l], l = v, l+1
+		} else {
+			r--
+			all[r] = v
+		}
+	}
+	//	non-matching values were filled in from the back, so restore their order
+	for i, j := r, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	return all[:l:l], all[l:]
+}
+
+//	Like `Partition`, but reorders `sl` in-place so that all values for which `pred` returns `true` come first,
+//	and returns their count. Unlike `Partition`, it does not preserve the relative order of the values.
+//
+//	O(n), with no allocations. `pred` is called exactly once per value.
+func PartitionInPlace[T any](sl []T, pred func(T) bool) int {
+	l, r := 0, len(sl)-1
+	for l <= r {
+		if pred(sl[l]) {
+			l++
+		} else {
+			sl[l], sl[r] = sl[r], sl[l]
+			r--
+		}
+	}
+	return l
+}
+
+//	Returns the distinct values in `sl`, keeping the first occurrence of each in order.
+//
+//	O(n) expected. Allocates the result and a set of the values seen.
+func Distinct[T comparable](sl []T) []T {
+	return DistinctBy(sl, func(v T) T { return v })
+}
+
+//	Returns the values in `sl` whose `key` was not already returned for any preceding value, in order.
+//
+//	O(n) expected, with `key` called exactly once per value. Allocates the result and a set of the keys seen.
+func DistinctBy[T any, K comparable](sl []T, key func(T) K) []T {
+	ret := make([]T, 0, len(sl))
+	seen := make(map[K]struct{}, len(sl))
+	for _, v := range sl {
+		if k := key(v); !mapHas(seen, k) {
+			seen[k], ret = struct{}{}, append(ret, v)
+		}
+	}
+	return ret
+}
+
+//	Like `DistinctBy`, but reuses and overwrites the backing array of `sl` for the result,
+//	zeroing its vacated tail. Only the set of the keys seen is allocated.
+func DistinctByInPlace[T any, K comparable](sl []T, key func(T) K) []T {
+	seen, n := make(map[K]struct{}, len(sl)), 0
+	for _, v := range sl {
+		if k := key(v); !mapHas(seen, k) {
+			seen[k], sl[n], n = struct{}{}, v, n+1
+		}
+	}
+	clearTail(sl, n)
+	return sl[:n]
+}
+
+//	Stably sorts `sl` in-place by ascending `key`, and returns it for convenience.
+//	Values with equal keys keep their relative order.
+//
+//	O(n log n) comparisons and O(n log² n) swaps, as with `sort.Stable`. `key` is called exactly once
+//	per value rather than once per comparison, at the cost of allocating a slice of `len(sl)` keys.
+func SortBy[T any, K Ordered](sl []T, key func(T) K) []T {
+	sort.Stable(&sortByKey[T, K]{vals: sl, keys: Map(sl, key)})
+	return sl
+}
+
+//	Like `SortBy`, but sorts by descending `key`. Values with equal keys still keep their relative order.
+func SortByDesc[T any, K Ordered](sl []T, key func(T) K) []T {
+	sort.Stable(&sortByKey[T, K]{vals: sl, keys: Map(sl, key), descending: true})
+	return sl
+}
+
+type sortByKey[T any, K Ordered] struct {
+	vals       []T
+	keys       []K
+	descending bool
+}
+
+//	Implements `sort.Interface.Len`.
+func (me *sortByKey[T, K]) Len() int { return len(me.vals) }
+
+//	Implements `sort.Interface.Less`.
+func (me *sortByKey[T, K]) Less(i, j int) bool {
+	if me.descending {
+		return me.keys[j] < me.keys[i]
+	}
+	return me.keys[i] < me.keys[j]
+}
+
+//	Implements `sort.Interface.Swap`.
+func (me *sortByKey[T, K]) Swap(i, j int) {
+	me.vals[i], me.vals[j] = me.vals[j], me.vals[i]
+	me.keys[i], me.keys[j] = me.keys[j], me.keys[i]
+}
+
+func clearTail[T any](sl []T, from int) {
+	var zero T
+	for i := from; i < len(sl); i++ {
+		sl[i] = zero
+	}
+}
+
+func mapHas[K comparable, V any](m map[K]V, k K) bool {
+	_, ok := m[k]
+	return ok
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,241 @@
+package uslice
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMapFilterReduce(t *testing.T) {
+	sl := []int{1, 2, 3, 4, 5}
+	if got, want := Map(sl, strconv.Itoa), []string{"1", "2", "3", "4", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Map: got %v, want %v", got, want)
+	}
+	if got := Map([]int(nil), strconv.Itoa); got == nil || len(got) != 0 {
+		t.Errorf("Map of nil: got %#v, want empty", got)
+	}
+
+	isOdd := func(v int) bool { return v%2 != 0 }
+	if got, want := Filter(sl, isOdd), []int{1, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter: got %v, want %v", got, want)
+	}
+	if got := Filter(sl, func(int) bool { return false }); got != nil {
+		t.Errorf("Filter without matches: got %#v, want nil", got)
+	}
+
+	inPlace := []int{1, 2, 3, 4, 5}
+	got := FilterInPlace(inPlace, isOdd)
+	if want := []int{1, 3, 5}; !reflect.DeepEqual(got, want) || &got[0] != &inPlace[0] {
+		t.Errorf("FilterInPlace: got %v, want %v in-place", got, want)
+	}
+	if tail := inPlace[3:]; !reflect.DeepEqual(tail, []int{0, 0}) {
+		t.Errorf("FilterInPlace left garbage in the tail: %v", tail)
+	}
+
+	if got := Reduce(sl, "", func(acc string, v int) string { return acc + strconv.Itoa(v) }); got != "12345" {
+		t.Errorf("Reduce: got %q", got)
+	}
+	if got := Reduce([]int(nil), 42, func(acc, v int) int { return acc + v }); got != 42 {
+		t.Errorf("Reduce of nil: got %d, want the initial value", got)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	groups, keys := GroupBy([]string{"bb", "a", "cc", "ddd", "e"}, func(s string) int { return len(s) })
+	if want := []int{2, 1, 3}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("GroupBy keys: got %v, want %v", keys, want)
+	}
+	if want := map[int][]string{1: {"a", "e"}, 2: {"bb", "cc"}, 3: {"ddd"}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("GroupBy groups: got %v, want %v", groups, want)
+	}
+	if groups, keys := GroupBy([]string(nil), strings.ToLower); len(groups) != 0 || keys != nil {
+		t.Errorf("GroupBy of nil: got %v, %v", groups, keys)
+	}
+}
+
+func TestChunkAndWindow(t *testing.T) {
+	sl := []int{1, 2, 3, 4, 5}
+	for _, test := range []struct {
+		size int
+		want [][]int
+	}{
+		{1, [][]int{{1}, {2}, {3}, {4}, {5}}},
+		{2, [][]int{{1, 2}, {3, 4}, {5}}},
+		{5, [][]int{{1, 2, 3, 4, 5}}},
+		{9, [][]int{{1, 2, 3, 4, 5}}},
+	} {
+		if got := Chunk(sl, test.size); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Chunk(%d): got %v, want %v", test.size, got, test.want)
+		}
+	}
+	if got := Chunk([]int(nil), 3); len(got) != 0 {
+		t.Errorf("Chunk of nil: got %v", got)
+	}
+
+	for _, test := range []struct {
+		size, step int
+		want       [][]int
+	}{
+		{2, 1, [][]int{{1, 2}, {2, 3}, {3, 4}, {4, 5}}},
+		{3, 2, [][]int{{1, 2, 3}, {3, 4, 5}}},
+		{2, 2, [][]int{{1, 2}, {3, 4}}},
+		{2, 9, [][]int{{1, 2}}},
+		{5, 1, [][]int{{1, 2, 3, 4, 5}}},
+		{6, 1, nil},
+	} {
+		if got := Window(sl, test.size, test.step); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Window(%d, %d): got %v, want %v", test.size, test.step, got, test.want)
+		}
+	}
+
+	//	appending to a chunk or window must never overwrite its neighbours
+	chunks := Chunk(sl, 2)
+	_ = append(chunks[0], 99)
+	windows := Window(sl, 2, 2)
+	_ = append(windows[1], 99)
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(sl, want) {
+		t.Errorf("append to a chunk or window changed the source: got %v", sl)
+	}
+
+	for _, fn := range []func(){
+		func() { Chunk(sl, 0) },
+		func() { Window(sl, 0, 1) },
+		func() { Window(sl, 1, 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic for a non-positive size or step")
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+func TestZipUnzip(t *testing.T) {
+	pairs := Zip([]int{1, 2, 3}, []string{"a", "b"})
+	if want := []Pair[int, string]{{1, "a"}, {2, "b"}}; !reflect.DeepEqual(pairs, want) {
+		t.Errorf("Zip: got %v, want %v", pairs, want)
+	}
+	a, b := Unzip(pairs)
+	if !reflect.DeepEqual(a, []int{1, 2}) || !reflect.DeepEqual(b, []string{"a", "b"}) {
+		t.Errorf("Unzip: got %v, %v", a, b)
+	}
+	if pairs := Zip([]int(nil), []string{"a"}); len(pairs) != 0 {
+		t.Errorf("Zip with an empty side: got %v", pairs)
+	}
+}
+
+func TestPartition(t *testing.T) {
+	isEven := func(v int) bool { return v%2 == 0 }
+	for _, test := range []struct {
+		sl             []int
+		matching, rest []int
+	}{
+		{[]int{1, 2, 3, 4, 5, 6}, []int{2, 4, 6}, []int{1, 3, 5}},
+		{[]int{5, 3, 1}, []int{}, []int{5, 3, 1}},
+		{[]int{4, 2}, []int{4, 2}, []int{}},
+		{nil, []int{}, []int{}},
+	} {
+		matching, rest := Partition(test.sl, isEven)
+		if !reflect.DeepEqual(matching, test.matching) || !reflect.DeepEqual(rest, test.rest) {
+			t.Errorf("Partition(%v): got %v, %v, want %v, %v", test.sl, matching, rest, test.matching, test.rest)
+		}
+		if len(rest) > 0 {
+			if _ = append(matching, -1); rest[0] == -1 {
+				t.Errorf("Partition(%v): appending to matching overwrote rest", test.sl)
+			}
+		}
+
+		sl := append([]int(nil), test.sl...)
+		n := PartitionInPlace(sl, isEven)
+		if n != len(test.matching) {
+			t.Errorf("PartitionInPlace(%v): got %d, want %d", test.sl, n, len(test.matching))
+		}
+		if !Equivalent(sl[:n], test.matching) || !Equivalent(sl[n:], test.rest) {
+			t.Errorf("PartitionInPlace(%v): got %v | %v", test.sl, sl[:n], sl[n:])
+		}
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		sl := make([]int, rnd.Intn(20))
+		for j := range sl {
+			sl[j] = rnd.Intn(10)
+		}
+		n := PartitionInPlace(sl, isEven)
+		for j, v := range sl {
+			if isEven(v) != (j < n) {
+				t.Fatalf("PartitionInPlace: %v is not partitioned at %d", sl, n)
+			}
+		}
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	for _, test := range []struct {
+		sl, want []string
+	}{
+		{[]string{"a", "b", "a", "c", "b"}, []string{"a", "b", "c"}},
+		{[]string{"a", "a", "a"}, []string{"a"}},
+		{nil, []string{}},
+	} {
+		if got := Distinct(test.sl); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Distinct(%v): got %v, want %v", test.sl, got, test.want)
+		}
+	}
+
+	sl := []string{"Foo", "bar", "FOO", "Bar", "baz"}
+	want := []string{"Foo", "bar", "baz"}
+	if got := DistinctBy(sl, strings.ToLower); !reflect.DeepEqual(got, want) {
+		t.Errorf("DistinctBy: got %v, want %v", got, want)
+	}
+	got := DistinctByInPlace(sl, strings.ToLower)
+	if !reflect.DeepEqual(got, want) || &got[0] != &sl[0] {
+		t.Errorf("DistinctByInPlace: got %v, want %v in-place", got, want)
+	}
+	if tail := sl[3:]; !reflect.DeepEqual(tail, []string{"", ""}) {
+		t.Errorf("DistinctByInPlace left garbage in the tail: %q", tail)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	type item struct {
+		name string
+		rank int
+	}
+	items := []item{{"c", 2}, {"a", 1}, {"d", 2}, {"b", 1}, {"e", 3}}
+	calls := 0
+	rank := func(it item) int { calls++; return it.rank }
+
+	asc := SortBy(append([]item(nil), items...), rank)
+	if want := []item{{"a", 1}, {"b", 1}, {"c", 2}, {"d", 2}, {"e", 3}}; !reflect.DeepEqual(asc, want) {
+		t.Errorf("SortBy: got %v, want %v", asc, want)
+	}
+	if calls != len(items) {
+		t.Errorf("SortBy called key %d times, want %d", calls, len(items))
+	}
+
+	desc := SortByDesc(append([]item(nil), items...), rank)
+	if want := []item{{"e", 3}, {"c", 2}, {"d", 2}, {"a", 1}, {"b", 1}}; !reflect.DeepEqual(desc, want) {
+		t.Errorf("SortByDesc: got %v, want %v", desc, want)
+	}
+
+	rnd := rand.New(rand.NewSource(2))
+	for i := 0; i < 50; i++ {
+		sl := make([]int, rnd.Intn(50))
+		for j := range sl {
+			sl[j] = rnd.Intn(100)
+		}
+		want := make([]int, len(sl))
+		copy(want, sl)
+		sort.Ints(want)
+		if got := SortBy(sl, func(v int) int { return v }); !reflect.DeepEqual(got, want) {
+			t.Fatalf("SortBy: got %v, want %v", got, want)
+		}
+	}
+}
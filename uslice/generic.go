@@ -0,0 +1,169 @@
+package uslice
+
+import (
+	"sort"
+)
+
+//	Satisfied by all built-in types supporting the `<`, `<=`, `>=` and `>` operators.
+type Ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64 | ~string
+}
+
+//	Appends `v` to `*ref` only if `*ref` does not already contain `v`.
+func AppendUnique[T comparable](ref *[]T, v T) {
+	if At(*ref, v) < 0 {
+		*ref = append(*ref, v)
+	}
+}
+
+//	Appends each value in `vals` to `*ref` only if `*ref` does not already contain it.
+func AppendUniques[T comparable](ref *[]T, vals ...T) {
+	for _, v := range vals {
+		AppendUnique(ref, v)
+	}
+}
+
+//	Returns the position of `val` in `slice`, or `-1`.
+func At[T comparable](slice []T, val T) int {
+	for i, v := range slice {
+		if v == val {
+			return i
+		}
+	}
+	return -1
+}
+
+//	Converts `src` to `dst`.
+//
+//	If `sparse` is `true`, then only successfully converted `T` values are placed
+//	in `dst`, so there may not be a 1-to-1 correspondence of `dst` to `src` in length or indices.
+//
+//	If `sparse` is `false`, `dst` has the same length as `src` and non-convertable values remain zeroed.
+func Convert[T any](src []interface{}, sparse bool) (dst []T) {
+	if sparse {
+		for _, v := range src {
+			if val, ok := v.(T); ok {
+				dst = append(dst, val)
+			}
+		}
+	} else {
+		dst = make([]T, len(src))
+		for i, v := range src {
+			dst[i], _ = v.(T)
+		}
+	}
+	return
+}
+
+//	Sets each value in `sl` to the result of passing it to each `apply` func.
+//	Although `sl` is modified in-place, it is also returned for convenience.
+func Each[T any](sl []T, apply ...func(T) T) []T {
+	for _, fn := range apply {
+		for i := range sl {
+			sl[i] = fn(sl[i])
+		}
+	}
+	return sl
+}
+
+//	Calls `SetCap` only if the current `cap(*ref)` is less than the specified `capacity`.
+func EnsureCap[T any](ref *[]T, capacity int) {
+	if cap(*ref) < capacity {
+		SetCap(ref, capacity)
+	}
+}
+
+//	Calls `SetLen` only if the current `len(*ref)` is less than the specified `length`.
+func EnsureLen[T any](ref *[]T, length int) {
+	if len(*ref) < length {
+		SetLen(ref, length)
+	}
+}
+
+//	Returns whether `one` and `two` only contain identical values, regardless of ordering.
+func Equivalent[T comparable](one, two []T) bool {
+	if len(one) != len(two) {
+		return false
+	}
+	for _, v := range one {
+		if At(two, v) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//	Returns whether `val` is in `slice`.
+func Has[T comparable](slice []T, val T) bool {
+	return At(slice, val) >= 0
+}
+
+//	Returns whether at least one of the specified `vals` is contained in `slice`.
+func HasAny[T comparable](slice []T, vals ...T) bool {
+	for _, v := range vals {
+		if At(slice, v) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+//	Removes the first occurrence of `v` encountered in `*ref`, or all occurrences if `all` is `true`.
+func Remove[T comparable](ref *[]T, v T, all bool) {
+	for i := 0; i < len(*ref); i++ {
+		if (*ref)[i] == v {
+			*ref = append((*ref)[:i], (*ref)[i+1:]...)
+			if !all {
+				break
+			}
+			i--
+		}
+	}
+}
+
+//	Sets `*ref` to a copy of `*ref` with the specified `capacity`.
+func SetCap[T any](ref *[]T, capacity int) {
+	nu := make([]T, len(*ref), capacity)
+	copy(nu, *ref)
+	*ref = nu
+}
+
+//	Sets `*ref` to a copy of `*ref` with the specified `length`.
+func SetLen[T any](ref *[]T, length int) {
+	nu := make([]T, length)
+	copy(nu, *ref)
+	*ref = nu
+}
+
+//	Returns `sl` sorted in-place by ascending order.
+func SortAsc[T Ordered](sl []T) []T {
+	sort.Slice(sl, func(i, j int) bool { return sl[i] < sl[j] })
+	return sl
+}
+
+//	Returns `sl` sorted in-place by descending order.
+func SortDesc[T Ordered](sl []T) []T {
+	sort.Slice(sl, func(i, j int) bool { return sl[j] < sl[i] })
+	return sl
+}
+
+//	Removes all specified `withoutVals` from `slice`.
+//
+//	If `keepOrder` is `false`, each removed value is replaced by the last one instead of shifting all following values.
+func Without[T comparable](slice []T, keepOrder bool, withoutVals ...T) []T {
+	if len(withoutVals) > 0 {
+		for _, w := range withoutVals {
+			for pos := At(slice, w); pos >= 0; pos = At(slice, w) {
+				if keepOrder {
+					slice = append(slice[:pos], slice[pos+1:]...)
+				} else {
+					slice[pos] = slice[len(slice)-1]
+					slice = slice[:len(slice)-1]
+				}
+			}
+		}
+	}
+	return slice
+}
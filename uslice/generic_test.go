@@ -0,0 +1,173 @@
+package uslice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppendUniqueAndRemove(t *testing.T) {
+	var sl []int
+	AppendUniques(&sl, 3, 1, 3, 2, 1)
+	AppendUnique(&sl, 2)
+	if want := []int{3, 1, 2}; !reflect.DeepEqual(sl, want) {
+		t.Fatalf("AppendUniques: got %v, want %v", sl, want)
+	}
+
+	for _, test := range []struct {
+		sl   []int
+		v    int
+		all  bool
+		want []int
+	}{
+		{[]int{1, 2, 1, 1, 3}, 1, false, []int{2, 1, 1, 3}},
+		{[]int{1, 2, 1, 1, 3}, 1, true, []int{2, 3}},
+		{[]int{1, 1, 1}, 1, true, []int{}},
+		{[]int{1, 2}, 5, true, []int{1, 2}},
+		{nil, 5, true, nil},
+	} {
+		sl := append([]int(nil), test.sl...)
+		if Remove(&sl, test.v, test.all); !reflect.DeepEqual(sl, test.want) {
+			t.Errorf("Remove(%v, %d, %v): got %v, want %v", test.sl, test.v, test.all, sl, test.want)
+		}
+	}
+}
+
+func TestLookups(t *testing.T) {
+	sl := []string{"a", "b", "c", "b"}
+	for _, test := range []struct {
+		val  string
+		at   int
+		has  bool
+		vals []string
+		any  bool
+	}{
+		{"a", 0, true, []string{"x", "a"}, true},
+		{"b", 1, true, []string{"b"}, true},
+		{"x", -1, false, []string{"x", "y"}, false},
+		{"", -1, false, nil, false},
+	} {
+		if at := At(sl, test.val); at != test.at {
+			t.Errorf("At(%q): got %d, want %d", test.val, at, test.at)
+		}
+		if has := Has(sl, test.val); has != test.has {
+			t.Errorf("Has(%q): got %v, want %v", test.val, has, test.has)
+		}
+		if any := HasAny(sl, test.vals...); any != test.any {
+			t.Errorf("HasAny(%q): got %v, want %v", test.vals, any, test.any)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	src := []interface{}{1, "two", 3, nil, 4.0}
+	if got, want := Convert[int](src, true), []int{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sparse Convert: got %v, want %v", got, want)
+	}
+	if got, want := Convert[int](src, false), []int{1, 0, 3, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dense Convert: got %v, want %v", got, want)
+	}
+	if got := Convert[int](nil, true); got != nil {
+		t.Errorf("sparse Convert of nil: got %v, want nil", got)
+	}
+}
+
+func TestEach(t *testing.T) {
+	sl := []int{1, 2, 3}
+	got := Each(sl, func(v int) int { return v + 1 }, func(v int) int { return v * 10 })
+	if want := []int{20, 30, 40}; !reflect.DeepEqual(got, want) || &got[0] != &sl[0] {
+		t.Errorf("Each: got %v, want %v in-place", got, want)
+	}
+}
+
+func TestSetAndEnsureLenCap(t *testing.T) {
+	orig := []int{1, 2, 3}
+	sl := orig
+	if SetLen(&sl, 2); !reflect.DeepEqual(sl, []int{1, 2}) {
+		t.Errorf("SetLen shrink: got %v", sl)
+	}
+	if SetLen(&sl, 4); !reflect.DeepEqual(sl, []int{1, 2, 0, 0}) {
+		t.Errorf("SetLen grow: got %v", sl)
+	}
+	if sl[0] = 9; orig[0] != 1 {
+		t.Error("SetLen did not copy")
+	}
+
+	if EnsureLen(&sl, 3); len(sl) != 4 {
+		t.Errorf("EnsureLen must not shrink: got len %d", len(sl))
+	}
+	if EnsureLen(&sl, 6); !reflect.DeepEqual(sl, []int{9, 2, 0, 0, 0, 0}) {
+		t.Errorf("EnsureLen grow: got %v", sl)
+	}
+
+	if SetCap(&sl, 10); cap(sl) != 10 || len(sl) != 6 {
+		t.Errorf("SetCap: got len %d cap %d", len(sl), cap(sl))
+	}
+	if EnsureCap(&sl, 8); cap(sl) != 10 {
+		t.Errorf("EnsureCap must not shrink: got cap %d", cap(sl))
+	}
+	if EnsureCap(&sl, 20); cap(sl) != 20 || sl[0] != 9 {
+		t.Errorf("EnsureCap grow: got %v cap %d", sl, cap(sl))
+	}
+}
+
+func TestEquivalent(t *testing.T) {
+	for _, test := range []struct {
+		one, two []int
+		want     bool
+	}{
+		{nil, nil, true},
+		{nil, []int{}, true},
+		{[]int{1, 2, 3}, []int{3, 1, 2}, true},
+		{[]int{1, 2}, []int{1, 2, 3}, false},
+		{[]int{1, 2, 4}, []int{1, 2, 3}, false},
+	} {
+		if got := Equivalent(test.one, test.two); got != test.want {
+			t.Errorf("Equivalent(%v, %v): got %v, want %v", test.one, test.two, got, test.want)
+		}
+	}
+}
+
+func TestSortAscDesc(t *testing.T) {
+	if got, want := SortAsc([]string{"b", "c", "a"}), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SortAsc: got %v, want %v", got, want)
+	}
+	if got, want := SortDesc([]float64{1.5, -2, 3}), []float64{3, 1.5, -2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SortDesc: got %v, want %v", got, want)
+	}
+	type myInt int
+	if got, want := SortAsc([]myInt{3, 1, 2}), []myInt{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SortAsc of named type: got %v, want %v", got, want)
+	}
+}
+
+func TestWithout(t *testing.T) {
+	for _, test := range []struct {
+		sl        []int
+		keepOrder bool
+		without   []int
+		want      []int
+	}{
+		{[]int{1, 2, 3, 2, 4}, true, []int{2}, []int{1, 3, 4}},
+		{[]int{1, 2, 3, 2, 4}, false, []int{2}, []int{1, 4, 3}},
+		{[]int{1, 2, 3}, true, []int{1, 2, 3}, []int{}},
+		{[]int{1, 2, 3}, true, nil, []int{1, 2, 3}},
+		{[]int{1, 2, 3}, false, []int{7}, []int{1, 2, 3}},
+	} {
+		sl := append([]int(nil), test.sl...)
+		if got := Without(sl, test.keepOrder, test.without...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Without(%v, %v, %v): got %v, want %v", test.sl, test.keepOrder, test.without, got, test.want)
+		}
+	}
+}
+
+func TestTypedWrappers(t *testing.T) {
+	ints := []int{3, 1}
+	IntAppendUniques(&ints, 1, 2)
+	if !IntEquivalent(ints, []int{1, 2, 3}) || IntAt(ints, 2) != 2 || !IntHasAny(ints, 9, 3) {
+		t.Errorf("Int wrappers: got %v", ints)
+	}
+	strs := []string{"Foo", "bar"}
+	if !StrHasIgnoreCase(strs, "FOO") || StrAtIgnoreCase(strs, "BAR") != 1 || StrHas(strs, "foo") {
+		t.Errorf("Str wrappers: unexpected lookups in %v", strs)
+	}
+}
@@ -0,0 +1,100 @@
+package uslice
+
+//	Returns the distinct values of all `slices`, in the order of their first occurrence.
+//
+//	O(n) expected, where n is the combined length of all `slices`.
+//	Allocates the result and a set of the values seen.
+func Union[T comparable](slices ...[]T) []T {
+	n := 0
+	for _, sl := range slices {
+		n += len(sl)
+	}
+	ret, seen := make([]T, 0, n), make(map[T]struct{}, n)
+	for _, sl := range slices {
+		for _, v := range sl {
+			if !mapHas(seen, v) {
+				seen[v], ret = struct{}{}, append(ret, v)
+			}
+		}
+	}
+	return ret
+}
+
+//	Returns the distinct values of `a` that are also in `b`, in their order in `a`.
+//
+//	O(len(a) + len(b)) expected. Allocates the result and a set of the values of `b`.
+func Intersection[T comparable](a []T, b []T) []T {
+	in := setOf(b)
+	ret := make([]T, 0, minInt(len(a), len(in)))
+	for _, v := range a {
+		if mapHas(in, v) {
+			delete(in, v)
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+//	Returns the distinct values of `a` that are not in `b`, in their order in `a`.
+//
+//	O(len(a) + len(b)) expected. Allocates the result and a set of the values of both `a` and `b`.
+func Difference[T comparable](a []T, b []T) []T {
+	out := setOf(b)
+	ret := make([]T, 0, len(a))
+	for _, v := range a {
+		if !mapHas(out, v) {
+			out[v], ret = struct{}{}, append(ret, v)
+		}
+	}
+	return ret
+}
+
+//	Returns the distinct values that are in exactly one of `a` and `b`:
+//	first those of `a` in their order in `a`, then those of `b` in their order in `b`.
+//
+//	O(len(a) + len(b)) expected. Allocates the result and a set of the values of each of `a` and `b`.
+func SymmetricDifference[T comparable](a []T, b []T) []T {
+	inA, inB := setOf(a), setOf(b)
+	ret := make([]T, 0, len(a)+len(b))
+	for _, v := range a {
+		if !mapHas(inB, v) {
+			inB[v], ret = struct{}{}, append(ret, v)
+		}
+	}
+	for _, v := range b {
+		if !mapHas(inA, v) {
+			inA[v], ret = struct{}{}, append(ret, v)
+		}
+	}
+	return ret
+}
+
+//	Returns whether every value of `sub` is also in `sl`.
+//
+//	O(len(sub) + len(sl)) expected. Allocates a set of the values of `sl`,
+//	unless `sub` is short enough for a linear search per value to be cheaper.
+func IsSubset[T comparable](sub []T, sl []T) bool {
+	if len(sub) <= 8 {
+		for _, v := range sub {
+			if At(sl, v) < 0 {
+				return false
+			}
+		}
+		return true
+	}
+	in := setOf(sl)
+	for _, v := range sub {
+		if !mapHas(in, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func setOf[T comparable](sl []T) map[T]struct{} {
+	set := make(map[T]struct{}, len(sl))
+	for _, v := range sl {
+		set[v] = struct{}{}
+	}
+	return set
+}
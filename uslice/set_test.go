@@ -0,0 +1,78 @@
+package uslice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetOps(t *testing.T) {
+	for _, test := range []struct {
+		a, b                          []int
+		union, inter, diff, symmetric []int
+	}{
+		{
+			[]int{1, 2, 2, 3}, []int{3, 4, 2, 4},
+			[]int{1, 2, 3, 4}, []int{2, 3}, []int{1}, []int{1, 4},
+		},
+		{
+			[]int{5, 6}, []int{7},
+			[]int{5, 6, 7}, []int{}, []int{5, 6}, []int{5, 6, 7},
+		},
+		{
+			[]int{1, 1}, []int{1},
+			[]int{1}, []int{1}, []int{}, []int{},
+		},
+		{
+			nil, []int{1, 1},
+			[]int{1}, []int{}, []int{}, []int{1},
+		},
+		{
+			nil, nil,
+			[]int{}, []int{}, []int{}, []int{},
+		},
+	} {
+		if got := Union(test.a, test.b); !reflect.DeepEqual(got, test.union) {
+			t.Errorf("Union(%v, %v): got %v, want %v", test.a, test.b, got, test.union)
+		}
+		if got := Intersection(test.a, test.b); !reflect.DeepEqual(got, test.inter) {
+			t.Errorf("Intersection(%v, %v): got %v, want %v", test.a, test.b, got, test.inter)
+		}
+		if got := Difference(test.a, test.b); !reflect.DeepEqual(got, test.diff) {
+			t.Errorf("Difference(%v, %v): got %v, want %v", test.a, test.b, got, test.diff)
+		}
+		if got := SymmetricDifference(test.a, test.b); !reflect.DeepEqual(got, test.symmetric) {
+			t.Errorf("SymmetricDifference(%v, %v): got %v, want %v", test.a, test.b, got, test.symmetric)
+		}
+	}
+
+	if got, want := Union([]string{"b"}, nil, []string{"a", "b"}, []string{"c"}), []string{"b", "a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Union of several: got %v, want %v", got, want)
+	}
+	if got := Union[int](); len(got) != 0 {
+		t.Errorf("Union of nothing: got %v", got)
+	}
+}
+
+func TestIsSubset(t *testing.T) {
+	long := make([]int, 20)
+	for i := range long {
+		long[i] = i
+	}
+	for _, test := range []struct {
+		sub, sl []int
+		want    bool
+	}{
+		{nil, nil, true},
+		{nil, []int{1}, true},
+		{[]int{1}, nil, false},
+		{[]int{2, 1, 2}, []int{1, 2, 3}, true},
+		{[]int{1, 4}, []int{1, 2, 3}, false},
+		//	longer `sub`s take the set-based path
+		{long[5:], long, true},
+		{append(long[10:], 99), long, false},
+	} {
+		if got := IsSubset(test.sub, test.sl); got != test.want {
+			t.Errorf("IsSubset(%v, %v): got %v, want %v", test.sub, test.sl, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+package ustr
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//	An Aho-Corasick automaton for finding all occurrences of many needles in a single pass over a text,
+//	taking time linear in the length of the text (plus the number of matches) regardless of the number of needles.
+//
+//	`FindAll`, `Find`, `Replace` and `FindReader` use leftmost-longest semantics: of all overlapping matches, the one
+//	starting first wins and, of those starting at the same position, the longest. `FindAllOverlapping` reports all.
+//
+//	An `AhoCorasick` is immutable once created, and so safe for concurrent use.
+type AhoCorasick struct {
+	needles         []string
+	caseInsensitive bool
+	classes         [256]uint8
+	numClasses      int
+	trans           []int32
+	depths          []int32
+	own, longest    []int32
+	dict            []int32
+}
+
+//	A match reported by an `AhoCorasick`.
+type AhoCorasickMatch struct {
+	//	The index of the matched needle, as passed to `NewAhoCorasick`.
+	Needle int
+
+	//	The byte offsets of the match in the text: `text[Start:End]`.
+	Start, End int
+}
+
+//	Returns an `AhoCorasick` for the specified `needles` (empty ones are ignored, and of duplicates only the first
+//	is reported). If `caseInsensitive`, letters match regardless of case (as per Unicode simple case folding,
+//	except that runes whose case variants differ in UTF-8 length, such as the Kelvin sign, only match themselves).
+func NewAhoCorasick(needles []string, caseInsensitive bool) (me *AhoCorasick) {
+	me = &AhoCorasick{needles: needles, caseInsensitive: caseInsensitive}
+	folded := needles
+	if caseInsensitive {
+		folded = make([]string, len(needles))
+		for i, needle := range needles {
+			folded[i] = ahoCorasickFold(needle)
+		}
+	}
+
+	//	alphabet compression: all bytes not occurring in any needle share one class
+	var used [256]bool
+	for _, needle := range folded {
+		for i := 0; i < len(needle); i++ {
+			used[needle[i]] = true
+		}
+	}
+	me.numClasses = 1
+	for b := range used {
+		if used[b] {
+			me.classes[b] = uint8(me.numClasses)
+			me.numClasses++
+		}
+	}
+	if me.numClasses > 256 {
+		//	every byte value occurs: class `0` is then just another byte
+		for b := range me.classes {
+			me.classes[b] = uint8(b)
+		}
+		me.numClasses = 256
+	}
+	if caseInsensitive {
+		//	needles are folded to upper-case, so the text's lower-case ASCII letters need no folding of their own
+		for b := 'a'; b <= 'z'; b++ {
+			me.classes[b] = me.classes[b-'a'+'A']
+		}
+	}
+
+	//	the trie, with `-1` for missing transitions
+	C := me.numClasses
+	me.trans, me.depths, me.own = make([]int32, C), []int32{0}, []int32{-1}
+	for j := range me.trans {
+		me.trans[j] = -1
+	}
+	for i, needle := range folded {
+		state := int32(0)
+		for k := 0; k < len(needle); k++ {
+			c := int32(me.classes[needle[k]])
+			if next := me.trans[state*int32(C)+c]; next >= 0 {
+				state = next
+			} else {
+				next = int32(len(me.depths))
+				me.trans[state*int32(C)+c] = next
+				for j := 0; j < C; j++ {
+					me.trans = append(me.trans, -1)
+				}
+				me.depths, me.own, state = append(me.depths, me.depths[state]+1), append(me.own, -1), next
+			}
+		}
+		if len(needle) > 0 && me.own[state] < 0 {
+			me.own[state] = int32(i)
+		}
+	}
+
+	//	breadth-first: resolve failure links into full DFA transitions, and derive the output links
+	numStates := len(me.depths)
+	fail := make([]int32, numStates)
+	me.longest, me.dict = make([]int32, numStates), make([]int32, numStates)
+	me.longest[0], me.dict[0] = -1, -1
+	queue := make([]int32, 0, numStates)
+	for c := 0; c < C; c++ {
+		if next := me.trans[c]; next > 0 {
+			queue = append(queue, next)
+		} else {
+			me.trans[c] = 0
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		f := fail[state]
+		if me.longest[state] = me.own[state]; me.longest[state] < 0 {
+			me.longest[state] = me.longest[f]
+		}
+		if me.dict[state] = f; f > 0 && me.own[f] < 0 {
+			me.dict[state] = me.dict[f]
+		} else if f == 0 {
+			me.dict[state] = -1
+		}
+		for c := int32(0); c < int32(C); c++ {
+			if next := me.trans[state*int32(C)+c]; next >= 0 {
+				fail[next] = me.trans[f*int32(C)+c]
+				queue = append(queue, next)
+			} else {
+				me.trans[state*int32(C)+c] = me.trans[f*int32(C)+c]
+			}
+		}
+	}
+	return
+}
+
+//	Returns the needles passed to `NewAhoCorasick`.
+func (me *AhoCorasick) Needles() []string {
+	return me.needles
+}
+
+//	Returns whether any needle occurs in `s`.
+func (me *AhoCorasick) IsMatch(s string) (isMatch bool) {
+	me.scanOverlapping(s, func(AhoCorasickMatch) bool {
+		isMatch = true
+		return false
+	})
+	return
+}
+
+//	Returns the leftmost-longest match in `s`, if any.
+func (me *AhoCorasick) Find(s string) (match AhoCorasickMatch, ok bool) {
+	acScanText(me, &acScan{}, s, 0, 0, true, func(m AhoCorasickMatch) bool {
+		match, ok = m, true
+		return false
+	})
+	return
+}
+
+//	Returns all non-overlapping leftmost-longest matches in `s`, in order.
+func (me *AhoCorasick) FindAll(s string) (matches []AhoCorasickMatch) {
+	acScanText(me, &acScan{}, s, 0, 0, true, func(m AhoCorasickMatch) bool {
+		matches = append(matches, m)
+		return true
+	})
+	return
+}
+
+//	Returns all matches in `s`, including overlapping ones, ordered by their `End` (and, for equal ones, longest first).
+func (me *AhoCorasick) FindAllOverlapping(s string) (matches []AhoCorasickMatch) {
+	me.scanOverlapping(s, func(m AhoCorasickMatch) bool {
+		matches = append(matches, m)
+		return true
+	})
+	return
+}
+
+//	Reports all non-overlapping leftmost-longest matches in the stream `r` to `onMatch` (with offsets relative to
+//	the start of the stream) until `onMatch` returns `false` or `r` is exhausted. Memory use is bounded by the buffer
+//	size and the length of the longest needle, regardless of the length of the stream. Returns any non-`io.EOF` error of `r`.
+func (me *AhoCorasick) FindReader(r io.Reader, onMatch func(AhoCorasickMatch) bool) (err error) {
+	var (
+		sc      acScan
+		window  = make([]byte, 0, 64*1024)
+		base, i int
+		stopped bool
+		n       int
+	)
+	for err == nil && !stopped {
+		if len(window) == cap(window) {
+			window = append(window, 0)[:len(window)]
+		}
+		n, err = r.Read(window[len(window):cap(window)])
+		window = window[:len(window)+n]
+		if i, stopped = acScanText(me, &sc, window, base, i, err != nil, onMatch); !stopped {
+			//	only bytes from which a rescan may yet be needed are kept
+			keep := i
+			if sc.hasCand {
+				keep = sc.cand.End - base
+			}
+			base, i = base+keep, i-keep
+			window = window[:copy(window, window[keep:])]
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return
+}
+
+//	Replaces all non-overlapping leftmost-longest matches in `s` with the `replacements` element at the index of the matched needle.
+func (me *AhoCorasick) Replace(s string, replacements []string) string {
+	var buf strings.Builder
+	last := 0
+	acScanText(me, &acScan{}, s, 0, 0, true, func(m AhoCorasickMatch) bool {
+		if buf.Len() == 0 {
+			buf.Grow(len(s))
+		}
+		buf.WriteString(s[last:m.Start])
+		buf.WriteString(replacements[m.Needle])
+		last = m.End
+		return true
+	})
+	if last == 0 {
+		return s
+	}
+	buf.WriteString(s[last:])
+	return buf.String()
+}
+
+
+//	Reports every match in `s`, including overlapping ones, until `onMatch` returns `false`.
+func (me *AhoCorasick) scanOverlapping(s string, onMatch func(AhoCorasickMatch) bool) {
+	for i, state := 0, int32(0); i < len(s); {
+		var size int
+		state, size = acStep(me, state, s, i, true)
+		i += size
+		for out := state; out >= 0; out = me.dict[out] {
+			if me.own[out] < 0 {
+				continue
+			}
+			needle := int(me.own[out])
+			if !onMatch(AhoCorasickMatch{Needle: needle, Start: i - len(me.needles[needle]), End: i}) {
+				return
+			}
+		}
+	}
+}
+
+//	The state of a leftmost-longest scan by `acScanText`, with `cand` being the best match found so far
+//	that may yet be superseded by a longer one starting at the same position (or one starting earlier).
+type acScan struct {
+	state   int32
+	cand    AhoCorasickMatch
+	hasCand bool
+}
+
+//	Continues the leftmost-longest scan `sc` at `text[i:]`, with `base` being the offset of `text[0]` in the whole
+//	text, reporting all matches to `onMatch` as soon as they can no longer be superseded. Returns the index in `text`
+//	at which to continue with more text and whether `onMatch` returned `false`. If not `final`, `text` may be
+//	continued later on, so any bytes from the returned index onwards must then be passed again.
+func acScanText[T string | []byte](me *AhoCorasick, sc *acScan, text T, base int, i int, final bool, onMatch func(AhoCorasickMatch) bool) (next int, stopped bool) {
+	for {
+		for i < len(text) {
+			state, size := acStep(me, sc.state, text, i, final)
+			if size == 0 {
+				return i, false
+			}
+			sc.state, i = state, i+size
+			end := base + i
+			if out := me.longest[sc.state]; out >= 0 {
+				if start := end - len(me.needles[out]); !sc.hasCand || start < sc.cand.Start || (start == sc.cand.Start && end > sc.cand.End) {
+					sc.cand, sc.hasCand = AhoCorasickMatch{Needle: int(out), Start: start, End: end}, true
+				}
+			}
+			//	any later match must start within the text currently represented by the state
+			if sc.hasCand && end-int(me.depths[sc.state]) > sc.cand.Start {
+				if i, stopped = sc.commit(base, onMatch); stopped {
+					return
+				}
+			}
+		}
+		if !(final && sc.hasCand) {
+			return i, false
+		} else if i, stopped = sc.commit(base, onMatch); stopped {
+			return
+		}
+	}
+}
+
+//	Reports `sc.cand` and restarts the scan right after it, returning the index (relative to `base`) to restart at.
+func (sc *acScan) commit(base int, onMatch func(AhoCorasickMatch) bool) (next int, stopped bool) {
+	cand := sc.cand
+	sc.state, sc.hasCand = 0, false
+	return cand.End - base, !onMatch(cand)
+}
+
+//	Feeds the rune (or, if case-sensitive, the byte) at `text[i]` into the automaton, returning the new state and the
+//	number of bytes consumed. Returns a `size` of `0` if `text` ends in the middle of a rune but is not `final`.
+func acStep[T string | []byte](me *AhoCorasick, state int32, text T, i int, final bool) (next int32, size int) {
+	C := int32(me.numClasses)
+	if b := text[i]; !me.caseInsensitive || b < utf8.RuneSelf {
+		return me.trans[state*C+int32(me.classes[b])], 1
+	}
+	var buf [utf8.UTFMax]byte
+	for size < len(buf) && i+size < len(text) {
+		buf[size] = text[i+size]
+		size++
+	}
+	if !(final || utf8.FullRune(buf[:size])) {
+		return state, 0
+	}
+	r, size := utf8.DecodeRune(buf[:size])
+	if f := ahoCorasickFoldRune(r); f != r {
+		utf8.EncodeRune(buf[:], f)
+	}
+	for _, b := range buf[:size] {
+		state = me.trans[state*C+int32(me.classes[b])]
+	}
+	return state, size
+}
+
+//	Returns the case-folded `s`, which is of the same length as `s` (see `ahoCorasickFoldRune`).
+func ahoCorasickFold(s string) string {
+	var buf strings.Builder
+	buf.Grow(len(s))
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if f := ahoCorasickFoldRune(r); f != r {
+			buf.WriteRune(f)
+		} else {
+			buf.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	return buf.String()
+}
+
+//	Returns the smallest rune that is equivalent to `r` under Unicode simple case folding and has the same UTF-8 length.
+func ahoCorasickFoldRune(r rune) (folded rune) {
+	folded = r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < folded && utf8.RuneLen(f) == utf8.RuneLen(r) {
+			folded = f
+		}
+	}
+	return
+}
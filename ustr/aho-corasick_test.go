@@ -0,0 +1,126 @@
+package ustr
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+//	Returns all matches of `needles` in `s` by brute force, as `FindAllOverlapping` should.
+func testAcOverlapping(needles []string, s string) (matches []AhoCorasickMatch) {
+	for end := 1; end <= len(s); end++ {
+		for start := 0; start < end; start++ {
+			for i, needle := range needles {
+				if s[start:end] == needle {
+					matches = append(matches, AhoCorasickMatch{Needle: i, Start: start, End: end})
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+//	Returns the non-overlapping leftmost-longest matches of `needles` in `s` by brute force, as `FindAll` should.
+func testAcLeftmostLongest(needles []string, s string) (matches []AhoCorasickMatch) {
+	for start := 0; start < len(s); {
+		best := AhoCorasickMatch{Needle: -1}
+		for i, needle := range needles {
+			if needle != "" && strings.HasPrefix(s[start:], needle) && (best.Needle < 0 || len(needle) > best.End-best.Start) {
+				best = AhoCorasickMatch{Needle: i, Start: start, End: start + len(needle)}
+			}
+		}
+		if best.Needle < 0 {
+			start++
+		} else {
+			matches, start = append(matches, best), best.End
+		}
+	}
+	return
+}
+
+func TestAhoCorasickRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randStr := func(maxLen int) string {
+		b := make([]byte, rnd.Intn(maxLen+1))
+		for i := range b {
+			b[i] = "abc"[rnd.Intn(3)]
+		}
+		return string(b)
+	}
+	for n := 0; n < 500; n++ {
+		needles := make([]string, 1+rnd.Intn(6))
+		for i := range needles {
+			needles[i] = randStr(4)
+		}
+		s := randStr(30)
+		ac := NewAhoCorasick(needles, false)
+		if got, want := fmt.Sprint(ac.FindAllOverlapping(s)), fmt.Sprint(testAcOverlapping(needles, s)); got != want {
+			t.Fatalf("FindAllOverlapping(%q) for %q: got %s, want %s", s, needles, got, want)
+		}
+		want := testAcLeftmostLongest(needles, s)
+		if got := ac.FindAll(s); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("FindAll(%q) for %q: got %v, want %v", s, needles, got, want)
+		}
+		var got []AhoCorasickMatch
+		if err := ac.FindReader(iotest.OneByteReader(strings.NewReader(s)), func(m AhoCorasickMatch) bool {
+			got = append(got, m)
+			return true
+		}); err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("FindReader(%q) for %q: got %v (%v), want %v", s, needles, got, err, want)
+		}
+		if first, ok := ac.Find(s); ok != (len(want) > 0) || (ok && first != want[0]) {
+			t.Fatalf("Find(%q) for %q: got %v, want %v", s, needles, first, want)
+		}
+		if ac.IsMatch(s) != (len(want) > 0) {
+			t.Fatalf("IsMatch(%q) for %q: got %v", s, needles, !(len(want) > 0))
+		}
+	}
+}
+
+func TestAhoCorasickCaseInsensitive(t *testing.T) {
+	ac := NewAhoCorasick([]string{"straße", "ÄRGER", "go"}, true)
+	for _, test := range []struct {
+		s    string
+		want string
+	}{
+		{"STRASSE Straße STRAßE", "[{0 8 15} {0 16 23}]"},
+		{"ärger Ärger", "[{1 0 6} {1 7 13}]"},
+		{"GoGO gO", "[{2 0 2} {2 2 4} {2 5 7}]"},
+		{"K go", "[{2 4 6}]"},
+	} {
+		if got := fmt.Sprint(ac.FindAll(test.s)); got != test.want {
+			t.Errorf("FindAll(%q): got %s, want %s", test.s, got, test.want)
+		}
+	}
+}
+
+func TestAhoCorasickReplace(t *testing.T) {
+	ac := NewAhoCorasick([]string{"he", "she", "hers", ""}, false)
+	if got := ac.Replace("ushers she", []string{"1", "2", "3", "4"}); got != "u2rs 2" {
+		t.Errorf("got %q", got)
+	}
+	if got := Replace("a < b && c > d", map[string]string{"<": "&lt;", ">": "&gt;", "&": "&amp;"}); got != "a &lt; b &amp;&amp; c &gt; d" {
+		t.Errorf("Replace: got %q", got)
+	}
+}
+
+func TestAhoCorasickFindReaderAcrossWindows(t *testing.T) {
+	ac := NewAhoCorasick([]string{"needle", "needles"}, false)
+	var text strings.Builder
+	var want []AhoCorasickMatch
+	for _, gap := range []int{64*1024 - 3, 64*1024 - 6, 100000, 1} {
+		text.WriteString(strings.Repeat("x", gap))
+		want = append(want, AhoCorasickMatch{Needle: 1, Start: text.Len(), End: text.Len() + 7})
+		text.WriteString("needles")
+	}
+	var got []AhoCorasickMatch
+	if err := ac.FindReader(strings.NewReader(text.String()), func(m AhoCorasickMatch) bool {
+		got = append(got, m)
+		return true
+	}); err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v (%v), want %v", got, err, want)
+	}
+}
@@ -3,6 +3,7 @@ package ustr
 import (
 	"bytes"
 	"fmt"
+	"strings"
 )
 
 //	A convenient wrapper for `bytes.Buffer`.
@@ -23,3 +24,70 @@ func (me *Buffer) Writeln(format string, args ...interface{}) {
 	me.Write(format, args...)
 	me.Buffer.WriteString("\n")
 }
+
+//	Writes `s` padded to the specified display `width` as per `Align`.
+func (me *Buffer) WriteAligned(s string, width int, align Alignment) {
+	me.Buffer.WriteString(Align(s, width, align))
+}
+
+//	Writes the lines returned by `Wrap`, each followed by a line break.
+func (me *Buffer) WriteWrapped(s string, width int, firstIndent string, indent string) {
+	for _, line := range Wrap(s, width, firstIndent, indent) {
+		me.Buffer.WriteString(line)
+		me.Buffer.WriteByte('\n')
+	}
+}
+
+//	Writes all rows of `table` (preceded by its headers, if any), each line followed by a line break.
+//	Cells spanning multiple lines are top-aligned, and no trailing spaces are written.
+func (me *Buffer) WriteTable(table *Table) {
+	sep := table.Sep
+	if sep == "" {
+		sep = "  "
+	}
+	rows, widths := table.layout()
+	for r, row := range rows {
+		numLines := 1
+		for _, lines := range row {
+			if len(lines) > numLines {
+				numLines = len(lines)
+			}
+		}
+		for l := 0; l < numLines; l++ {
+			var line strings.Builder
+			for c, lines := range row {
+				var cell string
+				if l < len(lines) {
+					cell = lines[l]
+				}
+				if c > 0 {
+					line.WriteString(sep)
+				}
+				align := AlignLeft
+				if c < len(table.Columns) {
+					align = table.Columns[c].Align
+				}
+				line.WriteString(Align(cell, widths[c], align))
+			}
+			me.Buffer.WriteString(strings.TrimRight(line.String(), " "))
+			me.Buffer.WriteByte('\n')
+		}
+		if r == 0 && table.HeaderLine != 0 && len(rows) > len(table.Rows) {
+			runeWidth := RuneWidth(table.HeaderLine)
+			if runeWidth < 1 {
+				runeWidth = 1
+			}
+			var line strings.Builder
+			for c, width := range widths {
+				if c > 0 {
+					line.WriteString(sep)
+				}
+				//	wide runes (such as CJK ones or emoji) may not fill a column exactly
+				line.WriteString(strings.Repeat(string(table.HeaderLine), width/runeWidth))
+				line.WriteString(strings.Repeat(" ", width%runeWidth))
+			}
+			me.Buffer.WriteString(strings.TrimRight(line.String(), " "))
+			me.Buffer.WriteByte('\n')
+		}
+	}
+}
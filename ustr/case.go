@@ -0,0 +1,203 @@
+package ustr
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//	Upper-cased words that `ToGoName` keeps (or makes) fully upper-case, as per the Go naming conventions
+//	(eg. "user_id" -> "UserID" rather than "UserId"). May be modified to add (or remove) initialisms.
+var GoInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DB": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true, "LHS": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true,
+	"TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "URI": true, "URL": true,
+	"UTF8": true, "UUID": true, "VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+type wordRuneKind uint8
+
+const (
+	wordRuneSep wordRuneKind = iota
+	wordRuneUpper
+	wordRuneLower
+	wordRuneCaseless
+	wordRuneDigit
+)
+
+//	A word found by `splitWords`, where `glued` indicates a digits-only word directly following letters (as in "utf8").
+type word struct {
+	text  string
+	glued bool
+}
+
+//	Splits the identifier-like `s` into its words: at any runes other than letters and digits (such as `_`, `-`,
+//	`.` or spaces), at lower-to-upper case changes ("fooBar" -> "foo", "Bar"), before the last upper-case letter of an
+//	acronym followed by lower-case ones ("HTTPServer" -> "HTTP", "Server", but "URLsFor" -> "URLs", "For"), and around digits
+//	("utf8Reader" -> "utf", "8", "Reader").
+//
+//	Unicode letters without case (such as CJK ideographs) form words of their own, and combining marks stay with the preceding rune.
+func Words(s string) (words []string) {
+	for _, w := range splitWords(s) {
+		words = append(words, w.text)
+	}
+	return
+}
+
+func splitWords(s string) (words []word) {
+	start, prev, prevPos, lastEnd := -1, wordRuneSep, 0, -1
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			isDigits := wordRuneKindOf(FirstRune(s[start:end])) == wordRuneDigit
+			words = append(words, word{text: s[start:end], glued: isDigits && start == lastEnd})
+			if lastEnd = end; isDigits {
+				lastEnd = -1
+			}
+		}
+		start = -1
+	}
+	for pos, r := range s {
+		kind := wordRuneKindOf(r)
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) {
+			//	combining marks belong to whatever rune precedes them
+			continue
+		}
+		switch {
+		case kind == wordRuneSep:
+			flush(pos)
+		case start < 0:
+			start = pos
+		case kind == prev && kind != wordRuneUpper:
+		case kind == wordRuneUpper && prev == wordRuneUpper:
+		case kind == wordRuneLower && prev == wordRuneUpper:
+			//	"HTTPServer": the upper-case rune before the first lower-case one starts the new word, unless it's the only one
+			//	so far or it's a plural "s" ending the acronym
+			if isPlural := r == 's' && wordRuneKindOf(FirstRune(s[pos+1:])) != wordRuneLower; prevPos > start && !isPlural {
+				flush(prevPos)
+				start = prevPos
+			}
+		default:
+			flush(pos)
+			start = pos
+		}
+		prev, prevPos = kind, pos
+	}
+	flush(len(s))
+	return
+}
+
+func wordRuneKindOf(r rune) wordRuneKind {
+	switch {
+	case unicode.IsUpper(r) || unicode.IsTitle(r):
+		return wordRuneUpper
+	case unicode.IsLower(r):
+		return wordRuneLower
+	case unicode.IsLetter(r):
+		return wordRuneCaseless
+	case unicode.IsDigit(r) || unicode.IsNumber(r):
+		return wordRuneDigit
+	}
+	return wordRuneSep
+}
+
+//	Returns `s` in "camelCase", as per `Words`: "HTTP_server_id" -> "httpServerId".
+//	Digits directly following letters in `s` stay attached to them ("utf8_reader" -> "utf8Reader").
+func ToCamelCase(s string) string {
+	return joinWords(splitWords(s), "", func(i int, w string) string {
+		if i == 0 {
+			return strings.ToLower(w)
+		}
+		return toTitleWord(w)
+	})
+}
+
+//	Returns `s` in "PascalCase", as per `Words`: "HTTP_server_id" -> "HttpServerId".
+//	Digits directly following letters in `s` stay attached to them ("utf8_reader" -> "Utf8Reader").
+func ToPascalCase(s string) string {
+	return joinWords(splitWords(s), "", func(_ int, w string) string {
+		return toTitleWord(w)
+	})
+}
+
+//	Returns `s` in "snake_case", as per `Words`: "HTTPServerID" -> "http_server_id".
+//	Digits directly following letters in `s` stay attached to them ("utf8Reader" -> "utf8_reader").
+func ToSnakeCase(s string) string {
+	return joinWords(splitWords(s), "_", func(_ int, w string) string {
+		return strings.ToLower(w)
+	})
+}
+
+//	Returns `s` in "SCREAMING_SNAKE_CASE", as per `Words`: "httpServerId" -> "HTTP_SERVER_ID".
+func ToScreamingSnakeCase(s string) string {
+	return joinWords(splitWords(s), "_", func(_ int, w string) string {
+		return strings.ToUpper(w)
+	})
+}
+
+//	Returns `s` in "kebab-case", as per `Words`: "HTTPServerID" -> "http-server-id".
+func ToKebabCase(s string) string {
+	return joinWords(splitWords(s), "-", func(_ int, w string) string {
+		return strings.ToLower(w)
+	})
+}
+
+//	Returns `s` as space-separated "Title Case" words, as per `Words`: "httpServer_id" -> "Http Server Id".
+func ToTitleWords(s string) string {
+	return joinWords(splitWords(s), " ", func(_ int, w string) string {
+		return toTitleWord(w)
+	})
+}
+
+//	Returns a Go identifier for `s`, as per `Words` but following the Go naming conventions: `GoInitialisms` are
+//	upper-cased ("user_id" -> "UserID", "user_ids" -> "UserIDs") except at the start of an unexported name
+//	("id_map" -> "idMap"). The result is prefixed with `_` if it would start with a digit (or be empty),
+//	and suffixed with `_` if it would be a Go keyword ("type" -> "type_").
+func ToGoName(s string, exported bool) (name string) {
+	words := splitWords(s)
+	name = joinWords(words, "", func(i int, w string) string {
+		if upper := strings.ToUpper(w); GoInitialisms[upper] || GoInitialisms[strings.TrimRightFunc(upper, unicode.IsDigit)] {
+			if i == 0 && !exported {
+				return strings.ToLower(w)
+			}
+			return upper
+		} else if plural := strings.TrimSuffix(w, "s"); plural != w && GoInitialisms[strings.ToUpper(plural)] && !(i == 0 && !exported) {
+			return strings.ToUpper(plural) + "s"
+		} else if i == 0 && !exported {
+			return strings.ToLower(w)
+		}
+		return toTitleWord(w)
+	})
+	if r, _ := utf8.DecodeRuneInString(name); name == "" || !(unicode.IsLetter(r) || r == '_') {
+		name = "_" + name
+	} else if token.IsKeyword(name) {
+		name += "_"
+	}
+	return
+}
+
+//	Joins the `words` by `sep` after transforming each via `casing`, with glued digits first attached to the preceding word.
+func joinWords(words []word, sep string, casing func(int, string) string) string {
+	var buf strings.Builder
+	for i, n := 0, 0; i < len(words); n++ {
+		w := words[i].text
+		for i++; i < len(words) && words[i].glued; i++ {
+			w += words[i].text
+		}
+		if n > 0 {
+			buf.WriteString(sep)
+		}
+		buf.WriteString(casing(n, w))
+	}
+	return buf.String()
+}
+
+//	Upper-cases (or, where Unicode defines one, title-cases) the first rune of `w` and lower-cases all others.
+func toTitleWord(w string) string {
+	r, size := utf8.DecodeRuneInString(w)
+	if size == 0 {
+		return w
+	}
+	return string(unicode.ToTitle(r)) + strings.ToLower(w[size:])
+}
@@ -0,0 +1,72 @@
+package ustr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	for s, want := range map[string]string{
+		"fooBar":          "foo Bar",
+		"HTTPServer":      "HTTP Server",
+		"URLsFor":         "URLs For",
+		"utf8Reader":      "utf 8 Reader",
+		"HTTP_server-id":  "HTTP server id",
+		"  spaced  out ":  "spaced out",
+		"A":               "A",
+		"ABC":             "ABC",
+		"Ab":              "Ab",
+		"x2y":             "x 2 y",
+		"日本語Text":         "日本語 Text",
+		"caféMenu":       "café Menu",
+		"ÄrgerÜberÖl":     "Ärger Über Öl",
+		"":                "",
+		"__":              "",
+		"getHTTPResponse": "get HTTP Response",
+	} {
+		if got := strings.Join(Words(s), " "); got != want {
+			t.Errorf("Words(%q): got %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestCaseConversions(t *testing.T) {
+	for _, test := range []struct {
+		s                                             string
+		camel, pascal, snake, screaming, kebab, title string
+	}{
+		{"HTTP_server_id", "httpServerId", "HttpServerId", "http_server_id", "HTTP_SERVER_ID", "http-server-id", "Http Server Id"},
+		{"utf8_reader", "utf8Reader", "Utf8Reader", "utf8_reader", "UTF8_READER", "utf8-reader", "Utf8 Reader"},
+		{"HTTPServerID", "httpServerId", "HttpServerId", "http_server_id", "HTTP_SERVER_ID", "http-server-id", "Http Server Id"},
+		{"version 2 beta", "version2Beta", "Version2Beta", "version_2_beta", "VERSION_2_BETA", "version-2-beta", "Version 2 Beta"},
+	} {
+		for i, got := range []string{ToCamelCase(test.s), ToPascalCase(test.s), ToSnakeCase(test.s), ToScreamingSnakeCase(test.s), ToKebabCase(test.s), ToTitleWords(test.s)} {
+			if want := []string{test.camel, test.pascal, test.snake, test.screaming, test.kebab, test.title}[i]; got != want {
+				t.Errorf("conversion #%d of %q: got %q, want %q", i, test.s, got, want)
+			}
+		}
+	}
+}
+
+func TestToGoName(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		exported bool
+		want     string
+	}{
+		{"user_id", true, "UserID"},
+		{"user_ids", true, "UserIDs"},
+		{"id_map", false, "idMap"},
+		{"id_map", true, "IDMap"},
+		{"http_server", false, "httpServer"},
+		{"utf8_string", true, "UTF8String"},
+		{"type", false, "type_"},
+		{"2fa_code", true, "_2FaCode"},
+		{"", true, "_"},
+		{"--", false, "_"},
+	} {
+		if got := ToGoName(test.s, test.exported); got != test.want {
+			t.Errorf("ToGoName(%q, %v): got %q, want %q", test.s, test.exported, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,527 @@
+package ustr
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//	The algorithm used by `DiffLines` and `DiffRunes`.
+type DiffAlgorithm int
+
+const (
+	//	Myers' O((N+M)D) algorithm (in linear space), which finds a minimal edit script.
+	DiffMyers DiffAlgorithm = iota
+
+	//	Patience diff, which first matches up items occurring exactly once on both sides (such as distinctive lines
+	//	of code) and then diffs the gaps in between (via Myers). Often yields more readable diffs of source code,
+	//	at the cost of them not always being minimal.
+	DiffPatience
+)
+
+//	The kind of a `DiffSpan`, `DiffLine` or `DiffChunk`.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+//	A run of items (lines or runes) that are equal in, deleted from or inserted into the new sequence B compared to
+//	the old sequence A: `A[AStart:AEnd]` (empty for `DiffInsert`) and `B[BStart:BEnd]` (empty for `DiffDelete`).
+type DiffSpan struct {
+	Op           DiffOp
+	AStart, AEnd int
+	BStart, BEnd int
+}
+
+//	A `DiffSpan` of runes, as returned by `DiffRunes`.
+type DiffChunk struct {
+	Op   DiffOp
+	Text string
+}
+
+//	The result of `DiffLines`.
+type LineDiff struct {
+	//	The lines of the old and the new text, as per `SplitLines`.
+	A, B []string
+
+	//	All runs of equal, deleted and inserted lines, in order. Of adjacent deletions and insertions, deletions come first.
+	Spans []DiffSpan
+}
+
+//	A line of a `DiffHunk`.
+type DiffLine struct {
+	Op DiffOp
+
+	//	The line, including its line break (if it has one).
+	Text string
+}
+
+//	A group of changes along with their surrounding context lines, as in a unified diff.
+type DiffHunk struct {
+	//	The `0`-based index and the number of the old and new lines covered by the hunk.
+	AStart, ALen int
+	BStart, BLen int
+
+	Lines []DiffLine
+}
+
+//	Splits `s` into its lines, each including its line break ("\n" or "\r\n"), except the last one if `s` doesn't end
+//	in a line break. Returns `nil` for an empty `s`.
+func SplitLines(s string) (lines []string) {
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n') + 1
+		if i == 0 {
+			i = len(s)
+		}
+		lines, s = append(lines, s[:i]), s[i:]
+	}
+	return
+}
+
+//	Diffs the lines of the old text `a` against those of the new text `b`.
+func DiffLines(a string, b string, algo DiffAlgorithm) (me *LineDiff) {
+	me = &LineDiff{A: SplitLines(a), B: SplitLines(b)}
+	ids := map[string]int{}
+	tokens := func(lines []string) (toks []int) {
+		toks = make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			toks[i] = id
+		}
+		return
+	}
+	me.Spans = diffTokens(tokens(me.A), tokens(me.B), algo)
+	return
+}
+
+//	Diffs the runes of the old text `a` against those of the new text `b`.
+func DiffRunes(a string, b string, algo DiffAlgorithm) (chunks []DiffChunk) {
+	ra, rb := []rune(a), []rune(b)
+	ta, tb := make([]int, len(ra)), make([]int, len(rb))
+	for i, r := range ra {
+		ta[i] = int(r)
+	}
+	for i, r := range rb {
+		tb[i] = int(r)
+	}
+	for _, span := range diffTokens(ta, tb, algo) {
+		if span.Op == DiffInsert {
+			chunks = append(chunks, DiffChunk{Op: span.Op, Text: string(rb[span.BStart:span.BEnd])})
+		} else {
+			chunks = append(chunks, DiffChunk{Op: span.Op, Text: string(ra[span.AStart:span.AEnd])})
+		}
+	}
+	return
+}
+
+//	Returns whether the old and new texts are equal.
+func (me *LineDiff) IsEqual() bool {
+	for _, span := range me.Spans {
+		if span.Op != DiffEqual {
+			return false
+		}
+	}
+	return true
+}
+
+//	Returns all changes grouped into hunks with up to `context` unchanged lines before and after each change.
+//	Changes separated by no more than twice as many unchanged lines share a hunk.
+func (me *LineDiff) Hunks(context int) (hunks []DiffHunk) {
+	if context < 0 {
+		context = 0
+	}
+	var hunk *DiffHunk
+	for i, span := range me.Spans {
+		if span.Op != DiffEqual {
+			if hunk == nil {
+				hunks = append(hunks, DiffHunk{AStart: span.AStart, BStart: span.BStart})
+				hunk = &hunks[len(hunks)-1]
+				if i > 0 {
+					n := minInt(context, me.Spans[i-1].AEnd-me.Spans[i-1].AStart)
+					hunk.AStart, hunk.BStart = span.AStart-n, span.BStart-n
+					hunk.addLines(DiffEqual, me.A[span.AStart-n:span.AStart])
+				}
+			}
+			if span.Op == DiffDelete {
+				hunk.addLines(DiffDelete, me.A[span.AStart:span.AEnd])
+			} else {
+				hunk.addLines(DiffInsert, me.B[span.BStart:span.BEnd])
+			}
+		} else if hunk != nil {
+			n := span.AEnd - span.AStart
+			if i < len(me.Spans)-1 && n <= 2*context {
+				hunk.addLines(DiffEqual, me.A[span.AStart:span.AEnd])
+			} else {
+				hunk.addLines(DiffEqual, me.A[span.AStart:span.AStart+minInt(n, context)])
+				hunk = nil
+			}
+		}
+	}
+	return
+}
+
+func (me *DiffHunk) addLines(op DiffOp, lines []string) {
+	for _, line := range lines {
+		me.Lines = append(me.Lines, DiffLine{Op: op, Text: line})
+		if op != DiffInsert {
+			me.ALen++
+		}
+		if op != DiffDelete {
+			me.BLen++
+		}
+	}
+}
+
+//	Renders `me` as a unified diff (as per `diff -u`) with `context` lines of context, with `aName` and `bName` for
+//	the "---" and "+++" header lines. Returns `""` if the texts are equal.
+func (me *LineDiff) Unified(aName string, bName string, context int) string {
+	hunks := me.Hunks(context)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString("--- " + aName + "\n+++ " + bName + "\n")
+	for _, hunk := range hunks {
+		buf.WriteString("@@ -" + unifiedRange(hunk.AStart, hunk.ALen) + " +" + unifiedRange(hunk.BStart, hunk.BLen) + " @@\n")
+		for _, line := range hunk.Lines {
+			buf.WriteByte(" -+"[line.Op])
+			buf.WriteString(line.Text)
+			if !strings.HasSuffix(line.Text, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+//	Formats a hunk range the way `diff -u` does: `1`-based, except for empty ranges, and without a length of `1`.
+func unifiedRange(start int, n int) string {
+	if n == 0 {
+		return strconv.Itoa(start) + ",0"
+	} else if n == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(n)
+}
+
+//	Diffs the token sequences `a` and `b`, returning the runs of equal, deleted and inserted tokens.
+func diffTokens(a []int, b []int, algo DiffAlgorithm) []DiffSpan {
+	var d differ
+	if algo == DiffPatience {
+		d.patience(a, b, 0, 0)
+	} else {
+		d.myers(a, b, 0, 0)
+	}
+	return d.finish(len(a), len(b))
+}
+
+//	Collects the edit script of a diff, with `a` and `b` being the tokens processed so far.
+type differ struct {
+	spans   []DiffSpan
+	a, b    int
+	scratch []int
+}
+
+//	Records the tokens up to `a` and `b` as changed (deletions first), followed by `n` equal ones.
+func (me *differ) equal(a int, b int, n int) {
+	if a > me.a {
+		me.add(DiffDelete, me.a, a, me.b, me.b)
+	}
+	if b > me.b {
+		me.add(DiffInsert, a, a, me.b, b)
+	}
+	if n > 0 {
+		me.add(DiffEqual, a, a+n, b, b+n)
+	}
+	me.a, me.b = a+n, b+n
+}
+
+func (me *differ) add(op DiffOp, aStart int, aEnd int, bStart int, bEnd int) {
+	if last := len(me.spans) - 1; last >= 0 && me.spans[last].Op == op {
+		me.spans[last].AEnd, me.spans[last].BEnd = aEnd, bEnd
+	} else if last >= 0 && op == DiffDelete && me.spans[last].Op == DiffInsert {
+		//	keep deletions before insertions, merging with any deletion before that insertion
+		ins := me.spans[last]
+		me.spans = me.spans[:last]
+		me.add(DiffDelete, aStart, aEnd, ins.BStart, ins.BStart)
+		me.add(DiffInsert, aEnd, aEnd, ins.BStart, ins.BEnd)
+	} else {
+		me.spans = append(me.spans, DiffSpan{Op: op, AStart: aStart, AEnd: aEnd, BStart: bStart, BEnd: bEnd})
+	}
+}
+
+func (me *differ) finish(aLen int, bLen int) []DiffSpan {
+	me.equal(aLen, bLen, 0)
+	return me.spans
+}
+
+//	Diffs `a` and `b` (which start at offsets `aOff` and `bOff` of the whole sequences) via Myers' linear-space
+//	divide-and-conquer: find the middle snake of an optimal edit path, then recurse before and after it.
+func (me *differ) myers(a []int, b []int, aOff int, bOff int) {
+	pre := commonPrefix(a, b)
+	if pre > 0 {
+		me.equal(aOff, bOff, pre)
+		a, b, aOff, bOff = a[pre:], b[pre:], aOff+pre, bOff+pre
+	}
+	suf := commonSuffix(a, b)
+	a, b = a[:len(a)-suf], b[:len(b)-suf]
+	if len(a) > 0 && len(b) > 0 {
+		x, y, u, v := me.middleSnake(a, b)
+		me.myers(a[:x], b[:y], aOff, bOff)
+		me.equal(aOff+x, bOff+y, u-x)
+		me.myers(a[u:], b[v:], aOff+u, bOff+v)
+	}
+	if suf > 0 {
+		me.equal(aOff+len(a), bOff+len(b), suf)
+	}
+}
+
+//	Returns the start `(x, y)` and end `(u, v)` of the middle snake of an optimal edit path from `a` to `b`
+//	(both non-empty and without a common prefix or suffix).
+func (me *differ) middleSnake(a []int, b []int) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	max := (n + m + 1) / 2
+	delta, size := n-m, 2*max+3
+	if cap(me.scratch) < 2*size {
+		me.scratch = make([]int, 2*size)
+	}
+	vf, vb := me.scratch[:size], me.scratch[size:2*size]
+	for i := range vf {
+		vf[i], vb[i] = 0, 0
+	}
+	off, odd := max+1, delta%2 != 0
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u, v = u+1, v+1
+			}
+			vf[off+k] = u
+			if kb := delta - k; odd && kb >= -(d-1) && kb <= d-1 && u+vb[off+kb] >= n {
+				return
+			}
+		}
+		for kb := -d; kb <= d; kb += 2 {
+			var xb int
+			if kb == -d || (kb != d && vb[off+kb-1] < vb[off+kb+1]) {
+				xb = vb[off+kb+1]
+			} else {
+				xb = vb[off+kb-1] + 1
+			}
+			yb := xb - kb
+			ub, wb := xb, yb
+			for ub < n && wb < m && a[n-1-ub] == b[m-1-wb] {
+				ub, wb = ub+1, wb+1
+			}
+			vb[off+kb] = ub
+			if k := delta - kb; !odd && k >= -d && k <= d && vf[off+k]+ub >= n {
+				return n - ub, m - wb, n - xb, m - yb
+			}
+		}
+	}
+	//	unreachable for non-empty `a` and `b`
+	return 0, 0, 0, 0
+}
+
+//	Diffs `a` and `b` (which start at offsets `aOff` and `bOff` of the whole sequences) by matching up the
+//	longest increasing sequence of tokens unique to both, and recursing between those (or via `myers` if none).
+func (me *differ) patience(a []int, b []int, aOff int, bOff int) {
+	pre := commonPrefix(a, b)
+	if pre > 0 {
+		me.equal(aOff, bOff, pre)
+		a, b, aOff, bOff = a[pre:], b[pre:], aOff+pre, bOff+pre
+	}
+	suf := commonSuffix(a, b)
+	a, b = a[:len(a)-suf], b[:len(b)-suf]
+	if anchors := patienceAnchors(a, b); len(anchors) == 0 {
+		me.myers(a, b, aOff, bOff)
+	} else {
+		x, y := 0, 0
+		for _, anchor := range anchors {
+			me.patience(a[x:anchor[0]], b[y:anchor[1]], aOff+x, bOff+y)
+			me.equal(aOff+anchor[0], bOff+anchor[1], 1)
+			x, y = anchor[0]+1, anchor[1]+1
+		}
+		me.patience(a[x:], b[y:], aOff+x, bOff+y)
+	}
+	if suf > 0 {
+		me.equal(aOff+len(a), bOff+len(b), suf)
+	}
+}
+
+//	Returns the index pairs of the longest sequence of tokens occurring exactly once in both `a` and `b`
+//	that appear in the same order in both.
+func patienceAnchors(a []int, b []int) (anchors [][2]int) {
+	type occurrence struct{ countA, countB, posA, posB int }
+	occ := map[int]*occurrence{}
+	for i, tok := range a {
+		o := occ[tok]
+		if o == nil {
+			o = &occurrence{}
+			occ[tok] = o
+		}
+		o.countA, o.posA = o.countA+1, i
+	}
+	for i, tok := range b {
+		if o := occ[tok]; o != nil {
+			o.countB, o.posB = o.countB+1, i
+		}
+	}
+	var uniques [][2]int
+	for _, o := range occ {
+		if o.countA == 1 && o.countB == 1 {
+			uniques = append(uniques, [2]int{o.posA, o.posB})
+		}
+	}
+	if len(uniques) == 0 {
+		return nil
+	}
+	sort.Slice(uniques, func(i, j int) bool { return uniques[i][0] < uniques[j][0] })
+
+	//	patience sorting: the longest increasing subsequence of the `b` positions
+	var piles []int
+	prev := make([]int, len(uniques))
+	for i, u := range uniques {
+		p := sort.Search(len(piles), func(p int) bool { return uniques[piles[p]][1] > u[1] })
+		if prev[i] = -1; p > 0 {
+			prev[i] = piles[p-1]
+		}
+		if p == len(piles) {
+			piles = append(piles, i)
+		} else {
+			piles[p] = i
+		}
+	}
+	anchors = make([][2]int, len(piles))
+	for i, j := len(piles)-1, piles[len(piles)-1]; i >= 0; i, j = i-1, prev[j] {
+		anchors[i] = uniques[j]
+	}
+	return
+}
+
+func commonPrefix(a []int, b []int) (n int) {
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return
+}
+
+func commonSuffix(a []int, b []int) (n int) {
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return
+}
+
+//	Three-way merges the changes from `base` to `ours` and from `base` to `theirs` (line by line, via `DiffPatience`).
+//	Changes by only one side, or identical ones by both, are taken over. Where both sides changed the same (or
+//	adjacent) lines differently, `merged` contains both versions between conflict markers (as `git merge` does):
+//
+//		<<<<<<< oursLabel
+//		(lines of ours)
+//		=======
+//		(lines of theirs)
+//		>>>>>>> theirsLabel
+//
+//	and `conflicts` is the number of such conflicts.
+func Merge3(base string, ours string, theirs string, oursLabel string, theirsLabel string) (merged string, conflicts int) {
+	baseLines := SplitLines(base)
+	sides := [2]*LineDiff{DiffLines(base, ours, DiffPatience), DiffLines(base, theirs, DiffPatience)}
+	var changes [2][]DiffSpan
+	for s, diff := range sides {
+		changes[s] = diff.changes()
+	}
+	var buf strings.Builder
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			buf.WriteString(line)
+		}
+	}
+	pos, next := 0, [2]int{}
+	for next[0] < len(changes[0]) || next[1] < len(changes[1]) {
+		//	start a group with the earliest change, then add all changes (of either side) that overlap or touch it
+		first := 0
+		if next[0] == len(changes[0]) || (next[1] < len(changes[1]) && changes[1][next[1]].AStart < changes[0][next[0]].AStart) {
+			first = 1
+		}
+		start, end := changes[first][next[first]].AStart, changes[first][next[first]].AEnd
+		from := next
+		for grown := true; grown; {
+			grown = false
+			for s := range changes {
+				for next[s] < len(changes[s]) && changes[s][next[s]].AStart <= end {
+					if changes[s][next[s]].AEnd > end {
+						end = changes[s][next[s]].AEnd
+					}
+					next[s], grown = next[s]+1, true
+				}
+			}
+		}
+		writeLines(baseLines[pos:start])
+		pos = end
+
+		//	each side's version of the base lines from `start` to `end`
+		var versions [2][]string
+		for s := range changes {
+			if next[s] == from[s] {
+				versions[s] = baseLines[start:end]
+			} else {
+				first, last := changes[s][from[s]], changes[s][next[s]-1]
+				bStart, bEnd := first.BStart-(first.AStart-start), last.BEnd+(end-last.AEnd)
+				versions[s] = sides[s].B[bStart:bEnd]
+			}
+		}
+		switch {
+		case next[1] == from[1]:
+			writeLines(versions[0])
+		case next[0] == from[0] || strings.Join(versions[0], "") == strings.Join(versions[1], ""):
+			writeLines(versions[1])
+		default:
+			conflicts++
+			buf.WriteString("<<<<<<< " + oursLabel + "\n")
+			writeConflictLines(&buf, versions[0])
+			buf.WriteString("=======\n")
+			writeConflictLines(&buf, versions[1])
+			buf.WriteString(">>>>>>> " + theirsLabel + "\n")
+		}
+	}
+	writeLines(baseLines[pos:])
+	return buf.String(), conflicts
+}
+
+func writeConflictLines(buf *strings.Builder, lines []string) {
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	if len(lines) > 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		buf.WriteByte('\n')
+	}
+}
+
+//	Returns the changes of `me`, with every adjacent deletion and insertion combined into one `DiffSpan`
+//	(with `Op` of `DiffDelete`, `DiffInsert` or, for replacements, `DiffEqual`).
+func (me *LineDiff) changes() (changes []DiffSpan) {
+	for _, span := range me.Spans {
+		if span.Op == DiffEqual {
+			continue
+		} else if last := len(changes) - 1; last >= 0 && changes[last].AEnd == span.AStart && changes[last].BEnd == span.BStart {
+			changes[last].AEnd, changes[last].BEnd, changes[last].Op = span.AEnd, span.BEnd, DiffEqual
+		} else {
+			changes = append(changes, span)
+		}
+	}
+	return
+}
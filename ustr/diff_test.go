@@ -0,0 +1,171 @@
+package ustr
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+//	Returns the length of the longest common subsequence of `a` and `b`.
+func testLcsLen(a []string, b []string) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else if cur[j] > prev[j+1] {
+				cur[j+1] = cur[j]
+			} else {
+				cur[j+1] = prev[j+1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func testRandLines(rnd *rand.Rand, maxLines int) string {
+	var buf strings.Builder
+	for n := rnd.Intn(maxLines + 1); n > 0; n-- {
+		buf.WriteString("line " + "abcde"[rnd.Intn(5):][:1] + "\n")
+	}
+	if rnd.Intn(4) == 0 {
+		buf.WriteString("no newline")
+	}
+	return buf.String()
+}
+
+func TestDiffLinesRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 300; n++ {
+		a, b := testRandLines(rnd, 12), testRandLines(rnd, 12)
+		for _, algo := range []DiffAlgorithm{DiffMyers, DiffPatience} {
+			diff := DiffLines(a, b, algo)
+			var gotA, gotB strings.Builder
+			numEqual, posA, posB := 0, 0, 0
+			for _, span := range diff.Spans {
+				if span.AStart != posA || span.BStart != posB {
+					t.Fatalf("algo %d: span %+v not contiguous for %q vs %q", algo, span, a, b)
+				}
+				for _, line := range diff.A[span.AStart:span.AEnd] {
+					gotA.WriteString(line)
+				}
+				for _, line := range diff.B[span.BStart:span.BEnd] {
+					gotB.WriteString(line)
+				}
+				if span.Op == DiffEqual {
+					numEqual += span.AEnd - span.AStart
+					if strings.Join(diff.A[span.AStart:span.AEnd], "") != strings.Join(diff.B[span.BStart:span.BEnd], "") {
+						t.Fatalf("algo %d: equal span %+v differs", algo, span)
+					}
+				}
+				posA, posB = span.AEnd, span.BEnd
+			}
+			if gotA.String() != a || gotB.String() != b {
+				t.Fatalf("algo %d: spans don't cover %q vs %q", algo, a, b)
+			}
+			if lcs := testLcsLen(diff.A, diff.B); algo == DiffMyers && numEqual != lcs {
+				t.Fatalf("Myers diff of %q vs %q not minimal: %d equal lines, want %d", a, b, numEqual, lcs)
+			}
+			if diff.IsEqual() != (a == b) {
+				t.Fatalf("IsEqual for %q vs %q: got %v", a, b, diff.IsEqual())
+			}
+			for _, context := range []int{0, 1, 3} {
+				if patched, err := ApplyUnifiedDiff(a, diff.Unified("a", "b", context), 0); err != nil || patched != b {
+					t.Fatalf("ApplyUnifiedDiff(%q) with context %d: got %q (%v), want %q", a, context, patched, err, b)
+				}
+			}
+		}
+	}
+}
+
+func TestDiffRunes(t *testing.T) {
+	for _, algo := range []DiffAlgorithm{DiffMyers, DiffPatience} {
+		chunks := DiffRunes("kätzchen", "kätchen!", algo)
+		var a, b strings.Builder
+		for _, chunk := range chunks {
+			if chunk.Op != DiffInsert {
+				a.WriteString(chunk.Text)
+			}
+			if chunk.Op != DiffDelete {
+				b.WriteString(chunk.Text)
+			}
+		}
+		if a.String() != "kätzchen" || b.String() != "kätchen!" {
+			t.Errorf("algo %d: got %v", algo, chunks)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	diff := DiffLines("a\nb\nc\nd\ne\nf\ng\nh\n", "a\nB\nc\nd\ne\nf\ng\nh", DiffMyers)
+	want := "--- old\n+++ new\n" +
+		"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n" +
+		"@@ -7,2 +7,2 @@\n g\n-h\n+h\n\\ No newline at end of file\n"
+	if got := diff.Unified("old", "new", 1); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := DiffLines("same\n", "same\n", DiffMyers).Unified("a", "b", 3); got != "" {
+		t.Errorf("got %q for equal texts", got)
+	}
+}
+
+func TestApplyUnifiedDiffFuzzAndErrors(t *testing.T) {
+	patch := DiffLines("1\n2\n3\n4\n5\n", "1\n2\nthree\n4\n5\n", DiffMyers).Unified("a", "b", 2)
+	if got, err := ApplyUnifiedDiff("0\n0\n1\n2\n3\n4\n5\n", patch, 0); err != nil || got != "0\n0\n1\n2\nthree\n4\n5\n" {
+		t.Errorf("shifted: got %q (%v)", got, err)
+	}
+	if got, err := ApplyUnifiedDiff("1\r\n2\r\n3\r\n4\r\n5\r\n", patch, 0); err != nil || got != "1\r\n2\r\nthree\n4\r\n5\r\n" {
+		t.Errorf("crlf: got %q (%v)", got, err)
+	}
+	if _, err := ApplyUnifiedDiff("X\n2\n3\n4\nY\n", patch, 0); err == nil {
+		t.Error("changed context: want an error without fuzz")
+	} else if pe, ok := err.(*PatchError); !ok || pe.Hunk != 1 {
+		t.Errorf("got %v, want a *PatchError for hunk 1", err)
+	}
+	if got, err := ApplyUnifiedDiff("X\n2\n3\n4\nY\n", patch, 1); err != nil || got != "X\n2\nthree\n4\nY\n" {
+		t.Errorf("fuzz 1: got %q (%v)", got, err)
+	}
+	if _, err := ApplyUnifiedDiff("text", "not a diff", 0); err == nil {
+		t.Error("want an error for a diff without hunks")
+	}
+}
+
+func TestApplyUnifiedDiffMalformed(t *testing.T) {
+	for _, patch := range []string{
+		"@@ -1,0 +1,0 @@\n\\ x\n",
+		"@@ -1,1 +1,1 @@\n\\ x\n-a\n+b\n",
+		"@@ -1 +1 @@\n-a\n",
+		"@@ -1 +1 @@\n*a\n+b\n",
+		"@@ -x +1 @@\n-a\n+b\n",
+		"@@ -1 @@\n-a\n",
+	} {
+		if _, err := ApplyUnifiedDiff("a\n", patch, 0); err == nil {
+			t.Errorf("%q: want an error", patch)
+		} else if _, ok := err.(*PatchError); !ok {
+			t.Errorf("%q: got %v, want a *PatchError", patch, err)
+		}
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	base := "a\nb\nc\nd\ne\n"
+	for _, test := range []struct {
+		name, ours, theirs string
+		want               string
+		conflicts          int
+	}{
+		{"ours only", "a\nB\nc\nd\ne\n", base, "a\nB\nc\nd\ne\n", 0},
+		{"theirs only", base, "a\nb\nc\nd\nE\n", "a\nb\nc\nd\nE\n", 0},
+		{"both apart", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"both same", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", "a\nX\nc\nd\ne\n", 0},
+		{"conflict", "a\nOURS\nc\nd\ne\n", "a\nTHEIRS\nc\nd\ne\n", "a\n<<<<<<< ours\nOURS\n=======\nTHEIRS\n>>>>>>> theirs\nc\nd\ne\n", 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts := Merge3(base, test.ours, test.theirs, "ours", "theirs")
+			if merged != test.want || conflicts != test.conflicts {
+				t.Errorf("got %q (%d conflicts), want %q (%d)", merged, conflicts, test.want, test.conflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package ustr
+
+import (
+	"container/heap"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//	Returns the Levenshtein distance between `s1` and `s2`: the minimum number of rune insertions, deletions and
+//	substitutions needed to turn one into the other. Allocates only two rows rather than a full matrix.
+func Levenshtein(s1, s2 string) int {
+	r1, r2 := []rune(s1), []rune(s2)
+	prev, cur := make([]int, len(r2)+1), make([]int, len(r2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		cur[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(r2)]
+}
+
+//	Returns the Damerau-Levenshtein distance between `s1` and `s2`: like `Levenshtein`, but swapping two adjacent runes
+//	also counts as a single edit ("teh" -> "the"). This is the "optimal string alignment" variant, which never edits
+//	a substring more than once. Allocates only three rows rather than a full matrix.
+func DamerauLevenshtein(s1, s2 string) int {
+	r1, r2 := []rune(s1), []rune(s2)
+	prevprev, prev, cur := make([]int, len(r2)+1), make([]int, len(r2)+1), make([]int, len(r2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		cur[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && r1[i-1] == r2[j-2] && r1[i-2] == r2[j-1] {
+				cur[j] = minInt(cur[j], prevprev[j-2]+1)
+			}
+		}
+		prevprev, prev, cur = prev, cur, prevprev
+	}
+	return prev[len(r2)]
+}
+
+//	Returns the Jaro similarity of `s1` and `s2`, from `0` (nothing in common) to `1` (equal).
+func Jaro(s1, s2 string) float64 {
+	r1, r2 := []rune(s1), []rune(s2)
+	if len(r1) == 0 && len(r2) == 0 {
+		return 1
+	} else if len(r1) == 0 || len(r2) == 0 {
+		return 0
+	}
+	window := maxInt(len(r1), len(r2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	matched1, matched2 := make([]bool, len(r1)), make([]bool, len(r2))
+	matches := 0
+	for i, r := range r1 {
+		for j := maxInt(0, i-window); j <= minInt(len(r2)-1, i+window); j++ {
+			if !matched2[j] && r2[j] == r {
+				matched1[i], matched2[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+	transpositions := 0
+	for i, j := 0, 0; i < len(r1); i++ {
+		if matched1[i] {
+			for !matched2[j] {
+				j++
+			}
+			if r1[i] != r2[j] {
+				transpositions++
+			}
+			j++
+		}
+	}
+	m := float64(matches)
+	return (m/float64(len(r1)) + m/float64(len(r2)) + (m-float64(transpositions/2))/m) / 3
+}
+
+//	Returns the Jaro-Winkler similarity of `s1` and `s2`, from `0` (nothing in common) to `1` (equal): like `Jaro`, but
+//	favouring strings with a common prefix (of up to 4 runes), which suits short strings such as names and typos.
+func JaroWinkler(s1, s2 string) float64 {
+	sim := Jaro(s1, s2)
+	if sim > 0.7 {
+		r1, r2, prefix := []rune(s1), []rune(s2), 0
+		for prefix < 4 && prefix < len(r1) && prefix < len(r2) && r1[prefix] == r2[prefix] {
+			prefix++
+		}
+		sim += float64(prefix) * 0.1 * (1 - sim)
+	}
+	return sim
+}
+
+//	Returns up to `limit` (or, if `limit` isn't positive, all) of the `candidates` that `s` is most likely a typo of,
+//	for "did you mean ...?" hints: those within a case-insensitive `DamerauLevenshtein` distance of a third of the
+//	length of `s` (but at least 1), closest first and then by `JaroWinkler` similarity.
+func Suggest(s string, candidates []string, limit int) (suggestions []string) {
+	type suggestion struct {
+		s    string
+		dist int
+		sim  float64
+	}
+	var all []suggestion
+	lower := []rune(strings.ToLower(s))
+	maxDist := maxInt(1, len(lower)/3)
+	for _, c := range candidates {
+		lc := strings.ToLower(c)
+		if abs := utf8.RuneCountInString(lc) - len(lower); abs > maxDist || -abs > maxDist {
+			continue
+		}
+		if dist := DamerauLevenshtein(string(lower), lc); dist <= maxDist {
+			all = append(all, suggestion{s: c, dist: dist, sim: JaroWinkler(string(lower), lc)})
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].dist != all[j].dist {
+			return all[i].dist < all[j].dist
+		}
+		return all[i].sim > all[j].sim
+	})
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	for _, sug := range all {
+		suggestions = append(suggestions, sug.s)
+	}
+	return
+}
+
+const (
+	fuzzyScoreMatch             = 16
+	fuzzyScoreGapStart          = -3
+	fuzzyScoreGapExtension      = -1
+	fuzzyBonusBoundary          = fuzzyScoreMatch / 2
+	fuzzyBonusBoundaryWhite     = fuzzyBonusBoundary + 2
+	fuzzyBonusBoundaryDelimiter = fuzzyBonusBoundary + 1
+	fuzzyBonusNonWord           = fuzzyScoreMatch / 2
+	fuzzyBonusCamel123          = fuzzyBonusBoundary + fuzzyScoreGapExtension
+	fuzzyBonusConsecutive       = -(fuzzyScoreGapStart + fuzzyScoreGapExtension)
+	fuzzyBonusFirstCharFactor   = 2
+	fuzzyScoreNone              = -1 << 30
+)
+
+type fuzzyCharClass uint8
+
+const (
+	fuzzyCharWhite fuzzyCharClass = iota
+	fuzzyCharNonWord
+	fuzzyCharDelimiter
+	fuzzyCharLower
+	fuzzyCharUpper
+	fuzzyCharLetter
+	fuzzyCharNumber
+)
+
+//	Scores how well a pattern matches candidate strings as a subsequence, in the manner of the `fzf` fuzzy finder:
+//	all pattern runes must occur in the candidate in order, with matches at word boundaries (after spaces, `/`, `_`,
+//	`.` etc., or at camelCase humps) and runs of consecutive matches scoring higher, and gaps in between scoring lower.
+//
+//	Matching is case-insensitive unless the pattern contains upper-case letters ("smart case").
+//
+//	A `FuzzyMatcher` reuses its internal buffers across calls, so that matching or ranking many candidates
+//	allocates next to nothing. For the same reason, it is not safe for concurrent use.
+type FuzzyMatcher struct {
+	pattern       []rune
+	caseSensitive bool
+	runes, folded []rune
+	offsets       []int
+	bonuses       []int32
+	scores        []int32
+	chunks        []int32
+	traces        []int32
+}
+
+//	A candidate matched by `FuzzyMatcher.Rank`.
+type FuzzyResult struct {
+	//	The index of the candidate in the slice passed to `Rank`.
+	Index int
+
+	Candidate string
+
+	Score int
+
+	//	The byte offsets in `Candidate` of all runes matched by the pattern, such as for highlighting.
+	Positions []int
+}
+
+//	Returns a new `FuzzyMatcher` for the specified `pattern`.
+func NewFuzzyMatcher(pattern string) (me *FuzzyMatcher) {
+	me = &FuzzyMatcher{pattern: []rune(pattern), caseSensitive: !IsLower(pattern)}
+	if !me.caseSensitive {
+		for i, r := range me.pattern {
+			me.pattern[i] = unicode.ToLower(r)
+		}
+	}
+	return
+}
+
+//	Returns whether `candidate` matches and, if so, its score (higher is better).
+func (me *FuzzyMatcher) Match(candidate string) (score int, ok bool) {
+	return me.match(candidate, nil)
+}
+
+//	Like `Match`, but also returns the byte offsets in `candidate` of all runes matched by the pattern.
+func (me *FuzzyMatcher) MatchPositions(candidate string) (score int, positions []int, ok bool) {
+	positions = make([]int, len(me.pattern))
+	if score, ok = me.match(candidate, positions); !ok {
+		positions = nil
+	}
+	return
+}
+
+//	Returns the (at most `limit`, if positive) best-matching `candidates`, best first: by score, then by shortest
+//	candidate, then by order in `candidates`. Holds no more than `limit` results in memory at any time,
+//	and only computes the `FuzzyResult.Positions` of those returned.
+func (me *FuzzyMatcher) Rank(candidates []string, limit int) (results []FuzzyResult) {
+	best := &fuzzyResults{}
+	for i, candidate := range candidates {
+		if score, ok := me.match(candidate, nil); ok {
+			result := FuzzyResult{Index: i, Candidate: candidate, Score: score}
+			if limit <= 0 || best.Len() < limit {
+				heap.Push(best, result)
+			} else if best.less((*best)[0], result) {
+				(*best)[0] = result
+				heap.Fix(best, 0)
+			}
+		}
+	}
+	results = *best
+	sort.Slice(results, func(i, j int) bool { return best.less(results[j], results[i]) })
+	for i := range results {
+		results[i].Positions = make([]int, len(me.pattern))
+		me.match(results[i].Candidate, results[i].Positions)
+	}
+	return
+}
+
+//	Ranks `candidates` by how well they match `pattern`, as per `FuzzyMatcher.Rank`.
+func FuzzyRank(pattern string, candidates []string, limit int) []FuzzyResult {
+	return NewFuzzyMatcher(pattern).Rank(candidates, limit)
+}
+
+//	Scores `candidate` and, if `positions` isn't `nil`, fills it with the byte offsets of the best match.
+func (me *FuzzyMatcher) match(candidate string, positions []int) (score int, ok bool) {
+	m := len(me.pattern)
+	if m == 0 {
+		return 0, true
+	}
+	me.runes, me.folded, me.offsets = me.runes[:0], me.folded[:0], me.offsets[:0]
+	for pos, r := range candidate {
+		me.runes, me.offsets = append(me.runes, r), append(me.offsets, pos)
+		if !me.caseSensitive {
+			r = unicode.ToLower(r)
+		}
+		me.folded = append(me.folded, r)
+	}
+
+	//	narrow down to the window between the earliest possible first match and the latest possible last match
+	first, pi := -1, 0
+	for j := 0; j < len(me.folded) && pi < m; j++ {
+		if me.folded[j] == me.pattern[pi] {
+			if pi == 0 {
+				first = j
+			}
+			pi++
+		}
+	}
+	if pi < m {
+		return 0, false
+	}
+	last := len(me.folded) - 1
+	for me.folded[last] != me.pattern[m-1] {
+		last--
+	}
+	w := last - first + 1
+
+	me.bonuses = growInt32s(me.bonuses, w)
+	prevClass := fuzzyCharWhite
+	if first > 0 {
+		prevClass = fuzzyCharClassOf(me.runes[first-1])
+	}
+	for jj := 0; jj < w; jj++ {
+		class := fuzzyCharClassOf(me.runes[first+jj])
+		me.bonuses[jj], prevClass = fuzzyBonus(prevClass, class), class
+	}
+
+	me.scores, me.chunks, me.traces = growInt32s(me.scores, m*w), growInt32s(me.chunks, m*w), growInt32s(me.traces, m*w)
+	scores, chunks, traces := me.scores, me.chunks, me.traces
+	for i := 0; i < m; i++ {
+		gapBest, gapFrom := int32(fuzzyScoreNone), int32(-1)
+		for jj := 0; jj < w; jj++ {
+			cell := i*w + jj
+			if i > 0 && jj >= 2 {
+				//	the best preceding match (for the previous pattern rune) leaving a gap before this one
+				if gapBest > fuzzyScoreNone {
+					gapBest += fuzzyScoreGapExtension
+				}
+				if s := scores[cell-w-2]; s > gapBest {
+					gapBest, gapFrom = s, int32(jj-2)
+				}
+			}
+			scores[cell] = fuzzyScoreNone
+			if me.folded[first+jj] != me.pattern[i] {
+				continue
+			}
+			bonus := me.bonuses[jj]
+			if i == 0 {
+				scores[cell], chunks[cell], traces[cell] = fuzzyScoreMatch+bonus*fuzzyBonusFirstCharFactor, bonus, -1
+				continue
+			}
+			if gapBest > fuzzyScoreNone {
+				scores[cell], chunks[cell], traces[cell] = gapBest+fuzzyScoreGapStart+fuzzyScoreMatch+bonus, bonus, gapFrom
+			}
+			if jj > 0 && scores[cell-w-1] > fuzzyScoreNone {
+				chunk := chunks[cell-w-1]
+				if bonus >= fuzzyBonusBoundary && bonus > chunk {
+					chunk = bonus
+				}
+				if s := scores[cell-w-1] + fuzzyScoreMatch + maxInt32(maxInt32(bonus, chunk), fuzzyBonusConsecutive); s >= scores[cell] {
+					scores[cell], chunks[cell], traces[cell] = s, chunk, int32(jj-1)
+				}
+			}
+		}
+	}
+
+	bestScore, bestAt := int32(fuzzyScoreNone), -1
+	for jj, s := range scores[(m-1)*w : m*w] {
+		if s > bestScore {
+			bestScore, bestAt = s, jj
+		}
+	}
+	if positions != nil {
+		for i, jj := m-1, bestAt; i >= 0; i-- {
+			positions[i] = me.offsets[first+jj]
+			jj = int(traces[i*w+jj])
+		}
+	}
+	return int(bestScore), true
+}
+
+func fuzzyCharClassOf(r rune) fuzzyCharClass {
+	switch {
+	case unicode.IsLower(r):
+		return fuzzyCharLower
+	case unicode.IsUpper(r) || unicode.IsTitle(r):
+		return fuzzyCharUpper
+	case unicode.IsLetter(r):
+		return fuzzyCharLetter
+	case unicode.IsNumber(r):
+		return fuzzyCharNumber
+	case unicode.IsSpace(r):
+		return fuzzyCharWhite
+	case r == '/' || r == '\\' || r == ',' || r == ':' || r == ';' || r == '|':
+		return fuzzyCharDelimiter
+	}
+	return fuzzyCharNonWord
+}
+
+//	Returns the bonus for matching a rune of `class` that follows a rune of `prevClass`.
+func fuzzyBonus(prevClass, class fuzzyCharClass) int32 {
+	if class > fuzzyCharDelimiter {
+		switch prevClass {
+		case fuzzyCharWhite:
+			return fuzzyBonusBoundaryWhite
+		case fuzzyCharDelimiter:
+			return fuzzyBonusBoundaryDelimiter
+		case fuzzyCharNonWord:
+			return fuzzyBonusBoundary
+		}
+	}
+	if (prevClass == fuzzyCharLower && class == fuzzyCharUpper) || (prevClass != fuzzyCharNumber && class == fuzzyCharNumber) {
+		return fuzzyBonusCamel123
+	}
+	switch class {
+	case fuzzyCharNonWord, fuzzyCharDelimiter:
+		return fuzzyBonusNonWord
+	case fuzzyCharWhite:
+		return fuzzyBonusBoundaryWhite
+	}
+	return 0
+}
+
+//	A min-heap of `FuzzyResult`s, so that the worst of the best can be replaced.
+type fuzzyResults []FuzzyResult
+
+func (me fuzzyResults) Len() int            { return len(me) }
+func (me fuzzyResults) Less(i, j int) bool  { return me.less(me[i], me[j]) }
+func (me fuzzyResults) Swap(i, j int)       { me[i], me[j] = me[j], me[i] }
+func (me *fuzzyResults) Push(x interface{}) { *me = append(*me, x.(FuzzyResult)) }
+func (me *fuzzyResults) Pop() (x interface{}) {
+	old := *me
+	x, *me = old[len(old)-1], old[:len(old)-1]
+	return
+}
+
+//	Returns whether `a` ranks below `b`.
+func (fuzzyResults) less(a, b FuzzyResult) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	} else if len(a.Candidate) != len(b.Candidate) {
+		return len(a.Candidate) > len(b.Candidate)
+	}
+	return a.Index > b.Index
+}
+
+func growInt32s(s []int32, n int) []int32 {
+	if cap(s) < n {
+		return make([]int32, n)
+	}
+	return s[:n]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,123 @@
+package ustr
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestEditDistances(t *testing.T) {
+	for _, test := range []struct {
+		s1, s2       string
+		lev, damerau int
+	}{
+		{"", "", 0, 0},
+		{"", "abc", 3, 3},
+		{"kitten", "sitting", 3, 3},
+		{"teh", "the", 2, 1},
+		{"ca", "abc", 3, 3},
+		{"größe", "grösse", 2, 2},
+		{"flaw", "lawn", 2, 2},
+	} {
+		for _, pair := range [][2]string{{test.s1, test.s2}, {test.s2, test.s1}} {
+			if got := Levenshtein(pair[0], pair[1]); got != test.lev {
+				t.Errorf("Levenshtein(%q, %q): got %d, want %d", pair[0], pair[1], got, test.lev)
+			}
+			if got := DamerauLevenshtein(pair[0], pair[1]); got != test.damerau {
+				t.Errorf("DamerauLevenshtein(%q, %q): got %d, want %d", pair[0], pair[1], got, test.damerau)
+			}
+		}
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	for _, test := range []struct {
+		s1, s2      string
+		jaro, winkl float64
+	}{
+		{"MARTHA", "MARHTA", 0.9444, 0.9611},
+		{"DIXON", "DICKSONX", 0.7667, 0.8133},
+		{"JELLYFISH", "SMELLYFISH", 0.8963, 0.8963},
+		{"", "", 1, 1},
+		{"abc", "", 0, 0},
+		{"abc", "xyz", 0, 0},
+	} {
+		if got := Jaro(test.s1, test.s2); math.Abs(got-test.jaro) > 1e-4 {
+			t.Errorf("Jaro(%q, %q): got %.4f, want %.4f", test.s1, test.s2, got, test.jaro)
+		}
+		if got := JaroWinkler(test.s1, test.s2); math.Abs(got-test.winkl) > 1e-4 {
+			t.Errorf("JaroWinkler(%q, %q): got %.4f, want %.4f", test.s1, test.s2, got, test.winkl)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"install", "uninstall", "list", "instal", "Lint", "status", "init"}
+	if got := strings.Join(Suggest("instll", candidates, 0), " "); got != "install instal" {
+		t.Errorf("got %q", got)
+	}
+	if got := strings.Join(Suggest("LIST", candidates, 1), " "); got != "list" {
+		t.Errorf("got %q", got)
+	}
+	if got := Suggest("completely-different", candidates, 0); len(got) != 0 {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	for _, test := range []struct {
+		pattern, candidate string
+		ok                 bool
+	}{
+		{"", "anything", true},
+		{"fb", "foo_bar", true},
+		{"fb", "bf", false},
+		{"FB", "foo_bar", false},
+		{"FB", "FooBar", true},
+		{"äö", "Ärger Öl", true},
+		{"abc", "ab", false},
+	} {
+		score, positions, ok := NewFuzzyMatcher(test.pattern).MatchPositions(test.candidate)
+		if ok != test.ok {
+			t.Errorf("%q in %q: got ok=%v (score %d)", test.pattern, test.candidate, ok, score)
+			continue
+		} else if !ok {
+			continue
+		}
+		//	the positions must spell out the pattern, in order
+		pattern, last := []rune(test.pattern), -1
+		for i, pos := range positions {
+			r, _ := utf8.DecodeRuneInString(test.candidate[pos:])
+			if pos <= last || unicode.ToLower(r) != unicode.ToLower(pattern[i]) {
+				t.Errorf("%q in %q: bad positions %v", test.pattern, test.candidate, positions)
+				break
+			}
+			last = pos
+		}
+	}
+}
+
+func TestFuzzyRank(t *testing.T) {
+	candidates := []string{"xfxxb", "src/foo/bar.go", "foobar", "FooBar.go", "nothing", "fb"}
+	results := FuzzyRank("fb", candidates, 0)
+	var got []string
+	for _, result := range results {
+		got = append(got, result.Candidate)
+		if result.Candidate != candidates[result.Index] || len(result.Positions) != 2 {
+			t.Errorf("bad result %+v", result)
+		}
+	}
+	if want := "fb FooBar.go src/foo/bar.go foobar xfxxb"; strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+	for limit := 1; limit <= len(results); limit++ {
+		limited := FuzzyRank("fb", candidates, limit)
+		for i := range limited {
+			if limited[i].Index != results[i].Index || limited[i].Score != results[i].Score {
+				t.Errorf("limit %d: got %+v at %d, want %+v", limit, limited[i], i, results[i])
+			}
+		}
+	}
+}
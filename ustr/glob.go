@@ -0,0 +1,277 @@
+package ustr
+
+import (
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+//	A compiled glob pattern with `.gitignore`-style semantics. Supported syntax:
+//
+//	- `*` matches any run of characters except `/`, `?` matches any single character except `/`;
+//
+//	- `[abc]`, `[a-z]` and the negated `[!abc]` or `[^abc]` match a single character from (or not from) a class;
+//
+//	- `\` escapes the character that follows it;
+//
+//	- `**` as a full path segment matches zero or more directories (`**/x`, `a/**/b`), or everything inside when trailing (`a/**`);
+//
+//	- a leading `!` negates the pattern (relevant only in a `Matcher` with multiple patterns, where the last matching one wins);
+//
+//	- a trailing `/` makes the pattern match directories only;
+//
+//	- a pattern containing a `/` other than a trailing one is anchored: it matches paths from the root.
+//	Otherwise it matches a name at any depth, just like `**/name`.
+//
+//	Paths given to a `Glob` are always `/`-separated (see `path/filepath.ToSlash`).
+type Glob struct {
+	pattern  string
+	segments [][]globToken
+	negated  bool
+	dirOnly  bool
+}
+
+type globTokenKind uint8
+
+const (
+	globLiteral globTokenKind = iota
+	globAnyRune
+	globAnyRunes
+	globClass
+	globAnySegments
+)
+
+type globToken struct {
+	kind    globTokenKind
+	r       rune
+	ranges  []rune
+	negated bool
+}
+
+//	Compiles the specified glob `pattern` (see `Glob` for syntax). Returns `path.ErrBadPattern` for malformed patterns.
+func ParseGlob(pattern string) (me *Glob, err error) {
+	me = &Glob{pattern: pattern}
+	if strings.HasPrefix(pattern, "!") {
+		me.negated, pattern = true, pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") && !strings.HasSuffix(pattern, "\\/") {
+		me.dirOnly, pattern = true, strings.TrimRight(pattern, "/")
+	}
+	anchored := strings.Contains(pattern, "/")
+	if pattern = strings.TrimLeft(pattern, "/"); len(pattern) == 0 {
+		return nil, path.ErrBadPattern
+	}
+	if !anchored {
+		me.segments = append(me.segments, []globToken{{kind: globAnySegments}})
+	}
+	for _, seg := range strings.Split(pattern, "/") {
+		if seg == "**" {
+			if n := len(me.segments); n == 0 || !me.segments[n-1][0].isAnySegments() {
+				me.segments = append(me.segments, []globToken{{kind: globAnySegments}})
+			}
+		} else if len(seg) > 0 {
+			var toks []globToken
+			if toks, err = parseGlobSegment(seg); err != nil {
+				return nil, err
+			}
+			me.segments = append(me.segments, toks)
+		}
+	}
+	return
+}
+
+func (me *globToken) isAnySegments() bool {
+	return me.kind == globAnySegments
+}
+
+func parseGlobSegment(seg string) (toks []globToken, err error) {
+	for i := 0; i < len(seg); {
+		switch seg[i] {
+		case '*':
+			if n := len(toks); n == 0 || toks[n-1].kind != globAnyRunes {
+				toks = append(toks, globToken{kind: globAnyRunes})
+			}
+			i++
+		case '?':
+			toks = append(toks, globToken{kind: globAnyRune})
+			i++
+		case '[':
+			tok, width, e := parseGlobClass(seg[i:])
+			if e != nil {
+				return nil, e
+			}
+			toks, i = append(toks, tok), i+width
+		case '\\':
+			if i++; i >= len(seg) {
+				return nil, path.ErrBadPattern
+			}
+			fallthrough
+		default:
+			r, n := utf8.DecodeRuneInString(seg[i:])
+			toks, i = append(toks, globToken{kind: globLiteral, r: r}), i+n
+		}
+	}
+	return
+}
+
+//	Parses the character class at the start of `s` and returns it along with its length in bytes.
+func parseGlobClass(s string) (tok globToken, width int, err error) {
+	tok.kind, width = globClass, 1
+	if width < len(s) && (s[width] == '!' || s[width] == '^') {
+		tok.negated, width = true, width+1
+	}
+	for first := true; ; first = false {
+		if width >= len(s) {
+			return tok, 0, path.ErrBadPattern
+		}
+		if s[width] == ']' && !first {
+			return tok, width + 1, nil
+		}
+		if s[width] == '\\' {
+			if width++; width >= len(s) {
+				return tok, 0, path.ErrBadPattern
+			}
+		}
+		lo, n := utf8.DecodeRuneInString(s[width:])
+		hi := lo
+		if width += n; width+1 < len(s) && s[width] == '-' && s[width+1] != ']' {
+			if width++; s[width] == '\\' {
+				if width++; width >= len(s) {
+					return tok, 0, path.ErrBadPattern
+				}
+			}
+			hi, n = utf8.DecodeRuneInString(s[width:])
+			if width += n; hi < lo {
+				return tok, 0, path.ErrBadPattern
+			}
+		}
+		tok.ranges = append(tok.ranges, lo, hi)
+	}
+}
+
+//	Returns the original pattern `me` was compiled from.
+func (me *Glob) String() string {
+	return me.pattern
+}
+
+//	Returns whether `me` is a `!`-prefixed pattern.
+func (me *Glob) IsNegated() bool {
+	return me.negated
+}
+
+//	Returns whether `me` is a `/`-suffixed pattern that only matches directories.
+func (me *Glob) IsDirOnly() bool {
+	return me.dirOnly
+}
+
+//	Returns whether the `/`-separated `path` matches `me`, where a trailing `/` in `path` denotes a directory.
+//	Negation is not applied: a negated `Glob` reports whether `path` matches its pattern sans the `!`.
+func (me *Glob) IsMatch(path string) bool {
+	return me.IsMatchPath(strings.TrimRight(path, "/"), strings.HasSuffix(path, "/"))
+}
+
+//	Returns whether the `/`-separated `path` (a directory if `isDir`) matches `me`.
+//	Negation is not applied: a negated `Glob` reports whether `path` matches its pattern sans the `!`.
+func (me *Glob) IsMatchPath(path string, isDir bool) bool {
+	if me.dirOnly && !isDir {
+		return false
+	}
+	var parts []string
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if len(part) > 0 && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return globMatchSegments(me.segments, parts)
+}
+
+func globMatchSegments(segments [][]globToken, parts []string) bool {
+	for len(segments) > 0 {
+		if segments[0][0].isAnySegments() {
+			if len(segments) == 1 {
+				//	a trailing `**` matches everything inside, but not the directory itself
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if globMatchSegments(segments[1:], parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 || !globMatchTokens(segments[0], parts[0]) {
+			return false
+		}
+		segments, parts = segments[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+func globMatchTokens(toks []globToken, name string) bool {
+	var (
+		tx, nx         int
+		starTx, starNx = -1, -1
+	)
+	for tx < len(toks) || nx < len(name) {
+		if tx < len(toks) {
+			tok := &toks[tx]
+			if tok.kind == globAnyRunes {
+				starTx, starNx, tx = tx, nx, tx+1
+				continue
+			}
+			if nx < len(name) {
+				r, n := utf8.DecodeRuneInString(name[nx:])
+				if tok.isMatch(r) {
+					tx, nx = tx+1, nx+n
+					continue
+				}
+			}
+		}
+		if starTx >= 0 && starNx < len(name) {
+			_, n := utf8.DecodeRuneInString(name[starNx:])
+			starNx += n
+			tx, nx = starTx+1, starNx
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func (me *globToken) isMatch(r rune) bool {
+	switch me.kind {
+	case globLiteral:
+		return r == me.r
+	case globAnyRune:
+		return true
+	case globClass:
+		for i := 0; i < len(me.ranges); i += 2 {
+			if r >= me.ranges[i] && r <= me.ranges[i+1] {
+				return !me.negated
+			}
+		}
+		return me.negated
+	}
+	return false
+}
+
+//	Parses the contents of a `.gitignore` file into a new `Matcher`: one `Glob` per line,
+//	ignoring blank lines, `#` comments and unescaped trailing spaces.
+//
+//	Match paths (relative to the `.gitignore` file's directory) via `Matcher.IsMatchPath`.
+func ParseGitIgnore(src string) (me *Matcher) {
+	me = &Matcher{}
+	for _, line := range strings.Split(src, "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.HasSuffix(line, " ") {
+			trimmed := strings.TrimRight(line, " ")
+			if strings.HasSuffix(trimmed, "\\") {
+				trimmed += " "
+			}
+			line = trimmed
+		}
+		if len(line) > 0 && line[0] != '#' {
+			me.addPattern(line, true)
+		}
+	}
+	return
+}
@@ -0,0 +1,129 @@
+package ustr
+
+import (
+	"path"
+	"testing"
+)
+
+func TestMatcherSimplePatternsStaySimple(t *testing.T) {
+	for _, test := range []struct {
+		pattern, value string
+		want           bool
+	}{
+		{`dir\sub`, `dir\sub`, true},
+		{`file[1].txt`, `file[1].txt`, true},
+		{`file[1].txt`, `file1.txt`, false},
+		{`!important`, `!important`, true},
+		{`!important`, `other`, false},
+		{`what?`, `what?`, true},
+		{`what?`, `whats`, false},
+		{`a*b`, `a*b`, true},
+		{`a*b`, `axb`, false},
+		{`src/main.go`, `src/main.go`, true},
+		{`*.go`, `main.go`, true},
+		{`main*`, `main.go`, true},
+		{`*ai*`, `main.go`, true},
+		{`*`, `anything`, true},
+	} {
+		var m Matcher
+		m.AddPatterns(test.pattern)
+		if got := m.IsMatch(test.value); got != test.want {
+			t.Errorf("Matcher(%q).IsMatch(%q): got %v, want %v", test.pattern, test.value, got, test.want)
+		}
+		if got := Pattern(test.pattern).IsMatch(test.value); got != test.want {
+			t.Errorf("Pattern(%q).IsMatch(%q): got %v, want %v", test.pattern, test.value, got, test.want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.go", "main.go", false, true},
+		{"*.go", "src/pkg/main.go", false, true},
+		{"*.go", "src/main.go/x", false, false},
+		{"src/*.go", "src/main.go", false, true},
+		{"src/*.go", "src/pkg/main.go", false, false},
+		{"src/**/*.go", "src/main.go", false, true},
+		{"src/**/*.go", "src/a/b/main.go", false, true},
+		{"**/testdata", "a/b/testdata", true, true},
+		{"build/**", "build/x/y", false, true},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"file?.txt", "file1.txt", false, true},
+		{"file?.txt", "file10.txt", false, false},
+		{"[a-c]x", "bx", false, true},
+		{"[!a-c]x", "bx", false, false},
+		{"[^a-c]x", "dx", false, true},
+		{`\*.txt`, "*.txt", false, true},
+		{`\*.txt`, "a.txt", false, false},
+		{"*", "é", false, true},
+		{"?", "é", false, true},
+	} {
+		glob, err := ParseGlob(test.pattern)
+		if err != nil {
+			t.Errorf("ParseGlob(%q): %v", test.pattern, err)
+			continue
+		}
+		if got := glob.IsMatchPath(test.path, test.isDir); got != test.want {
+			t.Errorf("Glob(%q).IsMatchPath(%q, %v): got %v, want %v", test.pattern, test.path, test.isDir, got, test.want)
+		}
+	}
+	for _, bad := range []string{"[a-", "x[", "a\\"} {
+		if _, err := ParseGlob(bad); err != path.ErrBadPattern {
+			t.Errorf("ParseGlob(%q): got %v, want %v", bad, err, path.ErrBadPattern)
+		}
+	}
+}
+
+func TestMatcherGlobsOptIn(t *testing.T) {
+	var m Matcher
+	if err := m.AddGlobs("*.log", "!keep.log", "[bad"); err != path.ErrBadPattern {
+		t.Errorf("AddGlobs: got %v, want %v", err, path.ErrBadPattern)
+	}
+	m.AddPatterns(GlobPatternPrefix+"tmp/**", "exact[1]")
+	for value, want := range map[string]bool{
+		"a.log":       true,
+		"dir/b.log":   true,
+		"keep.log":    false,
+		"tmp/x/y.txt": true,
+		"exact[1]":    true,
+		"exact1":      false,
+		"[bad":        false,
+	} {
+		if got := m.IsMatchPath(value, false); got != want {
+			t.Errorf("IsMatchPath(%q): got %v, want %v", value, got, want)
+		}
+	}
+	if !Pattern(GlobPatternPrefix+"src/**/*.go").IsMatchPath("src/a/b.go", false) {
+		t.Error("prefixed Pattern not matched as Glob")
+	}
+}
+
+func TestParseGitIgnore(t *testing.T) {
+	m := ParseGitIgnore("# comment\n\n*.o\n!important.o\nbuild/\n/root.txt\ntrailing\\ \n")
+	for _, test := range []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.o", false, true},
+		{"sub/a.o", false, true},
+		{"important.o", false, false},
+		{"build", true, true},
+		{"build", false, false},
+		{"sub/build", true, true},
+		{"root.txt", false, true},
+		{"sub/root.txt", false, false},
+		{"trailing ", false, true},
+		{"# comment", false, false},
+	} {
+		if got := m.IsMatchPath(test.path, test.isDir); got != test.want {
+			t.Errorf("IsMatchPath(%q, %v): got %v, want %v", test.path, test.isDir, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package ustr
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//	Escapes (or quotes) a value for a particular context, such as `EscapeShell`.
+type Escaper func(string) string
+
+//	The `Escaper`s available by name to `${name|escaper}` placeholders (see `Interpolation`). May be extended.
+var Escapers = map[string]Escaper{
+	"raw":      func(s string) string { return s },
+	"shell":    EscapeShell,
+	"sqlident": EscapeSQLIdent,
+	"json":     EscapeJSON,
+	"html":     html.EscapeString,
+	"regexp":   regexp.QuoteMeta,
+}
+
+//	A parsed string template with `${name}` placeholders, expanded via `Expand`. Supported syntax:
+//
+//	- `${name}` is replaced by the value of `name`, looked up in the data passed to `Expand`;
+//
+//	- `${a.b.c}` looks up nested values: map keys, struct fields (by name or `json` tag) and slice indices;
+//
+//	- `${name:-default}` uses `default` if `name` is missing or empty, with `\` escaping a `|` or `}` within `default`;
+//
+//	- `${name|shell}` escapes the value via the `Escapers` entry named `shell` (instead of via the `Escaper` passed to
+//	`Expand`), with `${name|raw}` not escaping at all. Multiple escapers (`${name|a|b}`) apply from left to right;
+//
+//	- `$$` is a literal `$`, as is any `$` not followed by `{`.
+type Interpolation struct {
+	src   string
+	parts []interpolationPart
+}
+
+type interpolationPart struct {
+	literal  string
+	pos      int
+	path     []string
+	name     string
+	def      string
+	hasDef   bool
+	escapers []Escaper
+}
+
+//	Describes the failure of `ParseInterpolation` or `Interpolation.Expand`.
+type InterpolationError struct {
+	//	The byte offset of the offending placeholder in the template.
+	Pos int
+
+	//	The placeholder name, if any.
+	Name string
+
+	Msg string
+}
+
+func (me *InterpolationError) Error() string {
+	if me.Name != "" {
+		return "ustr: " + me.Msg + " ${" + me.Name + "} at offset " + strconv.Itoa(me.Pos)
+	}
+	return "ustr: " + me.Msg + " at offset " + strconv.Itoa(me.Pos)
+}
+
+//	Parses the specified `src` template (see `Interpolation` for syntax).
+func ParseInterpolation(src string) (me *Interpolation, err error) {
+	me = &Interpolation{src: src}
+	var lit strings.Builder
+	for i := 0; i < len(src); i++ {
+		if src[i] != '$' || i == len(src)-1 {
+			lit.WriteByte(src[i])
+		} else if src[i+1] == '$' {
+			lit.WriteByte('$')
+			i++
+		} else if src[i+1] != '{' {
+			lit.WriteByte('$')
+		} else {
+			if lit.Len() > 0 {
+				me.parts = append(me.parts, interpolationPart{literal: lit.String()})
+				lit.Reset()
+			}
+			var part interpolationPart
+			if part, i, err = parseInterpolationPart(src, i); err != nil {
+				return nil, err
+			}
+			me.parts = append(me.parts, part)
+		}
+	}
+	if lit.Len() > 0 {
+		me.parts = append(me.parts, interpolationPart{literal: lit.String()})
+	}
+	return
+}
+
+//	Parses the placeholder starting at `src[pos:]` (with `"${"`) and returns it along with the offset of its closing `}`.
+func parseInterpolationPart(src string, pos int) (part interpolationPart, end int, err error) {
+	part.pos = pos
+	i := pos + 2
+	for ; i < len(src) && src[i] != '}' && src[i] != '|' && !strings.HasPrefix(src[i:], ":-"); i++ {
+	}
+	if part.name = strings.TrimSpace(src[pos+2 : i]); part.name == "" {
+		return part, i, &InterpolationError{Pos: pos, Msg: "empty placeholder"}
+	}
+	part.path = strings.Split(part.name, ".")
+	if strings.HasPrefix(src[i:], ":-") {
+		var def strings.Builder
+		for i, part.hasDef = i+2, true; i < len(src) && src[i] != '}' && src[i] != '|'; i++ {
+			if src[i] == '\\' && i < len(src)-1 {
+				i++
+			}
+			def.WriteByte(src[i])
+		}
+		part.def = def.String()
+	}
+	for i < len(src) && src[i] == '|' {
+		start := i + 1
+		for i = start; i < len(src) && src[i] != '}' && src[i] != '|'; i++ {
+		}
+		name := strings.TrimSpace(src[start:i])
+		escaper := Escapers[name]
+		if escaper == nil {
+			return part, i, &InterpolationError{Pos: pos, Name: part.name, Msg: "unknown escaper `" + name + "` in"}
+		}
+		part.escapers = append(part.escapers, escaper)
+	}
+	if i >= len(src) {
+		return part, i, &InterpolationError{Pos: pos, Name: part.name, Msg: "unterminated placeholder"}
+	}
+	return part, i, nil
+}
+
+//	Returns the template passed to `ParseInterpolation`.
+func (me *Interpolation) String() string {
+	return me.src
+}
+
+//	Returns the names of all placeholders (in order of first occurrence, without duplicates).
+func (me *Interpolation) Names() (names []string) {
+	for _, part := range me.parts {
+		if part.path != nil && !IsOneOf(part.name, names...) {
+			names = append(names, part.name)
+		}
+	}
+	return
+}
+
+//	Expands all placeholders with values looked up in `data`, which may be a map with `string` keys, a struct
+//	(or pointer to one), or a `func(string) (string, bool)` such as `os.LookupEnv`. Values are formatted via
+//	`fmt.Sprint` (so `fmt.Stringer`s are respected) and then escaped via the placeholder's own escapers
+//	(or, if it has none, via `escaper`, unless that's `nil`). Default values get escaped just the same.
+//
+//	Fails with an `*InterpolationError` upon the first placeholder that has no value in `data` and no default.
+func (me *Interpolation) Expand(data interface{}, escaper Escaper) (string, error) {
+	var buf strings.Builder
+	for _, part := range me.parts {
+		if part.path == nil {
+			buf.WriteString(part.literal)
+			continue
+		}
+		val, ok := interpolationLookup(data, part.path)
+		if (!ok || val == "") && part.hasDef {
+			val, ok = part.def, true
+		}
+		if !ok {
+			return "", &InterpolationError{Pos: part.pos, Name: part.name, Msg: "unknown placeholder"}
+		}
+		if len(part.escapers) > 0 {
+			for _, esc := range part.escapers {
+				val = esc(val)
+			}
+		} else if escaper != nil {
+			val = escaper(val)
+		}
+		buf.WriteString(val)
+	}
+	return buf.String(), nil
+}
+
+//	Parses `src` (see `Interpolation`) and `Expand`s it with the specified `data` and `escaper`.
+func Interpolate(src string, data interface{}, escaper Escaper) (string, error) {
+	me, err := ParseInterpolation(src)
+	if err != nil {
+		return "", err
+	}
+	return me.Expand(data, escaper)
+}
+
+func interpolationLookup(data interface{}, path []string) (val string, ok bool) {
+	if lookup, isFunc := data.(func(string) (string, bool)); isFunc {
+		return lookup(strings.Join(path, "."))
+	}
+	v := reflect.ValueOf(data)
+	for _, name := range path {
+		if v = interpolationField(v, name); !v.IsValid() {
+			return "", false
+		}
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		return string(v.Bytes()), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+//	Returns the value of the map entry, struct field or slice element `name` in `v`, or the zero `reflect.Value`.
+func interpolationField(v reflect.Value, name string) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			return v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		}
+	case reflect.Struct:
+		if f, ok := v.Type().FieldByName(name); ok && f.PkgPath == "" {
+			return v.FieldByIndex(f.Index)
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.PkgPath == "" && Before(f.Tag.Get("json"), ",", false) == name {
+				return v.Field(i)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if i, err := strconv.Atoi(name); err == nil && i >= 0 && i < v.Len() {
+			return v.Index(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+//	Quotes `s` for use as a single word in a POSIX shell command line, such as `'it'\''s'`.
+//	Returns `s` unchanged if it consists only of characters that need no quoting.
+func EscapeShell(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, r := range s {
+		if !(r < 128 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("_@%+=:,./-", r))) {
+			return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+		}
+	}
+	return s
+}
+
+//	Quotes `s` as an SQL identifier (such as a table or column name) in the standard-SQL manner: `"my ""odd"" name"`.
+func EscapeSQLIdent(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+//	Returns `s` as a quoted JSON string literal. Unlike `json.Marshal`, leaves `<`, `>` and `&` unescaped.
+func EscapeJSON(s string) string {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
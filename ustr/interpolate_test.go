@@ -0,0 +1,87 @@
+package ustr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testInterpolationData struct {
+	Name    string
+	Tagged  int `json:"tagged,omitempty"`
+	Nested  *testInterpolationData
+	Items   []string
+	private string
+}
+
+func TestInterpolate(t *testing.T) {
+	data := map[string]interface{}{
+		"user":  testInterpolationData{Name: "Ann", Tagged: 42, Nested: &testInterpolationData{Name: "Bob"}, Items: []string{"x", "y"}, private: "secret"},
+		"empty": "",
+		"path":  "it's a file.txt",
+		"html":  "<b>&</b>",
+	}
+	for _, test := range []struct {
+		src     string
+		escaper Escaper
+		want    string
+	}{
+		{"Hi ${user.Name}!", nil, "Hi Ann!"},
+		{"${user.tagged} ${user.Nested.Name} ${user.Items.1}", nil, "42 Bob y"},
+		{"${missing:-fallback} ${empty:-was empty}", nil, "fallback was empty"},
+		{`${missing:-a\}b\|c}`, nil, "a}b|c"},
+		{"$$5 and $ and $x", nil, "$5 and $ and $x"},
+		{"rm ${path|shell}", nil, `rm 'it'\''s a file.txt'`},
+		{"${html}", EscapeJSON, `"<b>&</b>"`},
+		{"${html|raw} ${html}", Escapers["html"], "<b>&</b> &lt;b&gt;&amp;&lt;/b&gt;"},
+		{"${path|sqlident|json}", nil, `"\"it's a file.txt\""`},
+		{"${missing:-x y|shell}", nil, "'x y'"},
+	} {
+		if got, err := Interpolate(test.src, data, test.escaper); err != nil || got != test.want {
+			t.Errorf("Interpolate(%q): got %q (%v), want %q", test.src, got, err, test.want)
+		}
+	}
+	lookup := func(name string) (string, bool) { return strings.ToUpper(name), name != "nope" }
+	if got, err := Interpolate("${a.b} ${nope:-default}", lookup, nil); err != nil || got != "A.B default" {
+		t.Errorf("with func: got %q (%v)", got, err)
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		data interface{}
+		pos  int
+		msg  string
+	}{
+		{"ab ${}", nil, 3, "empty placeholder"},
+		{"${x|nosuch}", nil, 0, "unknown escaper `nosuch` in"},
+		{"x ${unterminated", nil, 2, "unterminated placeholder"},
+		{"${a} ${user.private}", map[string]interface{}{"a": 1, "user": testInterpolationData{private: "secret"}}, 5, "unknown placeholder"},
+		{"${user.Items.5}", map[string]interface{}{"user": testInterpolationData{Items: []string{"x"}}}, 0, "unknown placeholder"},
+		{"${user.Nested.Name}", map[string]interface{}{"user": testInterpolationData{}}, 0, "unknown placeholder"},
+	} {
+		_, err := Interpolate(test.src, test.data, nil)
+		var ie *InterpolationError
+		if !errors.As(err, &ie) || ie.Pos != test.pos || ie.Msg != test.msg {
+			t.Errorf("Interpolate(%q): got %v, want %q at %d", test.src, err, test.msg, test.pos)
+		}
+	}
+}
+
+func TestInterpolationNames(t *testing.T) {
+	me, err := ParseInterpolation("${b} ${a.x:-1} $${c} ${b|shell}")
+	if err != nil {
+		t.Fatal(err)
+	} else if got := strings.Join(me.Names(), " "); got != "b a.x" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestEscapeShell(t *testing.T) {
+	for s, want := range map[string]string{"": "''", "plain-name_1.txt": "plain-name_1.txt", "a b": "'a b'", "it's": `'it'\''s'`, "$HOME": "'$HOME'", "ä": "'ä'"} {
+		if got := EscapeShell(s); got != want {
+			t.Errorf("EscapeShell(%q): got %q, want %q", s, got, want)
+		}
+	}
+}
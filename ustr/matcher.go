@@ -4,6 +4,11 @@ import (
 	"strings"
 )
 
+//	Opts a pattern given as a string (to `Matcher.AddPatterns`, as a `Pattern`, or to string-pattern-taking
+//	functions in other packages) into `Glob` semantics: `"glob:src/**/*.go"` is the `Glob` `src/**/*.go`.
+//	Without this prefix, patterns are always simple-patterns.
+const GlobPatternPrefix = "glob:"
+
 //	Uses a `Matcher` to determine whether `value` matches any one of the specified simple-`patterns`.
 func MatchesAny(value string, patterns ...string) bool {
 	var m Matcher
@@ -14,6 +19,7 @@ func MatchesAny(value string, patterns ...string) bool {
 type matcherPattern struct {
 	pattern, prefix, suffix, contains string
 	any                               bool
+	glob                              *Glob
 }
 
 //	Matches a string against "simple-patterns": patterns that can have asterisk (*) wildcards only
@@ -23,34 +29,70 @@ type matcherPattern struct {
 //	But I found that in a big portion of pattern-matching use-cases, I'm just doing "begins-or-ends-or-contains-or-equals" testing.
 //	Hence the conception of the "simple-pattern".
 //
+//	Patterns added via `AddGlobs` (or via `AddPatterns` with a `GlobPatternPrefix`, or via `ParseGitIgnore`)
+//	are compiled into `Glob`s instead (see there for syntax). With `!`-negated `Glob`s present,
+//	the last matching pattern decides, in the manner of `.gitignore` files.
+//
 //	There is also an alternative `Pattern` type in this package. Use `Matcher` to match strings against multiple patterns
 //	at once, especially if the patterns don't change often and the matchings occur frequently / repeatedly.
 //	In simpler, rarer one-off matchings, `Pattern` is preferable for simpler "setup-less" matching.
 type Matcher struct {
 	patterns     []matcherPattern
 	hasWildcards bool
+	hasNegations bool
 }
 
-//	Adds the specified simple-`patterns` to me.
+//	Adds the specified simple-`patterns` to me. Those prefixed with `GlobPatternPrefix` are added as `Glob`s
+//	(as per `AddGlobs`) instead, except for malformed ones: these are kept as simple-patterns matching only themselves.
 func (me *Matcher) AddPatterns(patterns ...string) {
-	var s string
-	patts := make([]matcherPattern, len(patterns))
-	for i := 0; i < len(patterns); i++ {
-		s = patterns[i]
-		if patts[i].pattern, patts[i].any = s, len(s) == 0 || s == "*"; !patts[i].any {
-			if strings.HasPrefix(s, "*") && strings.HasSuffix(s, "*") {
-				patts[i].contains = s[1 : len(s)-1]
-			} else if strings.HasPrefix(s, "*") {
-				patts[i].suffix = s[1:]
-			} else if strings.HasSuffix(s, "*") {
-				patts[i].prefix = s[:len(s)-1]
-			}
+	for _, s := range patterns {
+		if glob := strings.TrimPrefix(s, GlobPatternPrefix); glob != s {
+			me.addPattern(glob, true)
+		} else {
+			me.addPattern(s, false)
+		}
+	}
+}
+
+//	Adds the specified `Glob` `patterns` to me (without any `GlobPatternPrefix`).
+//	Malformed patterns are not added, the first one causing the returned `path.ErrBadPattern`.
+func (me *Matcher) AddGlobs(patterns ...string) (err error) {
+	for _, s := range patterns {
+		if glob, errglob := ParseGlob(s); errglob != nil && err == nil {
+			err = errglob
+		} else if errglob == nil {
+			me.addGlob(glob)
 		}
-		if patts[i].any || len(patts[i].contains) > 0 || len(patts[i].prefix) > 0 || len(patts[i].suffix) > 0 {
-			me.hasWildcards = true
+	}
+	return
+}
+
+func (me *Matcher) addGlob(glob *Glob) {
+	me.hasWildcards, me.hasNegations = true, me.hasNegations || glob.negated
+	me.patterns = append(me.patterns, matcherPattern{pattern: glob.pattern, glob: glob})
+}
+
+func (me *Matcher) addPattern(s string, asGlob bool) {
+	var patt matcherPattern
+	if patt.pattern = s; asGlob {
+		if glob, err := ParseGlob(s); err == nil {
+			me.addGlob(glob)
+			return
+		}
+	}
+	if patt.any = len(s) == 0 || s == "*"; !patt.any {
+		if strings.HasPrefix(s, "*") && strings.HasSuffix(s, "*") {
+			patt.contains = s[1 : len(s)-1]
+		} else if strings.HasPrefix(s, "*") {
+			patt.suffix = s[1:]
+		} else if strings.HasSuffix(s, "*") {
+			patt.prefix = s[:len(s)-1]
 		}
 	}
-	me.patterns = append(me.patterns, patts...)
+	if patt.any || len(patt.contains) > 0 || len(patt.prefix) > 0 || len(patt.suffix) > 0 {
+		me.hasWildcards = true
+	}
+	me.patterns = append(me.patterns, patt)
 }
 
 //	Returns whether any of the simple-patterns specified for `me` declares a (usable) *-wildcard.
@@ -58,25 +100,48 @@ func (me *Matcher) HasWildcardPatterns() bool {
 	return me.hasWildcards
 }
 
-//	Matches `s` against all patterns in `me`.
+//	Matches `s` against all patterns in `me`. For `Glob` patterns, `s` is treated as a `/`-separated path
+//	(denoting a directory if it has a trailing `/`), while simple-patterns are matched against the whole of `s`.
 func (me *Matcher) IsMatch(s string) bool {
+	return me.isMatch(s, strings.TrimRight(s, "/"), strings.HasSuffix(s, "/"))
+}
+
+//	Matches the `/`-separated `path` (a directory if `isDir`) against all patterns in `me`.
+//	Unlike in `IsMatch`, simple-patterns are matched only against the last path segment (the "base name").
+func (me *Matcher) IsMatchPath(path string, isDir bool) bool {
+	path = strings.TrimRight(path, "/")
+	name := path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		name = path[i+1:]
+	}
+	return me.isMatch(name, path, isDir)
+}
+
+//	Simple-patterns are matched against `name`, `Glob`s against `path`.
+func (me *Matcher) isMatch(name string, path string, isDir bool) (matched bool) {
+	var patt *matcherPattern
 	for i := 0; i < len(me.patterns); i++ {
-		if me.patterns[i].any || s == me.patterns[i].pattern {
-			return true
-		}
-		if me.hasWildcards {
-			if len(me.patterns[i].prefix) > 0 && strings.HasPrefix(s, me.patterns[i].prefix) {
-				return true
-			}
-			if len(me.patterns[i].suffix) > 0 && strings.HasSuffix(s, me.patterns[i].suffix) {
-				return true
+		if patt = &me.patterns[i]; patt.glob != nil {
+			//	once matched, only negations can change the outcome --- and vice versa
+			if patt.glob.negated == matched && patt.glob.IsMatchPath(path, isDir) {
+				if matched = !patt.glob.negated; !me.hasNegations {
+					return
+				}
 			}
-			if len(me.patterns[i].contains) > 0 && strings.Contains(s, me.patterns[i].contains) {
-				return true
+		} else if !matched && patt.isMatch(name) {
+			if matched = true; !me.hasNegations {
+				return
 			}
 		}
 	}
-	return false
+	return
+}
+
+func (me *matcherPattern) isMatch(s string) bool {
+	return me.any || s == me.pattern ||
+		(len(me.prefix) > 0 && strings.HasPrefix(s, me.prefix)) ||
+		(len(me.suffix) > 0 && strings.HasSuffix(s, me.suffix)) ||
+		(len(me.contains) > 0 && strings.Contains(s, me.contains))
 }
 
 //	An "leaner" alternative to `Matcher` (see docs for `Matcher`). This represents a
@@ -109,15 +174,21 @@ func (me Pattern) AnyMatches(values ...string) (firstMatch string) {
 	return
 }
 
-//	Returns whether the specified `value` matches this simple-pattern.
+//	Returns whether the specified `value` matches this simple-pattern (or, if prefixed with `GlobPatternPrefix`,
+//	this `Glob` pattern, see `Matcher.IsMatch`).
 func (me Pattern) IsMatch(value string) bool {
 	meLen := len(me)
 	if meLen == 0 || me == "*" {
 		return true
 	}
+	if strings.HasPrefix(string(me), GlobPatternPrefix) {
+		var m Matcher
+		m.AddPatterns(string(me))
+		return m.IsMatch(value)
+	}
 	prefix, suffix := me[0] == '*', me[meLen-1] == '*'
 	if prefix && suffix {
-		return strings.Contains(value, string(me)[1:meLen-2])
+		return strings.Contains(value, string(me)[1:meLen-1])
 	} else if prefix {
 		return strings.HasSuffix(value, string(me)[1:])
 	} else if suffix {
@@ -125,3 +196,11 @@ func (me Pattern) IsMatch(value string) bool {
 	}
 	return value == string(me)
 }
+
+//	Returns whether the `/`-separated `path` (a directory if `isDir`) matches this simple-pattern
+//	(or, if prefixed with `GlobPatternPrefix`, this `Glob` pattern), see `Matcher.IsMatchPath`.
+func (me Pattern) IsMatchPath(path string, isDir bool) bool {
+	var m Matcher
+	m.AddPatterns(string(me))
+	return m.IsMatchPath(path, isDir)
+}
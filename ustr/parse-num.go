@@ -0,0 +1,435 @@
+package ustr
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+//	Describes how numbers are written, for the strict parsing functions such as `NumFormat.ParseFloat`.
+type NumFormat struct {
+	//	The decimal separator, such as `'.'` or `','`. If `0`, `'.'` is used.
+	Decimal rune
+
+	//	The runes allowed as thousands separators, such as `","` or `". "`. All separators in a number must be the same
+	//	rune, with the first group of digits being 1 to 3 digits long and all others exactly 3. As in Go literals, `'_'`
+	//	(if included) may instead separate digits in any grouping, and is also allowed in hexadecimal, octal and binary numbers.
+	Thousands string
+}
+
+var (
+	//	The `NumFormat` used by the package-level strict parsing functions such as `ParseFloatStrict`:
+	//	"1,234.5", "1_000" or "1234.5". May be replaced, such as by `NumFormatDE`.
+	NumFormatDefault = &NumFormat{Decimal: '.', Thousands: ",_"}
+
+	//	Numbers as written in English: "1,234.5".
+	NumFormatEN = &NumFormat{Decimal: '.', Thousands: ","}
+
+	//	Numbers as written in German and many other European languages: "1.234,5" or "1 234,5".
+	NumFormatDE = &NumFormat{Decimal: ',', Thousands: ". \u00a0\u202f"}
+
+	//	Numbers as written in French: "1 234,5" (with a space, no-break space or narrow no-break space).
+	NumFormatFR = &NumFormat{Decimal: ',', Thousands: " \u00a0\u202f"}
+
+	//	Numbers as written in Switzerland: "1'234.5".
+	NumFormatCH = &NumFormat{Decimal: '.', Thousands: "'’"}
+)
+
+//	Describes the failure of a strict parsing function (such as `ParseIntStrict`) for a particular token.
+type NumError struct {
+	//	The offending input.
+	Token string
+
+	//	The byte offset of `Token` in the text passed to `ParseAll`, otherwise `-1`.
+	Pos int
+
+	//	The cause, such as `strconv.ErrSyntax` or `strconv.ErrRange`.
+	Err error
+}
+
+func (me *NumError) Error() string {
+	if me.Pos < 0 {
+		return "ustr: parsing " + strconv.Quote(me.Token) + ": " + me.Err.Error()
+	}
+	return "ustr: parsing " + strconv.Quote(me.Token) + " at offset " + strconv.Itoa(me.Pos) + ": " + me.Err.Error()
+}
+
+func (me *NumError) Unwrap() error {
+	return me.Err
+}
+
+//	All failures of a `ParseAll` call, in order.
+type NumErrors []*NumError
+
+func (me NumErrors) Error() string {
+	msgs := make([]string, len(me))
+	for i, err := range me {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var (
+	errNumNoUnit       = errors.New("missing or unknown unit")
+	errNumNoPercent    = errors.New("missing %")
+	errNumNotDecimal   = errors.New("fraction in non-decimal number")
+	errNumBadSeparator = errors.New("misplaced separator")
+)
+
+func numError(token string, err error) error {
+	if ne, ok := err.(*strconv.NumError); ok {
+		err = ne.Err
+	}
+	return &NumError{Token: token, Pos: -1, Err: err}
+}
+
+//	Parses `s` via `NumFormatDefault.ParseInt`, failing with a `*NumError` instead of returning `0`. Unlike `ParseInt`,
+//	accepts thousands separators and doesn't treat leading zeros as octal ("010" gives `10`, not `8`).
+func ParseIntStrict(s string) (int64, error) { return NumFormatDefault.ParseInt(s) }
+
+//	Parses `s` via `NumFormatDefault.ParseUint`, failing with a `*NumError` instead of returning `0`. Unlike `ParseUint`,
+//	accepts thousands separators and doesn't treat leading zeros as octal ("010" gives `10`, not `8`).
+func ParseUintStrict(s string) (uint64, error) { return NumFormatDefault.ParseUint(s) }
+
+//	Parses `s` via `NumFormatDefault.ParseFloat`, failing with a `*NumError` instead of returning `0`. Unlike `ParseFloat`,
+//	accepts thousands separators as well as hexadecimal, octal and binary integers, but not "Inf" and "NaN".
+func ParseFloatStrict(s string) (float64, error) { return NumFormatDefault.ParseFloat(s) }
+
+//	Parses a percentage such as "12.5%" into a fraction such as `0.125` (as per `NumFormatDefault`).
+func ParsePercent(s string) (float64, error) { return NumFormatDefault.ParsePercent(s) }
+
+//	Parses a byte size such as "1.5GiB" (as per `NumFormatDefault`), see `NumFormat.ParseByteSize`.
+func ParseByteSize(s string) (int64, error) { return NumFormatDefault.ParseByteSize(s) }
+
+//	Parses a signed integer (within `int64`): decimal with optional thousands separators, or hexadecimal, octal or
+//	binary with a `0x`, `0o` or `0b` prefix. Leading zeros don't denote octal. Surrounding white-space is ignored.
+func (me *NumFormat) ParseInt(s string) (i int64, err error) {
+	clean, base, err := me.clean(s, false)
+	if err == nil {
+		if i, err = strconv.ParseInt(clean, base, 64); err != nil {
+			i, err = 0, numError(s, err)
+		}
+	}
+	return
+}
+
+//	Parses an unsigned integer (within `uint64`), as per `ParseInt` but without a sign.
+func (me *NumFormat) ParseUint(s string) (u uint64, err error) {
+	clean, base, err := me.clean(s, false)
+	if err == nil {
+		if strings.HasPrefix(clean, "+") {
+			clean = clean[1:]
+		}
+		if u, err = strconv.ParseUint(clean, base, 64); err != nil {
+			u, err = 0, numError(s, err)
+		}
+	}
+	return
+}
+
+//	Parses a decimal number with optional thousands separators, decimal separator and exponent (such as "1,234.5e3").
+//	Hexadecimal, octal and binary integers (see `ParseInt`) are also accepted. "Inf" and "NaN" are not.
+func (me *NumFormat) ParseFloat(s string) (f float64, err error) {
+	clean, base, err := me.clean(s, true)
+	if err == nil && base != 10 {
+		var i int64
+		i, err = me.ParseInt(s)
+		return float64(i), err
+	}
+	if err == nil {
+		if f, err = strconv.ParseFloat(clean, 64); err != nil {
+			f, err = 0, numError(s, err)
+		}
+	}
+	return
+}
+
+//	Parses a percentage such as "12.5%" or "-3 %" into a fraction such as `0.125` or `-0.03`.
+func (me *NumFormat) ParsePercent(s string) (float64, error) {
+	trimmed := strings.TrimSpace(s)
+	num := strings.TrimSuffix(strings.TrimSuffix(trimmed, "%"), "％")
+	if num == trimmed {
+		return 0, &NumError{Token: s, Pos: -1, Err: errNumNoPercent}
+	}
+	f, err := me.ParseFloat(num)
+	if err != nil {
+		return 0, &NumError{Token: s, Pos: -1, Err: errors.Unwrap(err)}
+	}
+	return f / 100, nil
+}
+
+var byteSizeUnits = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1e3, "kb": 1e3, "ki": 1 << 10, "kib": 1 << 10,
+	"m": 1e6, "mb": 1e6, "mi": 1 << 20, "mib": 1 << 20,
+	"g": 1e9, "gb": 1e9, "gi": 1 << 30, "gib": 1 << 30,
+	"t": 1e12, "tb": 1e12, "ti": 1 << 40, "tib": 1 << 40,
+	"p": 1e15, "pb": 1e15, "pi": 1 << 50, "pib": 1 << 50,
+	"e": 1e18, "eb": 1e18, "ei": 1 << 60, "eib": 1 << 60,
+}
+
+//	Parses a byte size such as "512", "1.5GiB", "10 MB" or "4k" into a number of bytes (rounded to the nearest whole
+//	byte). Units are case-insensitive: "k"/"kB", "M"/"MB" etc. are decimal (powers of 1000), "Ki"/"KiB", "Mi"/"MiB" etc.
+//	binary (powers of 1024), up to exa-bytes. Negative sizes are rejected.
+func (me *NumFormat) ParseByteSize(s string) (int64, error) {
+	trimmed := strings.TrimSpace(s)
+	i := len(trimmed)
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(trimmed[:i])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		i -= size
+	}
+	mult, ok := byteSizeUnits[strings.ToLower(trimmed[i:])]
+	if !ok {
+		return 0, &NumError{Token: s, Pos: -1, Err: errNumNoUnit}
+	}
+	num := strings.TrimSpace(trimmed[:i])
+	if strings.HasPrefix(num, "-") || strings.HasPrefix(num, "−") {
+		return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrSyntax}
+	}
+	if !strings.ContainsAny(num, string(me.decimal())+"eE") {
+		n, err := me.ParseInt(num)
+		if err != nil {
+			return 0, &NumError{Token: s, Pos: -1, Err: errors.Unwrap(err)}
+		} else if m := int64(mult); n != 0 && (n > math.MaxInt64/m || n < math.MinInt64/m) {
+			return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrRange}
+		}
+		return n * int64(mult), nil
+	}
+	f, err := me.ParseFloat(num)
+	if err != nil {
+		return 0, &NumError{Token: s, Pos: -1, Err: errors.Unwrap(err)}
+	}
+	if f = math.Round(f * mult); f >= math.MaxInt64 || f < math.MinInt64 {
+		return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrRange}
+	}
+	return int64(f), nil
+}
+
+func (me *NumFormat) decimal() rune {
+	if me.Decimal == 0 {
+		return '.'
+	}
+	return me.Decimal
+}
+
+//	Validates `s` and returns it in a form accepted by `strconv` along with its base.
+func (me *NumFormat) clean(s string, allowFrac bool) (clean string, base int, err error) {
+	str := strings.TrimSpace(s)
+	var buf strings.Builder
+	if strings.HasPrefix(str, "+") || strings.HasPrefix(str, "-") {
+		buf.WriteByte(str[0])
+		str = str[1:]
+	} else if strings.HasPrefix(str, "−") {
+		//	(the Unicode minus sign)
+		buf.WriteByte('-')
+		str = str[len("−"):]
+	}
+	base = 10
+	if len(str) > 2 && str[0] == '0' {
+		switch str[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+	}
+	if base != 10 {
+		digits := str[2:]
+		if strings.HasPrefix(digits, "_") || strings.HasSuffix(digits, "_") || strings.Contains(digits, "__") ||
+			(!strings.ContainsRune(me.Thousands, '_') && strings.ContainsRune(digits, '_')) {
+			return "", base, &NumError{Token: s, Pos: -1, Err: errNumBadSeparator}
+		}
+		if allowFrac && strings.ContainsRune(digits, me.decimal()) {
+			return "", base, &NumError{Token: s, Pos: -1, Err: errNumNotDecimal}
+		}
+		buf.WriteString(strings.Replace(digits, "_", "", -1))
+		return buf.String(), base, nil
+	}
+
+	//	the integer part, with its separators checked and removed
+	var sep rune
+	group, groups := 0, 0
+	i := 0
+	for i < len(str) {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if r >= '0' && r <= '9' {
+			buf.WriteRune(r)
+			group++
+		} else if r != me.decimal() && strings.ContainsRune(me.Thousands, r) {
+			if group == 0 || (sep != 0 && r != sep) || (r != '_' && ((groups == 0 && group > 3) || (groups > 0 && group != 3))) {
+				return "", base, &NumError{Token: s, Pos: -1, Err: errNumBadSeparator}
+			}
+			sep, group, groups = r, 0, groups+1
+		} else {
+			break
+		}
+		i += size
+	}
+	if groups > 0 && (group == 0 || (sep != '_' && group != 3)) {
+		return "", base, &NumError{Token: s, Pos: -1, Err: errNumBadSeparator}
+	}
+	numDigits := len(strings.TrimLeft(buf.String(), "+-"))
+	str = str[i:]
+	if r, size := utf8.DecodeRuneInString(str); allowFrac && r == me.decimal() && size > 0 {
+		buf.WriteByte('.')
+		str = str[size:]
+		for len(str) > 0 && str[0] >= '0' && str[0] <= '9' {
+			buf.WriteByte(str[0])
+			str, numDigits = str[1:], numDigits+1
+		}
+	}
+	if numDigits == 0 {
+		return "", base, &NumError{Token: s, Pos: -1, Err: strconv.ErrSyntax}
+	}
+	if allowFrac && len(str) > 1 && (str[0] == 'e' || str[0] == 'E') {
+		j := 1
+		if str[j] == '+' || str[j] == '-' {
+			j++
+		}
+		start := j
+		for j < len(str) && str[j] >= '0' && str[j] <= '9' {
+			j++
+		}
+		if j > start {
+			buf.WriteString(str[:j])
+			str = str[j:]
+		}
+	}
+	if str != "" {
+		return "", base, &NumError{Token: s, Pos: -1, Err: strconv.ErrSyntax}
+	}
+	return buf.String(), base, nil
+}
+
+//	Parses `s` as `ParseBool` does, but fails with a `*NumError` instead of returning `false`.
+//	Accepts (case-insensitively) "1", "t", "true", "y", "yes" and "on" as well as "0", "f", "false", "n", "no" and "off".
+func ParseBoolStrict(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, &NumError{Token: s, Pos: -1, Err: strconv.ErrSyntax}
+}
+
+var durationUnits = map[string]uint64{
+	"ns": uint64(time.Nanosecond), "us": uint64(time.Microsecond), "µs": uint64(time.Microsecond), "μs": uint64(time.Microsecond),
+	"ms": uint64(time.Millisecond), "s": uint64(time.Second), "m": uint64(time.Minute), "h": uint64(time.Hour),
+	"d": uint64(24 * time.Hour), "w": uint64(7 * 24 * time.Hour),
+}
+
+//	Parses a duration as `time.ParseDuration` does (such as "1h30m" or "-1.5s"), but also accepting days ("d", 24 hours)
+//	and weeks ("w", 7 days), as in "2d12h" or "1.5w", and white-space between the components (as in "1d 6h").
+func ParseDuration(s string) (time.Duration, error) {
+	str := strings.TrimSpace(s)
+	neg := strings.HasPrefix(str, "-")
+	if neg || strings.HasPrefix(str, "+") {
+		str = str[1:]
+	}
+	if str == "0" {
+		return 0, nil
+	} else if str == "" {
+		return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrSyntax}
+	}
+	var total uint64
+	for str != "" {
+		//	the number: integer and fractional digits
+		i, whole, frac, scale := 0, uint64(0), uint64(0), uint64(1)
+		for ; i < len(str) && str[i] >= '0' && str[i] <= '9'; i++ {
+			//	up to 1<<63 to permit `math.MinInt64` when negative, the total is range-checked below
+			digit := uint64(str[i] - '0')
+			if whole > (1<<63-digit)/10 {
+				return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrRange}
+			}
+			whole = whole*10 + digit
+		}
+		intLen := i
+		if i < len(str) && str[i] == '.' {
+			for i++; i < len(str) && str[i] >= '0' && str[i] <= '9'; i++ {
+				if scale < 1e18 {
+					frac, scale = frac*10+uint64(str[i]-'0'), scale*10
+				}
+			}
+		}
+		if i == 0 || (intLen == 0 && scale == 1) {
+			return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrSyntax}
+		}
+		str = str[i:]
+
+		//	the unit
+		j := 0
+		for j < len(str) && !(str[j] >= '0' && str[j] <= '9') && str[j] != '.' && str[j] != ' ' {
+			j++
+		}
+		unit, ok := durationUnits[str[:j]]
+		if !ok {
+			return 0, &NumError{Token: s, Pos: -1, Err: errNumNoUnit}
+		}
+		str = strings.TrimLeft(str[j:], " ")
+		if whole > (1<<63)/unit {
+			return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrRange}
+		}
+		v := whole*unit + uint64(float64(frac)*(float64(unit)/float64(scale)))
+		if total += v; total > math.MaxInt64 || total < v {
+			if !(neg && total == 1<<63) {
+				return 0, &NumError{Token: s, Pos: -1, Err: strconv.ErrRange}
+			}
+		}
+	}
+	if neg {
+		return -time.Duration(total), nil
+	}
+	return time.Duration(total), nil
+}
+
+//	Splits `s` at any of the `seps` runes (or, if `seps` is empty, at white-space) and parses every non-empty token
+//	via `parse` (such as `ParseFloatStrict` or `NumFormatDE.ParseFloat`). Returns the values of all tokens in order
+//	(with the zero value for any that failed) and, if any failed, a `NumErrors` with the offset in `s` of each.
+func ParseAll[T any](s string, seps string, parse func(string) (T, error)) (vals []T, err error) {
+	var errs NumErrors
+	isSep := unicode.IsSpace
+	if seps != "" {
+		isSep = func(r rune) bool { return strings.ContainsRune(seps, r) }
+	}
+	start := -1
+	for i, r := range s + "\x00" {
+		if i < len(s) && !isSep(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		} else if start < 0 {
+			continue
+		}
+		raw := s[start:i]
+		token := strings.TrimLeftFunc(raw, unicode.IsSpace)
+		pos := start + len(raw) - len(token)
+		if token = strings.TrimRightFunc(token, unicode.IsSpace); token == "" {
+			start = -1
+			continue
+		}
+		val, e := parse(token)
+		if e != nil {
+			ne, ok := e.(*NumError)
+			if !ok {
+				ne = &NumError{Err: e}
+			}
+			errs = append(errs, &NumError{Token: token, Pos: pos, Err: ne.Err})
+		}
+		vals, start = append(vals, val), -1
+	}
+	if len(errs) > 0 {
+		err = errs
+	}
+	return
+}
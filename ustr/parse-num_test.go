@@ -0,0 +1,151 @@
+package ustr
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseDurationLikeStdlib(t *testing.T) {
+	for _, s := range []string{
+		"0", "-0", "+0", "1ns", "1.5s", "-1.5h", "1h30m", "300ms", "2h45m30.5s", ".5s", "1.s", "1µs", "1μs", "1us",
+		"9223372036854775807ns", "-9223372036854775808ns", "2562047h47m16.854775807s", "-2562047h47m16.854775808s",
+		"9223372036854775808ns", "2562048h", "99999999999999999999ns", "1.0000000000000000001s",
+		"", "1", "1x", ".s", "-", "1.5.5s",
+	} {
+		want, wantErr := time.ParseDuration(s)
+		got, err := ParseDuration(s)
+		if (err != nil) != (wantErr != nil) || got != want {
+			t.Errorf("ParseDuration(%q): got %v (%v), want %v (%v)", s, got, err, want, wantErr)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want time.Duration
+		err  error
+	}{
+		{"1d", 24 * time.Hour, nil},
+		{"1.5w", 252 * time.Hour, nil},
+		{" 2d 12h ", 60 * time.Hour, nil},
+		{"-1d6h", -30 * time.Hour, nil},
+		{"106751d23h47m16.854775807s", math.MaxInt64, nil},
+		{"106752d", 0, strconv.ErrRange},
+		{"1y", 0, errNumNoUnit},
+		{"d", 0, strconv.ErrSyntax},
+	} {
+		got, err := ParseDuration(test.s)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("ParseDuration(%q): got %v (%v), want %v (%v)", test.s, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestParseIntStrict(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want int64
+		err  error
+	}{
+		{"010", 10, nil},
+		{" -42 ", -42, nil},
+		{"−7", -7, nil},
+		{"1,234,567", 1234567, nil},
+		{"1_0_0", 100, nil},
+		{"0x1F", 31, nil},
+		{"0o17", 15, nil},
+		{"0b101", 5, nil},
+		{"0x_1F", 0, errNumBadSeparator},
+		{"1,23", 0, errNumBadSeparator},
+		{"1234,567", 0, errNumBadSeparator},
+		{"1,234_567", 0, errNumBadSeparator},
+		{"1.5", 0, strconv.ErrSyntax},
+		{"", 0, strconv.ErrSyntax},
+		{"9223372036854775808", 0, strconv.ErrRange},
+	} {
+		got, err := ParseIntStrict(test.s)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("ParseIntStrict(%q): got %v (%v), want %v (%v)", test.s, got, err, test.want, test.err)
+		}
+	}
+	if got := ParseInt("010"); got != 8 {
+		t.Errorf("ParseInt(%q): got %v, want 8", "010", got)
+	}
+}
+
+func TestParseFloatFormats(t *testing.T) {
+	for _, test := range []struct {
+		format *NumFormat
+		s      string
+		want   float64
+		err    error
+	}{
+		{NumFormatDefault, "1,234.5e3", 1234500, nil},
+		{NumFormatDefault, "0x10", 16, nil},
+		{NumFormatDefault, "Inf", 0, strconv.ErrSyntax},
+		{NumFormatDE, "1.234,5", 1234.5, nil},
+		{NumFormatDE, "1 234,5", 1234.5, nil},
+		{NumFormatDE, "1.234 567,5", 0, errNumBadSeparator},
+		{NumFormatFR, "1 234,5", 1234.5, nil},
+		{NumFormatCH, "1'234.5", 1234.5, nil},
+	} {
+		got, err := test.format.ParseFloat(test.s)
+		if got != test.want || !errors.Is(err, test.err) {
+			t.Errorf("ParseFloat(%q): got %v (%v), want %v (%v)", test.s, got, err, test.want, test.err)
+		}
+	}
+}
+
+func TestParseByteSizeAndPercent(t *testing.T) {
+	for s, want := range map[string]int64{"512": 512, "1.5GiB": 3 << 29, "10 MB": 10e6, "4k": 4000, "1KiB": 1024, "0.5b": 1} {
+		if got, err := ParseByteSize(s); err != nil || got != want {
+			t.Errorf("ParseByteSize(%q): got %v (%v), want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"-1k", "1x", "16EiB"} {
+		if _, err := ParseByteSize(s); err == nil {
+			t.Errorf("ParseByteSize(%q): want an error", s)
+		}
+	}
+	if got, err := ParsePercent("-12.5 %"); err != nil || got != -0.125 {
+		t.Errorf("ParsePercent: got %v (%v)", got, err)
+	}
+	if _, err := ParsePercent("12.5"); !errors.Is(err, errNumNoPercent) {
+		t.Errorf("ParsePercent without %%: got %v", err)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	vals, err := ParseAll(" 1, 2x,3 ,, 4y", ",", ParseIntStrict)
+	if want := []int64{1, 0, 3, 0}; len(vals) != len(want) || vals[0] != 1 || vals[2] != 3 {
+		t.Errorf("got %v, want %v", vals, want)
+	}
+	errs, ok := err.(NumErrors)
+	if !ok || len(errs) != 2 || errs[0].Token != "2x" || errs[0].Pos != 4 || errs[1].Token != "4y" || errs[1].Pos != 12 {
+		t.Errorf("got %v", err)
+	}
+	if want := `ustr: parsing "2x" at offset 4: invalid syntax; ustr: parsing "4y" at offset 12: invalid syntax`; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
+
+func TestNumErrorMessage(t *testing.T) {
+	for _, test := range []struct {
+		parse func() error
+		want  string
+	}{
+		{func() error { _, err := ParseIntStrict("12a"); return err }, `ustr: parsing "12a": invalid syntax`},
+		{func() error { _, err := ParseIntStrict("99999999999999999999"); return err }, `ustr: parsing "99999999999999999999": value out of range`},
+		{func() error { _, err := ParsePercent("0"); return err }, `ustr: parsing "0": missing %`},
+		{func() error { _, err := ParseByteSize("1.5XB"); return err }, `ustr: parsing "1.5XB": missing or unknown unit`},
+	} {
+		var ne *NumError
+		if err := test.parse(); !errors.As(err, &ne) || ne.Pos != -1 || err.Error() != test.want {
+			t.Errorf("got %v, want %q", err, test.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package ustr
+
+import (
+	"strconv"
+	"strings"
+)
+
+//	Describes the failure of `ApplyUnifiedDiff`.
+type PatchError struct {
+	//	The `1`-based number of the offending hunk, or `0` if the diff had no hunks at all.
+	Hunk int
+
+	Msg string
+}
+
+func (me *PatchError) Error() string {
+	if me.Hunk == 0 {
+		return "ustr: " + me.Msg
+	}
+	return "ustr: hunk #" + strconv.Itoa(me.Hunk) + ": " + me.Msg
+}
+
+//	Applies the unified diff `patch` (as rendered by `LineDiff.Unified` or `diff -u`, for a single file) to `text`.
+//
+//	Each hunk is first tried at the position given in its header (adjusted by how much preceding hunks changed
+//	the line count), then at increasingly distant positions. If it matches nowhere, up to `fuzz` leading and
+//	trailing context lines of the hunk are ignored, as with the `--fuzz` option of GNU `patch`. Lines are compared
+//	regardless of their line breaks ("\n" or "\r\n"), and context lines are kept as they are in `text`.
+//	Fails with a `*PatchError` if any hunk cannot be applied.
+func ApplyUnifiedDiff(text string, patch string, fuzz int) (patched string, err error) {
+	hunks, err := parseUnifiedDiff(patch)
+	if err != nil {
+		return "", err
+	}
+	lines, delta, minPos := SplitLines(text), 0, 0
+	for h, hunk := range hunks {
+		var oldLines, newLines []string
+		leadCtx, trailCtx := 0, 0
+		for i, line := range hunk.Lines {
+			if line.Op != DiffInsert {
+				oldLines = append(oldLines, line.Text)
+			}
+			if line.Op == DiffEqual && leadCtx == i {
+				leadCtx++
+			}
+		}
+		if leadCtx == len(hunk.Lines) {
+			continue
+		}
+		for i := len(hunk.Lines) - 1; hunk.Lines[i].Op == DiffEqual; i-- {
+			trailCtx++
+		}
+		pos, lead, trail := -1, 0, 0
+		for f := 0; f <= fuzz && f <= maxInt(leadCtx, trailCtx) && pos < 0; f++ {
+			lead, trail = minInt(f, leadCtx), minInt(f, trailCtx)
+			pos = findLines(lines, oldLines[lead:len(oldLines)-trail], hunk.AStart+delta+lead, minPos)
+		}
+		if pos < 0 {
+			return "", &PatchError{Hunk: h + 1, Msg: "does not match the text"}
+		}
+		//	ignored context lines stay as they are in `text`, as do matched ones (keeping their line breaks)
+		oldLines = oldLines[lead : len(oldLines)-trail]
+		next := pos
+		for _, line := range hunk.Lines[lead : len(hunk.Lines)-trail] {
+			switch line.Op {
+			case DiffInsert:
+				newLines = append(newLines, line.Text)
+			case DiffEqual:
+				newLines, next = append(newLines, lines[next]), next+1
+			case DiffDelete:
+				next++
+			}
+		}
+		lines = append(lines[:pos], append(newLines, lines[pos+len(oldLines):]...)...)
+		delta, minPos = delta+len(newLines)-len(oldLines), pos+len(newLines)
+	}
+	return strings.Join(lines, ""), nil
+}
+
+//	Returns the index of the occurrence of `want` in `lines` at or after `minPos` that is closest to `near`, or `-1`.
+func findLines(lines []string, want []string, near int, minPos int) int {
+	matches := func(pos int) bool {
+		for i, line := range want {
+			if strings.TrimRight(lines[pos+i], "\r\n") != strings.TrimRight(line, "\r\n") {
+				return false
+			}
+		}
+		return true
+	}
+	maxPos := len(lines) - len(want)
+	if near = minInt(maxInt(near, minPos), maxInt(maxPos, minPos)); maxPos < minPos {
+		return -1
+	}
+	for dist := 0; near-dist >= minPos || near+dist <= maxPos; dist++ {
+		if pos := near - dist; pos >= minPos && pos <= maxPos && matches(pos) {
+			return pos
+		} else if pos = near + dist; dist > 0 && pos <= maxPos && pos >= minPos && matches(pos) {
+			return pos
+		}
+	}
+	return -1
+}
+
+//	Parses the hunks of the unified diff `patch`, ignoring any lines before the first hunk (such as its headers).
+func parseUnifiedDiff(patch string) (hunks []DiffHunk, err error) {
+	lines := SplitLines(patch)
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "@@ -") {
+			continue
+		}
+		var hunk DiffHunk
+		fields := strings.Fields(lines[i])
+		if len(fields) < 4 || fields[3] != "@@" || !strings.HasPrefix(fields[2], "+") {
+			return nil, &PatchError{Hunk: len(hunks) + 1, Msg: "malformed header " + strconv.Quote(strings.TrimSpace(lines[i]))}
+		}
+		aStart, aLen, okA := parseUnifiedRange(fields[1][1:])
+		bStart, bLen, okB := parseUnifiedRange(fields[2][1:])
+		if !(okA && okB) {
+			return nil, &PatchError{Hunk: len(hunks) + 1, Msg: "malformed header " + strconv.Quote(strings.TrimSpace(lines[i]))}
+		}
+		hunk.AStart, hunk.BStart = aStart, bStart
+		for i++; i < len(lines) && (hunk.ALen < aLen || hunk.BLen < bLen); i++ {
+			line := lines[i]
+			var op DiffOp
+			switch {
+			case strings.HasPrefix(line, "\\"):
+				//	"\ No newline at end of file" refers to the preceding line
+				if n := len(hunk.Lines); n > 0 {
+					hunk.Lines[n-1].Text = strings.TrimSuffix(strings.TrimSuffix(hunk.Lines[n-1].Text, "\n"), "\r")
+					continue
+				}
+				return nil, &PatchError{Hunk: len(hunks) + 1, Msg: "no line precedes " + strconv.Quote(strings.TrimSpace(line))}
+			case line == "\n" || line == "\r\n":
+				//	(some tools strip the space of empty context lines)
+				op, line = DiffEqual, " "+line
+			case line[0] == ' ':
+				op = DiffEqual
+			case line[0] == '-':
+				op = DiffDelete
+			case line[0] == '+':
+				op = DiffInsert
+			default:
+				return nil, &PatchError{Hunk: len(hunks) + 1, Msg: "unexpected line " + strconv.Quote(strings.TrimSpace(line))}
+			}
+			hunk.addLines(op, []string{line[1:]})
+		}
+		if i < len(lines) && strings.HasPrefix(lines[i], "\\") {
+			n := len(hunk.Lines)
+			if n == 0 {
+				return nil, &PatchError{Hunk: len(hunks) + 1, Msg: "no line precedes " + strconv.Quote(strings.TrimSpace(lines[i]))}
+			}
+			hunk.Lines[n-1].Text = strings.TrimSuffix(strings.TrimSuffix(hunk.Lines[n-1].Text, "\n"), "\r")
+		} else {
+			i--
+		}
+		if hunk.ALen != aLen || hunk.BLen != bLen {
+			return nil, &PatchError{Hunk: len(hunks) + 1, Msg: "fewer lines than its header states"}
+		}
+		hunks = append(hunks, hunk)
+	}
+	if len(hunks) == 0 && strings.TrimSpace(patch) != "" {
+		err = &PatchError{Msg: "no hunks found"}
+	}
+	return
+}
+
+//	Parses a hunk range such as "12,3" or "12" into the `0`-based start and the length.
+func parseUnifiedRange(s string) (start int, n int, ok bool) {
+	n = 1
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		var err error
+		if n, err = strconv.Atoi(s[i+1:]); err != nil || n < 0 {
+			return 0, 0, false
+		}
+		s = s[:i]
+	}
+	start, err := strconv.Atoi(s)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	if n > 0 && start > 0 {
+		start--
+	}
+	return start, n, true
+}
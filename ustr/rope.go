@@ -0,0 +1,454 @@
+package ustr
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//	The unit in which `Rope` offsets and columns are counted.
+type TextUnit int
+
+const (
+	//	Bytes of the UTF-8 encoding, as used by Go strings.
+	TextUnitByte TextUnit = iota
+
+	//	Unicode code points (runes), as used by many editors.
+	TextUnitRune
+
+	//	UTF-16 code units, as used by the Language Server Protocol and JavaScript.
+	TextUnitUTF16
+)
+
+//	A position in a text, as line and column (both `0`-based), with the column counted in some `TextUnit`.
+type TextPos struct {
+	Line int
+	Col  int
+}
+
+//	Replaces the text from `Start` up to (excluding) `End` with `Text`. (To insert, `Start` and `End` are the same.)
+type TextEdit struct {
+	Start TextPos
+	End   TextPos
+	Text  string
+}
+
+//	Returned by `Rope.ApplyEdits` for edits whose ranges overlap.
+var ErrTextEditsOverlap = errors.New("ustr: overlapping text edits")
+
+//	The maximum size in bytes of the chunks that a `Rope` holds its text in.
+const ropeChunkMax = 1024
+
+//	A mutable text for efficient editing of large documents: a balanced tree (treap) of text chunks in which
+//	inserting, deleting and converting between byte offsets, rune offsets, UTF-16 offsets and line/column positions
+//	each take expected O(log n) time, with n being the length of the text (not counting the length of inserted text).
+//
+//	All offsets are clamped to the text, and byte offsets must not fall into the middle of a UTF-8 sequence.
+//	Lines are separated by "\n" (so also by "\r\n"). The zero value is an empty `Rope`, ready to use.
+type Rope struct {
+	root *ropeNode
+	seed uint32
+}
+
+type ropeMetrics struct {
+	bytes, runes, utf16, lines int
+}
+
+type ropeNode struct {
+	left, right *ropeNode
+	prio        uint32
+	chunk       string
+	own, sum    ropeMetrics
+}
+
+//	Returns a `Rope` containing `s`.
+func NewRope(s string) (me *Rope) {
+	me = &Rope{}
+	me.Insert(0, s)
+	return
+}
+
+//	Returns the length of the text in bytes.
+func (me *Rope) Len() int {
+	return me.root.metrics().bytes
+}
+
+//	Returns the length of the text in the specified `unit`.
+func (me *Rope) LenIn(unit TextUnit) int {
+	m := me.root.metrics()
+	return m.in(unit)
+}
+
+//	Returns the number of lines, which is `1` more than the number of "\n"s.
+func (me *Rope) LineCount() int {
+	return me.root.metrics().lines + 1
+}
+
+//	Returns the whole text.
+func (me *Rope) String() string {
+	var buf strings.Builder
+	buf.Grow(me.Len())
+	me.root.walk(0, me.Len(), func(s string) { buf.WriteString(s) })
+	return buf.String()
+}
+
+//	Implements `io.WriterTo` by writing the whole text to `w`.
+func (me *Rope) WriteTo(w io.Writer) (n int64, err error) {
+	me.root.walk(0, me.Len(), func(s string) {
+		if err == nil {
+			var written int
+			written, err = io.WriteString(w, s)
+			n += int64(written)
+		}
+	})
+	return
+}
+
+//	Returns the text between the byte offsets `start` and `end`.
+func (me *Rope) Slice(start int, end int) string {
+	start, end = me.clamp(start), me.clamp(end)
+	if end <= start {
+		return ""
+	}
+	var buf strings.Builder
+	buf.Grow(end - start)
+	me.root.walk(start, end, func(s string) { buf.WriteString(s) })
+	return buf.String()
+}
+
+//	Returns the `0`-based `line` without its line break, or `""` if there is no such line.
+func (me *Rope) Line(line int) string {
+	if line < 0 || line >= me.LineCount() {
+		return ""
+	}
+	return me.Slice(me.LineStart(line), me.lineEnd(line))
+}
+
+//	Inserts `s` at the byte offset `offset`.
+func (me *Rope) Insert(offset int, s string) {
+	if s == "" {
+		return
+	}
+	left, right := me.root.split(me.clamp(offset))
+	if left == nil || !left.appendLast(s) {
+		for len(s) > 0 {
+			n := len(s)
+			if n > ropeChunkMax {
+				for n = ropeChunkMax; n > ropeChunkMax-utf8.UTFMax && !utf8.RuneStart(s[n]); n-- {
+				}
+			}
+			left = ropeMerge(left, me.newNode(s[:n]))
+			s = s[n:]
+		}
+	}
+	me.root = ropeMerge(left, right)
+}
+
+//	Deletes the text between the byte offsets `start` and `end`.
+func (me *Rope) Delete(start int, end int) {
+	start, end = me.clamp(start), me.clamp(end)
+	if end <= start {
+		return
+	}
+	left, rest := me.root.split(start)
+	_, right := rest.split(end - start)
+	me.root = ropeMerge(left, right)
+}
+
+//	Replaces the text between the byte offsets `start` and `end` with `s`.
+func (me *Rope) Replace(start int, end int, s string) {
+	me.Delete(start, end)
+	me.Insert(start, s)
+}
+
+//	Converts the `offset` counted in `unit` into a byte offset. An offset into the middle of a UTF-16 surrogate pair
+//	is rounded down to the start of its rune.
+func (me *Rope) ByteOffset(offset int, unit TextUnit) int {
+	if unit == TextUnitByte {
+		return me.clamp(offset)
+	} else if offset <= 0 {
+		return 0
+	}
+	chunk, before, rest := me.root.seek(offset, func(m *ropeMetrics) int { return m.in(unit) })
+	if chunk == "" {
+		return me.Len()
+	}
+	i := 0
+	for rest > 0 && i < len(chunk) {
+		r, size := utf8.DecodeRuneInString(chunk[i:])
+		if n := runeUnits(r, unit); n > rest {
+			break
+		} else {
+			rest -= n
+		}
+		i += size
+	}
+	return before.bytes + i
+}
+
+//	Converts the byte offset `offset` into an offset counted in `unit`.
+func (me *Rope) Offset(offset int, unit TextUnit) int {
+	if offset = me.clamp(offset); unit == TextUnitByte || offset == 0 {
+		return offset
+	}
+	chunk, before, rest := me.root.seek(offset, func(m *ropeMetrics) int { return m.bytes })
+	m := metricsOf(chunk[:rest])
+	return before.in(unit) + m.in(unit)
+}
+
+//	Returns the line/column position of the byte offset `offset`, with the column counted in `unit`.
+func (me *Rope) Pos(offset int, unit TextUnit) (pos TextPos) {
+	if offset = me.clamp(offset); offset == 0 {
+		return
+	}
+	chunk, before, rest := me.root.seek(offset, func(m *ropeMetrics) int { return m.bytes })
+	pos.Line = before.lines + strings.Count(chunk[:rest], "\n")
+	pos.Col = me.Offset(offset, unit) - me.Offset(me.LineStart(pos.Line), unit)
+	return
+}
+
+//	Returns the byte offset of the line/column position `pos`, with the column counted in `unit`.
+//	Columns beyond the end of the line are clamped to the end of the line (before its line break).
+func (me *Rope) OffsetOf(pos TextPos, unit TextUnit) int {
+	if pos.Line < 0 {
+		return 0
+	} else if pos.Line >= me.LineCount() {
+		return me.Len()
+	}
+	start, end := me.LineStart(pos.Line), me.lineEnd(pos.Line)
+	if pos.Col <= 0 {
+		return start
+	}
+	if offset := me.ByteOffset(me.Offset(start, unit)+pos.Col, unit); offset < end {
+		return offset
+	}
+	return end
+}
+
+//	Returns the byte offset at which the `0`-based `line` starts (or the length of the text, if there is no such line).
+func (me *Rope) LineStart(line int) int {
+	if line <= 0 {
+		return 0
+	} else if line >= me.LineCount() {
+		return me.Len()
+	}
+	chunk, before, rest := me.root.seek(line, func(m *ropeMetrics) int { return m.lines })
+	i := 0
+	for ; rest > 0; rest-- {
+		i += strings.IndexByte(chunk[i:], '\n') + 1
+	}
+	return before.bytes + i
+}
+
+//	Returns the byte offset at which the `0`-based `line` ends, before its "\n" or "\r\n" (if any).
+func (me *Rope) lineEnd(line int) (end int) {
+	if line+1 >= me.LineCount() {
+		return me.Len()
+	}
+	if end = me.LineStart(line+1) - 1; end > me.LineStart(line) && me.Slice(end-1, end) == "\r" {
+		end--
+	}
+	return
+}
+
+//	Applies all `edits` (with columns counted in `unit`), whose positions all refer to the text as it was before any
+//	of them, like those of a Language Server Protocol `TextEdit[]` or those returned by `udevgo.Gorename`
+//	(see `udev.SrcMsgs.TextEdits`). Edits inserting at the same position are inserted in their given order.
+//	Fails (without changing anything) with `ErrTextEditsOverlap` if the ranges of any two edits overlap.
+func (me *Rope) ApplyEdits(edits []TextEdit, unit TextUnit) error {
+	type edit struct {
+		start, end, index int
+	}
+	sorted := make([]edit, len(edits))
+	for i := range edits {
+		start, end := me.OffsetOf(edits[i].Start, unit), me.OffsetOf(edits[i].End, unit)
+		if end < start {
+			start, end = end, start
+		}
+		sorted[i] = edit{start: start, end: end, index: i}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].start < sorted[j].start || (sorted[i].start == sorted[j].start && sorted[i].end < sorted[j].end)
+	})
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i-1].end > sorted[i].start {
+			return ErrTextEditsOverlap
+		}
+	}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		me.Replace(sorted[i].start, sorted[i].end, edits[sorted[i].index].Text)
+	}
+	return nil
+}
+
+func (me *Rope) clamp(offset int) int {
+	if offset < 0 {
+		return 0
+	} else if l := me.Len(); offset > l {
+		return l
+	}
+	return offset
+}
+
+func (me *Rope) newNode(chunk string) (node *ropeNode) {
+	//	xorshift32 for the treap priorities
+	if me.seed == 0 {
+		me.seed = 2463534242
+	}
+	me.seed ^= me.seed << 13
+	me.seed ^= me.seed >> 17
+	me.seed ^= me.seed << 5
+	node = &ropeNode{prio: me.seed, chunk: chunk, own: metricsOf(chunk)}
+	node.sum = node.own
+	return
+}
+
+func metricsOf(s string) (m ropeMetrics) {
+	m.bytes, m.lines = len(s), strings.Count(s, "\n")
+	for _, r := range s {
+		m.runes, m.utf16 = m.runes+1, m.utf16+runeUnits(r, TextUnitUTF16)
+	}
+	return
+}
+
+func (me *ropeMetrics) add(m *ropeMetrics) {
+	me.bytes, me.runes, me.utf16, me.lines = me.bytes+m.bytes, me.runes+m.runes, me.utf16+m.utf16, me.lines+m.lines
+}
+
+func (me *ropeMetrics) in(unit TextUnit) int {
+	switch unit {
+	case TextUnitRune:
+		return me.runes
+	case TextUnitUTF16:
+		return me.utf16
+	}
+	return me.bytes
+}
+
+//	Returns the number of `unit`s that `r` is encoded in.
+func runeUnits(r rune, unit TextUnit) int {
+	switch unit {
+	case TextUnitByte:
+		if r == utf8.RuneError {
+			return 1
+		}
+		return utf8.RuneLen(r)
+	case TextUnitUTF16:
+		if r >= 0x10000 {
+			return 2
+		}
+	}
+	return 1
+}
+
+func (me *ropeNode) metrics() ropeMetrics {
+	if me == nil {
+		return ropeMetrics{}
+	}
+	return me.sum
+}
+
+func (me *ropeNode) update() {
+	me.sum = me.left.metrics()
+	me.sum.add(&me.own)
+	right := me.right.metrics()
+	me.sum.add(&right)
+}
+
+//	Splits the tree at the byte offset `offset` (within `0` and `me.sum.bytes`).
+func (me *ropeNode) split(offset int) (left *ropeNode, right *ropeNode) {
+	if me == nil {
+		return nil, nil
+	}
+	if l := me.left.metrics().bytes; offset <= l {
+		left, me.left = me.left.split(offset)
+		me.update()
+		return left, me
+	} else if offset -= l; offset >= len(me.chunk) {
+		me.right, right = me.right.split(offset - len(me.chunk))
+		me.update()
+		return me, right
+	}
+	//	the offset falls within this chunk: its tail becomes a node of its own, taking over the right subtree
+	tail := &ropeNode{prio: me.prio, chunk: me.chunk[offset:], right: me.right}
+	tail.own = metricsOf(tail.chunk)
+	me.chunk, me.right = me.chunk[:offset], nil
+	me.own = metricsOf(me.chunk)
+	me.update()
+	tail.update()
+	return me, tail
+}
+
+func ropeMerge(left *ropeNode, right *ropeNode) *ropeNode {
+	if left == nil {
+		return right
+	} else if right == nil {
+		return left
+	} else if left.prio >= right.prio {
+		left.right = ropeMerge(left.right, right)
+		left.update()
+		return left
+	}
+	right.left = ropeMerge(left, right.left)
+	right.update()
+	return right
+}
+
+//	Appends `s` to the last chunk if that keeps it within `ropeChunkMax`, returning whether it did.
+func (me *ropeNode) appendLast(s string) bool {
+	if me.right != nil {
+		if !me.right.appendLast(s) {
+			return false
+		}
+	} else if len(me.chunk)+len(s) > ropeChunkMax {
+		return false
+	} else {
+		me.chunk += s
+		me.own = metricsOf(me.chunk)
+	}
+	me.update()
+	return true
+}
+
+//	Returns the first chunk by which the `metric` (which must be greater than `0`) is reached, along with the
+//	metrics of all text preceding that chunk and the remainder of `metric` within the chunk.
+//	Returns an empty `chunk` if the `metric` exceeds the whole text.
+func (me *ropeNode) seek(metric int, of func(*ropeMetrics) int) (chunk string, before ropeMetrics, rest int) {
+	for n := me; n != nil; {
+		left := n.left.metrics()
+		if l := of(&left); metric <= l {
+			n = n.left
+			continue
+		} else if metric -= l; metric <= of(&n.own) {
+			before.add(&left)
+			return n.chunk, before, metric
+		} else {
+			metric -= of(&n.own)
+			before.add(&left)
+			before.add(&n.own)
+			n = n.right
+		}
+	}
+	return "", before, metric
+}
+
+//	Calls `on` with all (partial) chunks making up the text between the byte offsets `start` and `end`, in order.
+func (me *ropeNode) walk(start int, end int, on func(string)) {
+	if me == nil || end <= 0 || start >= me.sum.bytes {
+		return
+	}
+	l := me.left.metrics().bytes
+	me.left.walk(start, end, on)
+	if from, to := start-l, end-l; from < len(me.chunk) && to > 0 {
+		if from < 0 {
+			from = 0
+		}
+		if to > len(me.chunk) {
+			to = len(me.chunk)
+		}
+		on(me.chunk[from:to])
+	}
+	me.right.walk(start-l-len(me.chunk), end-l-len(me.chunk), on)
+}
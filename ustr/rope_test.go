@@ -0,0 +1,121 @@
+package ustr
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+func testUnits(s string, unit TextUnit) int {
+	switch unit {
+	case TextUnitRune:
+		return utf8.RuneCountInString(s)
+	case TextUnitUTF16:
+		return len(utf16.Encode([]rune(s)))
+	}
+	return len(s)
+}
+
+func TestRopeRandomEdits(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	pieces := []string{"a", "bc", "\n", "\r\n", "ä", "€", "😀", strings.Repeat("x", 700), strings.Repeat("é\n", 300)}
+	//	returns a random byte offset into `s` at a rune boundary
+	randOffset := func(s string) int {
+		for {
+			if i := rnd.Intn(len(s) + 1); i == len(s) || utf8.RuneStart(s[i]) {
+				return i
+			}
+		}
+	}
+	var rope Rope
+	want := ""
+	for n := 0; n < 400; n++ {
+		if start := randOffset(want); len(want) > 0 && rnd.Intn(3) == 0 {
+			end := start + randOffset(want[start:])
+			rope.Delete(start, end)
+			want = want[:start] + want[end:]
+		} else {
+			s := pieces[rnd.Intn(len(pieces))]
+			rope.Insert(start, s)
+			want = want[:start] + s + want[start:]
+		}
+		if got := rope.String(); got != want {
+			t.Fatalf("after edit %d: got %d bytes, want %d", n, len(got), len(want))
+		}
+		if n%20 != 0 {
+			continue
+		}
+		lines := strings.Split(want, "\n")
+		if rope.LineCount() != len(lines) {
+			t.Fatalf("LineCount: got %d, want %d", rope.LineCount(), len(lines))
+		}
+		for _, unit := range []TextUnit{TextUnitByte, TextUnitRune, TextUnitUTF16} {
+			if got, want := rope.LenIn(unit), testUnits(want, unit); got != want {
+				t.Fatalf("LenIn(%d): got %d, want %d", unit, got, want)
+			}
+			for k := 0; k < 20; k++ {
+				offset := randOffset(want)
+				wantOffset := testUnits(want[:offset], unit)
+				if got := rope.Offset(offset, unit); got != wantOffset {
+					t.Fatalf("Offset(%d, %d): got %d, want %d", offset, unit, got, wantOffset)
+				} else if got = rope.ByteOffset(wantOffset, unit); got != offset {
+					t.Fatalf("ByteOffset(%d, %d): got %d, want %d", wantOffset, unit, got, offset)
+				}
+				line := strings.Count(want[:offset], "\n")
+				wantPos := TextPos{Line: line, Col: testUnits(want[strings.LastIndexByte(want[:offset], '\n')+1:offset], unit)}
+				if got := rope.Pos(offset, unit); got != wantPos {
+					t.Fatalf("Pos(%d, %d): got %v, want %v", offset, unit, got, wantPos)
+				} else if got := rope.OffsetOf(wantPos, unit); got != offset && !(want[offset-1] == '\r' && got == offset-1) {
+					t.Fatalf("OffsetOf(%v, %d): got %d, want %d", wantPos, unit, got, offset)
+				}
+			}
+		}
+		line := rnd.Intn(len(lines))
+		if got := rope.Line(line); got != strings.TrimSuffix(lines[line], "\r") {
+			t.Fatalf("Line(%d): got %q, want %q", line, got, lines[line])
+		}
+	}
+}
+
+func TestRopeApplyEdits(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		src   string
+		unit  TextUnit
+		edits []TextEdit
+		want  string
+		err   error
+	}{
+		{name: "replace and insert", src: "hello\nworld\n", edits: []TextEdit{
+			{Start: TextPos{1, 0}, End: TextPos{1, 5}, Text: "there"},
+			{Start: TextPos{0, 0}, End: TextPos{0, 0}, Text: "> "},
+		}, want: "> hello\nthere\n"},
+		{name: "inserts at same position keep order", src: "ab", edits: []TextEdit{
+			{Start: TextPos{0, 1}, End: TextPos{0, 1}, Text: "1"},
+			{Start: TextPos{0, 1}, End: TextPos{0, 1}, Text: "2"},
+		}, want: "a12b"},
+		{name: "utf16 columns", src: "😀x😀y", unit: TextUnitUTF16, edits: []TextEdit{
+			{Start: TextPos{0, 2}, End: TextPos{0, 3}, Text: "X"},
+			{Start: TextPos{0, 5}, End: TextPos{0, 6}, Text: "Y"},
+		}, want: "😀X😀Y"},
+		{name: "column beyond crlf line end", src: "ab\r\ncd", edits: []TextEdit{
+			{Start: TextPos{0, 99}, End: TextPos{0, 99}, Text: "!"},
+		}, want: "ab!\r\ncd"},
+		{name: "overlap", src: "abcdef", edits: []TextEdit{
+			{Start: TextPos{0, 1}, End: TextPos{0, 4}, Text: "x"},
+			{Start: TextPos{0, 3}, End: TextPos{0, 5}, Text: "y"},
+		}, want: "abcdef", err: ErrTextEditsOverlap},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rope := NewRope(test.src)
+			if err := rope.ApplyEdits(test.edits, test.unit); err != test.err {
+				t.Errorf("got error %v, want %v", err, test.err)
+			}
+			if got := rope.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -69,16 +69,15 @@ func MapEq(m1, m2 map[string]string) bool {
 	return true
 }
 
+//	Returns `s` suffixed with spaces to (at least) the specified display width `ensurelen` (see `StringWidth`).
 func PadRight(s string, ensurelen int) string {
-	if numspaces := ensurelen - len(s); numspaces > 0 {
-		return s + strings.Repeat(" ", numspaces)
-	}
-	return s
+	return Align(s, ensurelen, AlignLeft)
 }
 
+//	Returns the greatest display width (see `StringWidth`) of all `vals`.
 func Longest(vals ...string) (maxlen int) {
 	for _, str := range vals {
-		if l := len(str); l > maxlen {
+		if l := StringWidth(str); l > maxlen {
 			maxlen = l
 		}
 	}
@@ -189,32 +188,11 @@ func Concat(vals ...string) string {
 	return strings.Join(vals, "")
 }
 
-/*
-//	A simple string-similarity algorithm.
+//	A simple string-similarity algorithm: returns the `Levenshtein` distance between `s1` and `s2`.
+//	See also `DamerauLevenshtein`, `JaroWinkler`, `Suggest` and `FuzzyMatcher`.
 func Distance(s1, s2 string) int {
-	var (
-		cost, min1, min2, min3, i, j int
-		d                            = make([][]int, len(s1)+1)
-	)
-	for i = 0; i < len(d); i++ {
-		d[i] = make([]int, len(s2)+1)
-		d[i][0] = i
-	}
-	for i = 0; i < len(d[0]); i++ {
-		d[0][i] = i
-	}
-	for i = 1; i < len(d); i++ {
-		for j = 1; j < len(d[0]); j++ {
-			cost = umisc.IfI(s1[i-1] == s2[j-1], 0, 1)
-			min1 = d[i-1][j] + 1
-			min2 = d[i][j-1] + 1
-			min3 = d[i-1][j-1] + cost
-			d[i][j] = int(math.Min(math.Min(float64(min1), float64(min2)), float64(min3)))
-		}
-	}
-	return d[len(s1)][len(s2)]
+	return Levenshtein(s1, s2)
 }
-*/
 
 //	Extracts all "identifiers" (as per `ExtractFirstIdentifier`) in `src` and starting with `prefix` (no duplicates, ordered by occurrence).
 func ExtractAllIdentifiers(src, prefix string) (identifiers []string) {
@@ -414,13 +392,13 @@ func NonEmpties(breakAtFirstEmpty bool, vals ...string) (slice []string) {
 	return
 }
 
-//	Returns `strconv.ParseBool` or `false`.
+//	Returns `strconv.ParseBool` or `false`. (See `ParseBoolStrict` for an error-returning variant.)
 func ParseBool(s string) bool {
 	v, _ := strconv.ParseBool(s)
 	return v
 }
 
-//	Returns `strconv.ParseFloat` or `0`.
+//	Returns `strconv.ParseFloat` or `0`. (See `ParseFloatStrict` for an error-returning variant.)
 func ParseFloat(s string) float64 {
 	v, _ := strconv.ParseFloat(s, 64)
 	return v
@@ -452,13 +430,13 @@ func ToInt(s string) (i int) {
 	return
 }
 
-//	Returns `strconv.ParseInt` or `0`.
+//	Returns `strconv.ParseInt` or `0`. (See `ParseIntStrict` for an error-returning variant.)
 func ParseInt(s string) int64 {
 	v, _ := strconv.ParseInt(s, 0, 64)
 	return v
 }
 
-//	Returns `strconv.ParseUint` or `0`.
+//	Returns `strconv.ParseUint` or `0`. (See `ParseUintStrict` for an error-returning variant.)
 func ParseUint(s string) uint64 {
 	v, _ := strconv.ParseUint(s, 0, 64)
 	return v
@@ -506,11 +484,22 @@ func ReduceSpaces(s string) string {
 }
 
 //	Replaces in `str` all occurrences of all `repls` hash-map keys with their respective associated (mapped) value.
+//	All keys are searched for in a single pass (via an `AhoCorasick`), so that replaced values are not themselves subject
+//	to further replacements and, where occurrences of keys overlap, the leftmost-longest one is replaced. Empty keys are ignored.
 func Replace(str string, repls map[string]string) string {
+	if len(repls) == 1 {
+		for k, v := range repls {
+			if k != "" {
+				str = strings.Replace(str, k, v, -1)
+			}
+		}
+		return str
+	}
+	needles, replacements := make([]string, 0, len(repls)), make([]string, 0, len(repls))
 	for k, v := range repls {
-		str = strings.Replace(str, k, v, -1)
+		needles, replacements = append(needles, k), append(replacements, v)
 	}
-	return str
+	return NewAhoCorasick(needles, false).Replace(str, replacements)
 }
 
 //	Creates a Pascal-cased "identifier" version of the specified string.
@@ -0,0 +1,89 @@
+package ustr
+
+import (
+	"strings"
+)
+
+//	A simple text table of display-width aligned columns (see `StringWidth`), rendered via `Buffer.WriteTable`.
+type Table struct {
+	//	The columns, at least as many as the longest row has cells. Their headers are only written if any is non-empty.
+	Columns []TableColumn
+
+	//	The cells of each row, which may contain line breaks. Rows may have fewer cells than there are `Columns`.
+	Rows [][]string
+
+	//	Written between the columns. Defaults to two spaces if empty.
+	Sep string
+
+	//	If not `0`, a line of this rune (such as `'-'`) is written beneath the headers, as wide as each column.
+	HeaderLine rune
+}
+
+//	A column of a `Table`.
+type TableColumn struct {
+	Header string
+	Align  Alignment
+
+	//	If greater than `0`, the column is at most this wide: wider cells are then `Wrap`ped if `Wrap`,
+	//	otherwise `Truncate`d with a trailing "…".
+	MaxWidth int
+	Wrap     bool
+}
+
+//	Renders `me` into a `string`, see `Buffer.WriteTable`.
+func (me *Table) String() string {
+	var buf Buffer
+	buf.WriteTable(me)
+	return buf.String()
+}
+
+//	Returns the lines of all cells (headers first, if any) per row and the width of each column.
+func (me *Table) layout() (rows [][][]string, widths []int) {
+	numCols := len(me.Columns)
+	for _, row := range me.Rows {
+		if len(row) > numCols {
+			numCols = len(row)
+		}
+	}
+	cols := make([]TableColumn, numCols)
+	copy(cols, me.Columns)
+	widths = make([]int, numCols)
+	cells := me.Rows
+	for _, col := range cols {
+		if col.Header != "" {
+			headers := make([]string, numCols)
+			for i := range cols {
+				headers[i] = cols[i].Header
+			}
+			cells = append([][]string{headers}, cells...)
+			break
+		}
+	}
+	rows = make([][][]string, len(cells))
+	for r, row := range cells {
+		rows[r] = make([][]string, numCols)
+		for c := range cols {
+			var cell string
+			if c < len(row) {
+				cell = row[c]
+			}
+			lines := strings.Split(strings.Replace(cell, "\r\n", "\n", -1), "\n")
+			if max := cols[c].MaxWidth; max > 0 {
+				if cols[c].Wrap {
+					lines = Wrap(cell, max, "", "")
+				} else {
+					for i := range lines {
+						lines[i] = Truncate(lines[i], max, "…")
+					}
+				}
+			}
+			for _, line := range lines {
+				if w := StringWidth(line); w > widths[c] {
+					widths[c] = w
+				}
+			}
+			rows[r][c] = lines
+		}
+	}
+	return
+}
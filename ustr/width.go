@@ -0,0 +1,298 @@
+package ustr
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//	If `true`, runes of East Asian Width "Ambiguous" (such as Greek and Cyrillic letters, box-drawing characters
+//	and `±`) are measured as 2 columns wide, as in terminals configured for CJK locales. Otherwise, as 1.
+var EastAsianAmbiguousWide = false
+
+//	How text is aligned within a given width, such as by `Align`.
+type Alignment int
+
+const (
+	AlignLeft Alignment = iota
+	AlignRight
+	AlignCenter
+)
+
+type runeRange struct{ lo, hi rune }
+
+//	East Asian Width "Wide" and "Fullwidth" runes, including emoji that default to emoji presentation.
+var runeRangesWide = []runeRange{
+	{0x1100, 0x115F}, {0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+	{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693}, {0x26A1, 0x26A1},
+	{0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE}, {0x26D4, 0x26D4}, {0x26EA, 0x26EA},
+	{0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA}, {0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B},
+	{0x2728, 0x2728}, {0x274C, 0x274C}, {0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797},
+	{0x27B0, 0x27B0}, {0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55}, {0x2E80, 0x303E},
+	{0x3041, 0x33FF}, {0x3400, 0x4DBF}, {0x4E00, 0x9FFF}, {0xA000, 0xA4CF}, {0xA960, 0xA97F}, {0xAC00, 0xD7A3},
+	{0xF900, 0xFAFF}, {0xFE10, 0xFE19}, {0xFE30, 0xFE6F}, {0xFF00, 0xFF60}, {0xFFE0, 0xFFE6}, {0x16FE0, 0x16FE4},
+	{0x16FF0, 0x16FF1}, {0x17000, 0x18CFF}, {0x18D00, 0x18D08}, {0x1AFF0, 0x1B2FF}, {0x1F004, 0x1F004},
+	{0x1F0CF, 0x1F0CF}, {0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A}, {0x1F200, 0x1F202}, {0x1F210, 0x1F23B},
+	{0x1F240, 0x1F248}, {0x1F250, 0x1F251}, {0x1F260, 0x1F265}, {0x1F300, 0x1F320}, {0x1F32D, 0x1F335},
+	{0x1F337, 0x1F37C}, {0x1F37E, 0x1F393}, {0x1F3A0, 0x1F3CA}, {0x1F3CF, 0x1F3D3}, {0x1F3E0, 0x1F3F0},
+	{0x1F3F4, 0x1F3F4}, {0x1F3F8, 0x1F43E}, {0x1F440, 0x1F440}, {0x1F442, 0x1F4FC}, {0x1F4FF, 0x1F53D},
+	{0x1F54B, 0x1F54E}, {0x1F550, 0x1F567}, {0x1F57A, 0x1F57A}, {0x1F595, 0x1F596}, {0x1F5A4, 0x1F5A4},
+	{0x1F5FB, 0x1F64F}, {0x1F680, 0x1F6C5}, {0x1F6CC, 0x1F6CC}, {0x1F6D0, 0x1F6D2}, {0x1F6D5, 0x1F6D7},
+	{0x1F6DC, 0x1F6DF}, {0x1F6EB, 0x1F6EC}, {0x1F6F4, 0x1F6FC}, {0x1F7E0, 0x1F7EB}, {0x1F7F0, 0x1F7F0},
+	{0x1F90C, 0x1F93A}, {0x1F93C, 0x1F945}, {0x1F947, 0x1F9FF}, {0x1FA70, 0x1FAFF}, {0x20000, 0x2FFFD},
+	{0x30000, 0x3FFFD},
+}
+
+//	East Asian Width "Ambiguous" runes, see `EastAsianAmbiguousWide`.
+var runeRangesAmbiguous = []runeRange{
+	{0x00A1, 0x00A1}, {0x00A4, 0x00A4}, {0x00A7, 0x00A8}, {0x00AA, 0x00AA}, {0x00AE, 0x00AE}, {0x00B0, 0x00B4},
+	{0x00B6, 0x00BA}, {0x00BC, 0x00BF}, {0x00C6, 0x00C6}, {0x00D0, 0x00D0}, {0x00D7, 0x00D8}, {0x00DE, 0x00E1},
+	{0x00E6, 0x00E6}, {0x00E8, 0x00EA}, {0x00EC, 0x00ED}, {0x00F0, 0x00F0}, {0x00F2, 0x00F3}, {0x00F7, 0x00FA},
+	{0x00FC, 0x00FC}, {0x00FE, 0x00FE}, {0x0391, 0x03A9}, {0x03B1, 0x03C9}, {0x0401, 0x0401}, {0x0410, 0x044F},
+	{0x0451, 0x0451}, {0x2010, 0x2010}, {0x2013, 0x2016}, {0x2018, 0x2019}, {0x201C, 0x201D}, {0x2020, 0x2022},
+	{0x2024, 0x2027}, {0x2030, 0x2030}, {0x2032, 0x2033}, {0x2035, 0x2035}, {0x203B, 0x203B}, {0x203E, 0x203E},
+	{0x20AC, 0x20AC}, {0x2103, 0x2103}, {0x2109, 0x2109}, {0x2116, 0x2116}, {0x2121, 0x2122}, {0x2160, 0x216B},
+	{0x2170, 0x2179}, {0x2190, 0x2199}, {0x21D2, 0x21D2}, {0x21D4, 0x21D4}, {0x2200, 0x22FF}, {0x2460, 0x24E9},
+	{0x24EB, 0x254B}, {0x2550, 0x2573}, {0x2580, 0x258F}, {0x2592, 0x2595}, {0x25A0, 0x25A1}, {0x25A3, 0x25A9},
+	{0x25B2, 0x25B3}, {0x25B6, 0x25B7}, {0x25BC, 0x25BD}, {0x25C0, 0x25C1}, {0x25C6, 0x25C8}, {0x25CB, 0x25CB},
+	{0x25CE, 0x25D1}, {0x25E2, 0x25E5}, {0x25EF, 0x25EF}, {0x2605, 0x2606}, {0x2609, 0x2609}, {0x260E, 0x260F},
+	{0x261C, 0x261C}, {0x261E, 0x261E}, {0x2640, 0x2640}, {0x2642, 0x2642}, {0x2660, 0x2661}, {0x2663, 0x2665},
+	{0x2667, 0x266A}, {0x266C, 0x266D}, {0x266F, 0x266F}, {0x273D, 0x273D}, {0x2776, 0x277F}, {0xE000, 0xF8FF},
+	{0xFFFD, 0xFFFD}, {0xF0000, 0xFFFFD}, {0x100000, 0x10FFFD},
+}
+
+func inRuneRanges(r rune, ranges []runeRange) bool {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].hi >= r })
+	return i < len(ranges) && ranges[i].lo <= r
+}
+
+//	Returns the number of terminal columns taken up by `r` on its own: `0` for control characters, combining marks
+//	and other zero-width runes (such as the zero-width joiner), `2` for East Asian "Wide" and "Fullwidth" runes
+//	(CJK ideographs, kana, hangul syllables, most emoji), and `1` for all others (see also `EastAsianAmbiguousWide`).
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7F:
+		return 0
+	case r < 0xA0:
+		return 1
+	case r >= 0x1160 && r <= 0x11FF, r >= 0xD7B0 && r <= 0xD7FF, r == 0x200B,
+		unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+		//	(conjoining hangul vowels and final consonants combine with their preceding initial consonant)
+		return 0
+	case inRuneRanges(r, runeRangesWide):
+		return 2
+	case EastAsianAmbiguousWide && inRuneRanges(r, runeRangesAmbiguous):
+		return 2
+	}
+	return 1
+}
+
+//	Returns the number of terminal columns taken up by `s`, as per its `Graphemes` (tabs and other control
+//	characters count as zero columns).
+func StringWidth(s string) (width int) {
+	for i := 0; i < len(s); {
+		if b := s[i]; b >= 0x20 && b < 0x7F && (i+1 == len(s) || s[i+1] < utf8.RuneSelf) {
+			i, width = i+1, width+1
+		} else {
+			size, w := nextGrapheme(s[i:])
+			i, width = i+size, width+w
+		}
+	}
+	return
+}
+
+//	Splits `s` into its grapheme clusters: user-perceived characters such as a letter with its combining marks,
+//	an emoji with its skin-tone modifier or variation selector, an emoji sequence joined by zero-width joiners,
+//	or a flag made of two regional indicators. (This follows the essentials of Unicode's extended grapheme cluster
+//	rules, not every special case.)
+func Graphemes(s string) (clusters []string) {
+	for len(s) > 0 {
+		size, _ := nextGrapheme(s)
+		clusters, s = append(clusters, s[:size]), s[size:]
+	}
+	return
+}
+
+//	Returns the byte length and the width of the grapheme cluster that `s` (which must not be empty) starts with.
+func nextGrapheme(s string) (size int, width int) {
+	first, size := utf8.DecodeRuneInString(s)
+	if first == '\r' && len(s) > 1 && s[1] == '\n' {
+		return 2, 0
+	} else if first < 0x20 || first == 0x7F || first == 0x2028 || first == 0x2029 {
+		return size, 0
+	}
+	width = RuneWidth(first)
+	isRegional := first >= 0x1F1E6 && first <= 0x1F1FF
+	for prev := first; size < len(s); {
+		r, n := utf8.DecodeRuneInString(s[size:])
+		switch {
+		case r == 0x200D, isGraphemeExtend(r):
+			if r == 0xFE0F && width == 1 {
+				//	emoji presentation of an otherwise narrow symbol (such as the heart U+2764)
+				width = 2
+			}
+		case prev == 0x200D && r >= 0x20 && r != 0x7F:
+			//	zero-width joiner sequences (such as woman + ZWJ + laptop) show as one glyph
+		case isRegional && r >= 0x1F1E6 && r <= 0x1F1FF:
+			isRegional, width = false, 2
+		default:
+			return
+		}
+		prev, size = r, size+n
+	}
+	return
+}
+
+//	Returns whether `r` attaches to the preceding rune in a grapheme cluster: combining and spacing marks, variation
+//	selectors, emoji modifiers, tag characters and conjoining hangul vowels and final consonants.
+func isGraphemeExtend(r rune) bool {
+	return (r >= 0x1F3FB && r <= 0x1F3FF) || (r >= 0xE0020 && r <= 0xE007F) || (r >= 0x1160 && r <= 0x11FF) ||
+		(r >= 0xD7B0 && r <= 0xD7FF) || (r >= 0x300 && unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc))
+}
+
+//	Returns `s` padded with spaces to (at least) the specified display `width` (see `StringWidth`) in
+//	accordance with `align`, with any odd space of `AlignCenter` going to the right.
+func Align(s string, width int, align Alignment) string {
+	n := width - StringWidth(s)
+	if n <= 0 {
+		return s
+	}
+	switch align {
+	case AlignRight:
+		return strings.Repeat(" ", n) + s
+	case AlignCenter:
+		return strings.Repeat(" ", n/2) + s + strings.Repeat(" ", n-n/2)
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+//	Returns `s` prefixed with spaces to (at least) the specified display `width` (see `StringWidth`).
+func PadLeft(s string, width int) string {
+	return Align(s, width, AlignRight)
+}
+
+//	Returns `s` surrounded by spaces to (at least) the specified display `width` (see `StringWidth`).
+func PadCenter(s string, width int) string {
+	return Align(s, width, AlignCenter)
+}
+
+//	Returns `s` if its display width (see `StringWidth`) is at most `width`, otherwise as many of its leading
+//	`Graphemes` as fit into `width` together with the `ellipsis` (such as "…" or "..."), followed by that `ellipsis`.
+//	Should `ellipsis` itself not fit, `s` is cut to `width` without any.
+func Truncate(s string, width int, ellipsis string) string {
+	if StringWidth(s) <= width {
+		return s
+	}
+	avail := width - StringWidth(ellipsis)
+	if avail < 0 {
+		avail, ellipsis = width, ""
+	}
+	end, w := 0, 0
+	for end < len(s) {
+		size, gw := nextGrapheme(s[end:])
+		if w+gw > avail {
+			break
+		}
+		end, w = end+size, w+gw
+	}
+	return s[:end] + ellipsis
+}
+
+//	Word-wraps `s` into lines of at most the specified display `width` (see `StringWidth`), with the first line of
+//	every paragraph (as separated by line breaks in `s`) starting with `firstIndent` and all others with `indent`
+//	(such as spaces for a hanging indent), both counting towards `width`.
+//
+//	Lines are broken at white-space (each run of which is collapsed into a single space) and between any two wide
+//	(such as CJK) characters. Words wider than the available width are broken between their `Graphemes`.
+func Wrap(s string, width int, firstIndent string, indent string) (lines []string) {
+	for _, para := range strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n") {
+		var line strings.Builder
+		prefix, lineWidth, pendingSpace := firstIndent, 0, false
+		avail := width - StringWidth(prefix)
+		flush := func() {
+			if line.Len() == 0 {
+				lines = append(lines, "")
+			} else {
+				lines = append(lines, prefix+line.String())
+			}
+			line.Reset()
+			prefix, lineWidth, pendingSpace = indent, 0, false
+			if avail = width - StringWidth(prefix); avail < 1 {
+				avail = 1
+			}
+		}
+		if avail < 1 {
+			avail = 1
+		}
+		for _, tok := range wrapTokens(para) {
+			if tok.text == "" {
+				pendingSpace = lineWidth > 0
+				continue
+			}
+			space := 0
+			if pendingSpace {
+				space = 1
+			}
+			if lineWidth > 0 && lineWidth+space+tok.width > avail {
+				flush()
+				space = 0
+			}
+			if space > 0 {
+				line.WriteByte(' ')
+				lineWidth, pendingSpace = lineWidth+1, false
+			}
+			for rest := tok.text; rest != ""; {
+				if lineWidth+StringWidth(rest) <= avail {
+					line.WriteString(rest)
+					lineWidth += StringWidth(rest)
+					break
+				}
+				//	break an overlong word, but never without having put at least one grapheme on the line
+				size, gw := nextGrapheme(rest)
+				if lineWidth > 0 && lineWidth+gw > avail {
+					flush()
+					continue
+				}
+				line.WriteString(rest[:size])
+				lineWidth, rest = lineWidth+gw, rest[size:]
+			}
+		}
+		flush()
+	}
+	return
+}
+
+type wrapToken struct {
+	text  string
+	width int
+}
+
+//	Splits `para` into words, with an empty token for each run of white-space, and every wide grapheme a word of its own.
+func wrapTokens(para string) (toks []wrapToken) {
+	start, width := -1, 0
+	for i := 0; i < len(para); {
+		r, _ := utf8.DecodeRuneInString(para[i:])
+		size, gw := nextGrapheme(para[i:])
+		if isSpace := unicode.IsSpace(r); isSpace || gw > 1 {
+			if start >= 0 {
+				toks, start, width = append(toks, wrapToken{text: para[start:i], width: width}), -1, 0
+			}
+			if !isSpace {
+				toks = append(toks, wrapToken{text: para[i : i+size], width: gw})
+			} else if len(toks) == 0 || toks[len(toks)-1].text != "" {
+				toks = append(toks, wrapToken{})
+			}
+		} else {
+			if start < 0 {
+				start = i
+			}
+			width += gw
+		}
+		i += size
+	}
+	if start >= 0 {
+		toks = append(toks, wrapToken{text: para[start:], width: width})
+	}
+	return
+}
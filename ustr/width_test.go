@@ -0,0 +1,123 @@
+package ustr
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStringWidth(t *testing.T) {
+	for s, want := range map[string]int{
+		"":                           0,
+		"hello":                      5,
+		"日本語":                        6,
+		"ｈｉ":                         4,
+		"e\u0301":                    1,
+		"a\tb":                       2,
+		"\U0001f44d\U0001f3fd":       2,
+		"\U0001f469\u200d\U0001f4bb": 2,
+		"\U0001f1e9\U0001f1ea":       2,
+		"\u2764\ufe0f":               2,
+		"\u2764":                     1,
+		"한국어":                        6,
+		"\u1100\u1161\u11a8":         2,
+		"αβγ":                        3,
+	} {
+		if got := StringWidth(s); got != want {
+			t.Errorf("StringWidth(%q): got %d, want %d", s, got, want)
+		}
+	}
+	defer func() { EastAsianAmbiguousWide = false }()
+	if EastAsianAmbiguousWide = true; StringWidth("αβγ") != 6 {
+		t.Errorf("StringWidth of ambiguous runes: got %d, want 6", StringWidth("αβγ"))
+	}
+}
+
+func TestGraphemes(t *testing.T) {
+	for s, want := range map[string]string{
+		"abc":                         "a|b|c",
+		"e\u0301x":                    "e\u0301|x",
+		"\U0001f469\u200d\U0001f4bb!": "\U0001f469\u200d\U0001f4bb|!",
+		"\U0001f1e9\U0001f1ea\U0001f1eb\U0001f1f7": "\U0001f1e9\U0001f1ea|\U0001f1eb\U0001f1f7",
+		"a\r\nb":                         "a|\r\n|b",
+		"\U0001f44d\U0001f3fd\U0001f44d": "\U0001f44d\U0001f3fd|\U0001f44d",
+		"\u1100\u1161x":                  "\u1100\u1161|x",
+	} {
+		if got := strings.Join(Graphemes(s), "|"); got != want {
+			t.Errorf("Graphemes(%q): got %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestAlignAndTruncate(t *testing.T) {
+	for _, test := range []struct{ got, want string }{
+		{Align("日本", 6, AlignLeft), "日本  "},
+		{PadLeft("日本", 6), "  日本"},
+		{PadCenter("ab", 5), " ab  "},
+		{Align("toolong", 3, AlignRight), "toolong"},
+		{Truncate("hello world", 8, "…"), "hello w…"},
+		{Truncate("日本語テキスト", 7, "…"), "日本語…"},
+		{Truncate("e\u0301e\u0301e\u0301e\u0301", 3, "…"), "e\u0301e\u0301\u2026"},
+		{Truncate("short", 10, "..."), "short"},
+		{Truncate("abcdef", 2, "..."), "ab"},
+	} {
+		if test.got != test.want {
+			t.Errorf("got %q, want %q", test.got, test.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	for _, test := range []struct {
+		s                   string
+		width               int
+		firstIndent, indent string
+		want                string
+	}{
+		{"the quick brown fox jumps", 10, "", "", "the quick|brown fox|jumps"},
+		{"the  quick\tbrown", 20, "", "", "the quick brown"},
+		{"one two three", 9, "- ", "  ", "- one two|  three"},
+		{"abcdefghij", 4, "", "", "abcd|efgh|ij"},
+		{"日本語のテキスト", 6, "", "", "日本語|のテキ|スト"},
+		{"para one\n\npara two", 20, "", "", "para one||para two"},
+		{"x", 0, "", "", "x"},
+	} {
+		if got := strings.Join(Wrap(test.s, test.width, test.firstIndent, test.indent), "|"); got != test.want {
+			t.Errorf("Wrap(%q, %d): got %q, want %q", test.s, test.width, got, test.want)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := &Table{
+		Columns: []TableColumn{
+			{Header: "Name"},
+			{Header: "Size", Align: AlignRight},
+			{Header: "Note", MaxWidth: 8},
+		},
+		Rows: [][]string{
+			{"日本", "12", "short"},
+			{"a.txt", "1234", "much too long"},
+			{"multi\nline"},
+		},
+		HeaderLine: '-',
+	}
+	want := "Name   Size  Note\n" +
+		"-----  ----  --------\n" +
+		"日本     12  short\n" +
+		"a.txt  1234  much to…\n" +
+		"multi\n" +
+		"line\n"
+	if got := table.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	table.Columns[2].Wrap = true
+	if got := table.String(); !strings.Contains(got, "a.txt  1234  much too\n             long\n") {
+		t.Errorf("wrapped: got\n%s", got)
+	}
+
+	//	a double-width header line rune fills odd widths up with a space
+	table = &Table{Columns: []TableColumn{{Header: "Name"}, {Header: "Ext"}}, Rows: [][]string{{"a", "txt"}}, HeaderLine: '＝'}
+	if got, want := table.String(), "Name  Ext\n＝＝  ＝\na     txt\n"; got != want {
+		t.Errorf("wide header line: got\n%s\nwant\n%s", got, want)
+	}
+}